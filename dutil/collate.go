@@ -0,0 +1,329 @@
+package dutil
+
+// Collate functions merging samples into batched tensors.
+//
+// NOTE. Collate functions do not take ownership of sample tensors. Returned batch tensors
+// are newly created and should be dropped by the caller.
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/ts"
+)
+
+// CollateStack stacks same-shape sample tensors along a new batch dimension 0.
+func CollateStack(items []*ts.Tensor) (*ts.Tensor, error) {
+	if len(items) == 0 {
+		err := fmt.Errorf("CollateStack() failed: empty samples")
+		return nil, err
+	}
+	shape := items[0].MustSize()
+	for i, x := range items[1:] {
+		if s := x.MustSize(); !reflect.DeepEqual(shape, s) {
+			err := fmt.Errorf("CollateStack() failed: sample %v has shape %v, expected %v", i+1, s, shape)
+			return nil, err
+		}
+	}
+
+	return ts.Stack(items, 0)
+}
+
+// PaddedBatch is a batch of variable-length sequences padded to the longest sequence.
+type PaddedBatch struct {
+	Data    *ts.Tensor // padded sequences of shape [batch, maxLen, ...]
+	Lengths *ts.Tensor // int64 sequence lengths of shape [batch]
+	Mask    *ts.Tensor // bool mask of shape [batch, maxLen]. True for non-padding positions.
+}
+
+// ToDevice implements ToDevicer interface. Batch tensors are dropped on success.
+// On error, the batch is unchanged.
+func (b PaddedBatch) ToDevice(device gotch.Device) (PaddedBatch, error) {
+	var (
+		out PaddedBatch
+		err error
+	)
+	if out.Data, err = b.Data.To(device, false); err != nil {
+		return b, err
+	}
+	if out.Lengths, err = b.Lengths.To(device, false); err != nil {
+		out.Drop()
+		return b, err
+	}
+	if out.Mask, err = b.Mask.To(device, false); err != nil {
+		out.Drop()
+		return b, err
+	}
+	b.Drop()
+
+	return out, nil
+}
+
+// Drop drops batch tensors.
+func (b PaddedBatch) Drop() {
+	for _, x := range []*ts.Tensor{b.Data, b.Lengths, b.Mask} {
+		if x != nil {
+			x.MustDrop()
+		}
+	}
+}
+
+// CollatePad returns a Collate that pads sample sequences along dimension 0 to the longest
+// sequence with padValue and stacks them. Samples should have the same dtype, device and
+// trailing dimensions.
+func CollatePad(padValue float64) Collate[*ts.Tensor, PaddedBatch] {
+	return func(items []*ts.Tensor) (PaddedBatch, error) {
+		var batch PaddedBatch
+		if len(items) == 0 {
+			err := fmt.Errorf("CollatePad() failed: empty samples")
+			return batch, err
+		}
+
+		lengths := make([]int64, len(items))
+		var maxLen int64
+		shape := items[0].MustSize()
+		if len(shape) == 0 {
+			err := fmt.Errorf("CollatePad() failed: expected sequence samples of at least 1 dimension")
+			return batch, err
+		}
+		for i, x := range items {
+			s := x.MustSize()
+			if len(s) != len(shape) || !reflect.DeepEqual(s[1:], shape[1:]) {
+				err := fmt.Errorf("CollatePad() failed: sample %v has shape %v, expected [*, %v]", i, s, shape[1:])
+				return batch, err
+			}
+			lengths[i] = s[0]
+			if s[0] > maxLen {
+				maxLen = s[0]
+			}
+		}
+
+		dtype := items[0].DType()
+		device, err := items[0].Device()
+		if err != nil {
+			return batch, err
+		}
+
+		padded := make([]*ts.Tensor, len(items))
+		defer func() {
+			for i, x := range padded {
+				if x != nil && x != items[i] {
+					x.MustDrop()
+				}
+			}
+		}()
+		for i, x := range items {
+			if lengths[i] == maxLen {
+				padded[i] = x
+				continue
+			}
+			padShape := append([]int64{maxLen - lengths[i]}, shape[1:]...)
+			pad, err := ts.Full(padShape, ts.FloatScalar(padValue), dtype, device)
+			if err != nil {
+				return batch, err
+			}
+			padded[i], err = ts.Cat([]*ts.Tensor{x, pad}, 0)
+			pad.MustDrop()
+			if err != nil {
+				return batch, err
+			}
+		}
+
+		mask := make([]bool, int64(len(items))*maxLen)
+		for i, l := range lengths {
+			for j := int64(0); j < l; j++ {
+				mask[int64(i)*maxLen+j] = true
+			}
+		}
+
+		if batch.Data, err = ts.Stack(padded, 0); err != nil {
+			return batch, err
+		}
+		lengthsTs := ts.MustOfSlice(lengths)
+		if batch.Lengths, err = lengthsTs.To(device, true); err != nil {
+			batch.Drop()
+			return PaddedBatch{}, err
+		}
+		maskTs := ts.MustOfSlice(mask).MustView([]int64{int64(len(items)), maxLen}, true)
+		if batch.Mask, err = maskTs.To(device, true); err != nil {
+			batch.Drop()
+			return PaddedBatch{}, err
+		}
+
+		return batch, nil
+	}
+}
+
+// CollateMap stacks samples of named tensors key by key. All samples should have the same keys.
+func CollateMap(items []map[string]*ts.Tensor) (map[string]*ts.Tensor, error) {
+	if len(items) == 0 {
+		err := fmt.Errorf("CollateMap() failed: empty samples")
+		return nil, err
+	}
+
+	var keys []string
+	for k := range items[0] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	batch := make(map[string]*ts.Tensor, len(keys))
+	for _, k := range keys {
+		xs := make([]*ts.Tensor, len(items))
+		for i, item := range items {
+			x, ok := item[k]
+			if !ok || len(item) != len(keys) {
+				dropTensors(batch)
+				err := fmt.Errorf("CollateMap() failed: sample %v keys mismatched with sample 0 keys %v", i, keys)
+				return nil, err
+			}
+			xs[i] = x
+		}
+		x, err := CollateStack(xs)
+		if err != nil {
+			dropTensors(batch)
+			err = fmt.Errorf("CollateMap() failed - key %q: %w", k, err)
+			return nil, err
+		}
+		batch[k] = x
+	}
+
+	return batch, nil
+}
+
+// CollateStruct collates samples of struct (or pointer to struct) type T field by field into
+// named tensors:
+//   - `*ts.Tensor` fields are stacked (see CollateStack). Fields that are nil in all samples
+//     (e.g. TabularSample.Label without label column) are skipped; nil in some samples is an error.
+//   - numeric and bool fields are merged to a 1D tensor. Integer fields become int64 tensors.
+//
+// Tensors are keyed by field name or `collate:"name"` tag. Unexported fields and fields
+// tagged `collate:"-"` are skipped. Other field types result in an error.
+func CollateStruct[T any](items []T) (map[string]*ts.Tensor, error) {
+	if len(items) == 0 {
+		err := fmt.Errorf("CollateStruct() failed: empty samples")
+		return nil, err
+	}
+
+	values := make([]reflect.Value, len(items))
+	for i, item := range items {
+		v := reflect.ValueOf(item)
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				err := fmt.Errorf("CollateStruct() failed: sample %v is nil", i)
+				return nil, err
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			err := fmt.Errorf("CollateStruct() failed: expected struct samples, got %T", item)
+			return nil, err
+		}
+		values[i] = v
+	}
+
+	typ := values[0].Type()
+	tensorType := reflect.TypeOf((*ts.Tensor)(nil))
+	batch := make(map[string]*ts.Tensor)
+	for f := 0; f < typ.NumField(); f++ {
+		field := typ.Field(f)
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("collate"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag = strings.TrimSpace(tag); tag != "" {
+				name = tag
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		var (
+			x   *ts.Tensor
+			err error
+		)
+		switch {
+		case field.Type == tensorType:
+			xs := make([]*ts.Tensor, len(values))
+			nils := 0
+			for i, v := range values {
+				xs[i] = v.Field(f).Interface().(*ts.Tensor)
+				if xs[i] == nil {
+					nils++
+				}
+			}
+			if nils == len(xs) {
+				continue
+			}
+			if nils > 0 {
+				err = fmt.Errorf("nil tensor in %v of %v samples", nils, len(xs))
+				break
+			}
+			x, err = CollateStack(xs)
+		default:
+			x, err = collateScalars(values, f)
+		}
+		if err != nil {
+			dropTensors(batch)
+			err = fmt.Errorf("CollateStruct() failed - field %q: %w", field.Name, err)
+			return nil, err
+		}
+		batch[name] = x
+	}
+
+	return batch, nil
+}
+
+// collateScalars merges numeric or bool field f of struct values to a 1D tensor.
+func collateScalars(values []reflect.Value, f int) (*ts.Tensor, error) {
+	var data interface{}
+	kind := values[0].Field(f).Kind()
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		xs := make([]int64, len(values))
+		for i, v := range values {
+			xs[i] = v.Field(f).Int()
+		}
+		data = xs
+	case reflect.Uint8:
+		xs := make([]uint8, len(values))
+		for i, v := range values {
+			xs[i] = uint8(v.Field(f).Uint())
+		}
+		data = xs
+	case reflect.Float32:
+		xs := make([]float32, len(values))
+		for i, v := range values {
+			xs[i] = float32(v.Field(f).Float())
+		}
+		data = xs
+	case reflect.Float64:
+		xs := make([]float64, len(values))
+		for i, v := range values {
+			xs[i] = v.Field(f).Float()
+		}
+		data = xs
+	case reflect.Bool:
+		xs := make([]bool, len(values))
+		for i, v := range values {
+			xs[i] = v.Field(f).Bool()
+		}
+		data = xs
+	default:
+		err := fmt.Errorf("unsupported field type %v", values[0].Field(f).Type())
+		return nil, err
+	}
+
+	return ts.OfSlice(data)
+}
+
+func dropTensors(xs map[string]*ts.Tensor) {
+	for _, x := range xs {
+		x.MustDrop()
+	}
+}
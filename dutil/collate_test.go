@@ -0,0 +1,114 @@
+package dutil_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch/dutil"
+	"github.com/sugarme/gotch/ts"
+)
+
+func TestCollateStack(t *testing.T) {
+	items := []*ts.Tensor{ts.MustOfSlice([]float32{1, 2}), ts.MustOfSlice([]float32{3, 4})}
+	x, err := dutil.CollateStack(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := []int64{2, 2}, x.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want shape %v, got %v\n", want, got)
+	}
+
+	items = append(items, ts.MustOfSlice([]float32{5}))
+	if _, err := dutil.CollateStack(items); err == nil {
+		t.Errorf("want error for mismatched shapes\n")
+	}
+}
+
+func TestCollatePad(t *testing.T) {
+	items := []*ts.Tensor{
+		ts.MustOfSlice([]int64{1, 2, 3}),
+		ts.MustOfSlice([]int64{4}),
+		ts.MustOfSlice([]int64{5, 6}),
+	}
+	b, err := dutil.CollatePad(-1)(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Drop()
+
+	if want, got := []int64{3, 3}, b.Data.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want shape %v, got %v\n", want, got)
+	}
+	if want, got := []int64{1, 2, 3, 4, -1, -1, 5, 6, -1}, b.Data.Int64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want data %v, got %v\n", want, got)
+	}
+	if want, got := []int64{3, 1, 2}, b.Lengths.Int64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want lengths %v, got %v\n", want, got)
+	}
+	if want, got := []int64{1, 1, 1, 1, 0, 0, 1, 1, 0}, b.Mask.MustTotype(b.Data.DType(), false).Int64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want mask %v, got %v\n", want, got)
+	}
+}
+
+func TestCollateMap(t *testing.T) {
+	items := []map[string]*ts.Tensor{
+		{"x": ts.MustOfSlice([]float64{1, 2}), "y": ts.MustOfSlice([]int64{0})},
+		{"x": ts.MustOfSlice([]float64{3, 4}), "y": ts.MustOfSlice([]int64{1})},
+	}
+	b, err := dutil.CollateMap(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := []float64{1, 2, 3, 4}, b["x"].Float64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want x %v, got %v\n", want, got)
+	}
+	if want, got := []int64{2, 1}, b["y"].MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want y shape %v, got %v\n", want, got)
+	}
+
+	items = append(items, map[string]*ts.Tensor{"x": ts.MustOfSlice([]float64{5, 6})})
+	if _, err := dutil.CollateMap(items); err == nil {
+		t.Errorf("want error for missing key\n")
+	}
+}
+
+func TestCollateStruct(t *testing.T) {
+	type example struct {
+		Image *ts.Tensor
+		Label int    `collate:"label"`
+		Name  string `collate:"-"`
+		score float64
+	}
+	items := []*example{
+		{Image: ts.MustOfSlice([]float32{1, 2}), Label: 3, Name: "a"},
+		{Image: ts.MustOfSlice([]float32{3, 4}), Label: 7, Name: "b"},
+	}
+	b, err := dutil.CollateStruct(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 2 {
+		t.Fatalf("want 2 tensors, got %v\n", len(b))
+	}
+	if want, got := []int64{2, 2}, b["Image"].MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want image shape %v, got %v\n", want, got)
+	}
+	if want, got := []int64{3, 7}, b["label"].Int64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want labels %v, got %v\n", want, got)
+	}
+
+	partial := []*example{
+		{Image: ts.MustOfSlice([]float32{1, 2})},
+		{Image: nil},
+	}
+	if _, err := dutil.CollateStruct(partial); err == nil {
+		t.Errorf("want error for tensor field nil in some samples\n")
+	}
+
+	type invalid struct {
+		Name string
+	}
+	if _, err := dutil.CollateStruct([]invalid{{"a"}}); err == nil {
+		t.Errorf("want error for unsupported field type\n")
+	}
+}
@@ -0,0 +1,496 @@
+package dutil
+
+// Dataset combinators.
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/sugarme/gotch/ts"
+)
+
+// mappedDataset applies a function to samples of a dataset.
+type mappedDataset[T, U any] struct {
+	ds Dataset
+	fn func(T) (U, error)
+}
+
+// Map returns a dataset of samples of ds transformed by fn on access. Item returns an
+// error if a sample is not of type T.
+func Map[T, U any](ds Dataset, fn func(item T) (U, error)) Dataset {
+	return &mappedDataset[T, U]{ds, fn}
+}
+
+// Item implements Dataset interface.
+func (m *mappedDataset[T, U]) Item(idx int) (interface{}, error) {
+	item, err := m.ds.Item(idx)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := item.(T)
+	if !ok {
+		var zero T
+		err := fmt.Errorf("Map - invalid item type: expected %T, got %T", zero, item)
+		return nil, err
+	}
+	return m.fn(v)
+}
+
+// Len implements Dataset interface.
+func (m *mappedDataset[T, U]) Len() int {
+	return m.ds.Len()
+}
+
+// DType implements Dataset interface.
+func (m *mappedDataset[T, U]) DType() reflect.Type {
+	return reflect.TypeOf([]U(nil))
+}
+
+// SubsetDataset is a subset of a dataset at given indices.
+type SubsetDataset struct {
+	ds      Dataset
+	indices []int
+}
+
+// Subset creates a subset of ds at given indices, e.g. `Fold.Train` of a Splitter or
+// indices of a sampler.
+func Subset(ds Dataset, indices []int) (*SubsetDataset, error) {
+	n := ds.Len()
+	for _, idx := range indices {
+		if idx < 0 || idx >= n {
+			err := fmt.Errorf("Subset() failed: index %v out of range [0, %v)", idx, n)
+			return nil, err
+		}
+	}
+
+	return &SubsetDataset{ds, indices}, nil
+}
+
+// Item implements Dataset interface.
+func (s *SubsetDataset) Item(idx int) (interface{}, error) {
+	if idx < 0 || idx >= len(s.indices) {
+		err := fmt.Errorf("Idx is out of range.")
+		return nil, err
+	}
+	return s.ds.Item(s.indices[idx])
+}
+
+// Len implements Dataset interface.
+func (s *SubsetDataset) Len() int {
+	return len(s.indices)
+}
+
+// DType implements Dataset interface.
+func (s *SubsetDataset) DType() reflect.Type {
+	return s.ds.DType()
+}
+
+// Indices returns indices of the subset in the original dataset.
+func (s *SubsetDataset) Indices() []int {
+	return s.indices
+}
+
+// FoldSubsets returns train and test subsets of ds for a Fold.
+func FoldSubsets(ds Dataset, fold Fold) (train, test *SubsetDataset, err error) {
+	if train, err = Subset(ds, fold.Train); err != nil {
+		return nil, nil, err
+	}
+	if test, err = Subset(ds, fold.Test); err != nil {
+		return nil, nil, err
+	}
+	return train, test, nil
+}
+
+// Filter returns the subset of ds samples for which keep returns true. All samples are
+// read once on creation. It returns an error if a sample is not of type T.
+func Filter[T any](ds Dataset, keep func(item T) bool) (*SubsetDataset, error) {
+	var indices []int
+	for i := 0; i < ds.Len(); i++ {
+		item, err := ds.Item(i)
+		if err != nil {
+			err = fmt.Errorf("Filter() failed: %w", err)
+			return nil, err
+		}
+		v, ok := item.(T)
+		if !ok {
+			var zero T
+			err := fmt.Errorf("Filter() failed: invalid item type: expected %T, got %T", zero, item)
+			return nil, err
+		}
+		if keep(v) {
+			indices = append(indices, i)
+		}
+	}
+
+	return &SubsetDataset{ds, indices}, nil
+}
+
+// RandomSplit randomly splits ds into non-overlapping subsets of given fractions of samples.
+// Fractions should sum up to 1. Samples left over by rounding down are given one by one to
+// subsets with the largest remainders; subsets of zero fraction stay empty.
+func RandomSplit(ds Dataset, fractions []float64, seed int64) ([]*SubsetDataset, error) {
+	if len(fractions) == 0 {
+		err := fmt.Errorf("RandomSplit() failed: empty fractions")
+		return nil, err
+	}
+	var sum float64
+	for _, f := range fractions {
+		if f < 0 {
+			err := fmt.Errorf("RandomSplit() failed: negative fraction %v", f)
+			return nil, err
+		}
+		sum += f
+	}
+	if math.Abs(sum-1) > 1e-6 {
+		err := fmt.Errorf("RandomSplit() failed: fractions should sum up to 1. Got %v", sum)
+		return nil, err
+	}
+
+	n := ds.Len()
+	sizes := make([]int, len(fractions))
+	remainders := make([]float64, len(fractions))
+	var order []int // subsets of non-zero fraction by decreasing remainder.
+	total := 0
+	for i, f := range fractions {
+		x := f * float64(n)
+		sizes[i] = int(math.Floor(x))
+		remainders[i] = x - float64(sizes[i])
+		total += sizes[i]
+		if f > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return remainders[order[i]] > remainders[order[j]]
+	})
+	for k := 0; total < n; k = (k + 1) % len(order) {
+		sizes[order[k]]++
+		total++
+	}
+
+	perm := rand.New(rand.NewSource(seed)).Perm(n)
+	subsets := make([]*SubsetDataset, len(sizes))
+	start := 0
+	for i, size := range sizes {
+		subsets[i] = &SubsetDataset{ds, perm[start : start+size]}
+		start += size
+	}
+
+	return subsets, nil
+}
+
+// ConcatDataset is a concatenation of datasets.
+type ConcatDataset struct {
+	datasets []Dataset
+	offsets  []int // cumulative number of samples, offsets[i] is the end of datasets[i].
+}
+
+// Concat concatenates datasets of the same DType.
+func Concat(datasets ...Dataset) (*ConcatDataset, error) {
+	if len(datasets) == 0 {
+		err := fmt.Errorf("Concat() failed: no datasets")
+		return nil, err
+	}
+
+	dtype := datasets[0].DType()
+	offsets := make([]int, len(datasets))
+	total := 0
+	for i, ds := range datasets {
+		if ds.DType() != dtype {
+			err := fmt.Errorf("Concat() failed: mismatched dataset types %v and %v", dtype, ds.DType())
+			return nil, err
+		}
+		total += ds.Len()
+		offsets[i] = total
+	}
+
+	return &ConcatDataset{datasets, offsets}, nil
+}
+
+// Item implements Dataset interface.
+func (c *ConcatDataset) Item(idx int) (interface{}, error) {
+	if idx < 0 || idx >= c.Len() {
+		err := fmt.Errorf("Idx is out of range.")
+		return nil, err
+	}
+
+	i := sort.SearchInts(c.offsets, idx+1)
+	start := 0
+	if i > 0 {
+		start = c.offsets[i-1]
+	}
+	return c.datasets[i].Item(idx - start)
+}
+
+// Len implements Dataset interface.
+func (c *ConcatDataset) Len() int {
+	return c.offsets[len(c.offsets)-1]
+}
+
+// DType implements Dataset interface.
+func (c *ConcatDataset) DType() reflect.Type {
+	return c.datasets[0].DType()
+}
+
+type CacheOptions struct {
+	Dir string // directory to cache samples encoded by encoding/gob. Default="" (cache in memory)
+}
+
+type CacheOption func(*CacheOptions)
+
+func NewCacheOptions(options ...CacheOption) CacheOptions {
+	opts := CacheOptions{
+		Dir: "",
+	}
+
+	for _, o := range options {
+		o(&opts)
+	}
+
+	return opts
+}
+
+// WithCacheDir caches samples in files of dir instead of memory. Samples should be
+// encodable by encoding/gob (e.g. tensors are not) and the dataset element type should
+// be concrete (e.g. not a SliceDataset of []interface{}) to be decoded.
+func WithCacheDir(dir string) CacheOption {
+	return func(o *CacheOptions) {
+		o.Dir = dir
+	}
+}
+
+// CacheDataset memoizes samples of a dataset, e.g. of an expensive Map. It is safe for
+// concurrent use.
+//
+// NOTE. Tensor samples cached in memory are returned as shallow clones owned by the
+// caller. Other cached samples are returned as they are.
+type CacheDataset struct {
+	ds    Dataset
+	dir   string
+	mu    sync.Mutex
+	items map[int]interface{}
+}
+
+// Cache creates a dataset memoizing `Item()` results of ds. Errors are not cached.
+func Cache(ds Dataset, opts ...CacheOption) (*CacheDataset, error) {
+	o := NewCacheOptions(opts...)
+
+	if o.Dir != "" {
+		if kind := ds.DType().Elem().Kind(); kind == reflect.Interface {
+			err := fmt.Errorf("Cache() failed: cannot decode cached samples of interface type %v. Dataset element type should be concrete", ds.DType().Elem())
+			return nil, err
+		}
+		if err := os.MkdirAll(o.Dir, 0755); err != nil {
+			err = fmt.Errorf("Cache() failed: %w", err)
+			return nil, err
+		}
+	}
+
+	return &CacheDataset{
+		ds:    ds,
+		dir:   o.Dir,
+		items: make(map[int]interface{}),
+	}, nil
+}
+
+// Item implements Dataset interface.
+func (c *CacheDataset) Item(idx int) (interface{}, error) {
+	if c.dir != "" {
+		return c.fileItem(idx)
+	}
+
+	c.mu.Lock()
+	item, ok := c.items[idx]
+	c.mu.Unlock()
+	if ok {
+		return cloneSample(item), nil
+	}
+
+	item, err := c.ds.Item(idx)
+	if err != nil {
+		return nil, err
+	}
+	// Another caller may have cached the sample meanwhile: keep the cached one so that
+	// all callers share the same sample, and drop ours.
+	c.mu.Lock()
+	cached, ok := c.items[idx]
+	if !ok {
+		c.items[idx] = item
+	}
+	c.mu.Unlock()
+	if ok {
+		dropBatch(item)
+		return cloneSample(cached), nil
+	}
+
+	return cloneSample(item), nil
+}
+
+// cloneSample returns a shallow clone of a tensor sample so that callers can drop it
+// without freeing the cached tensor.
+func cloneSample(item interface{}) interface{} {
+	if x, ok := item.(*ts.Tensor); ok && x != nil {
+		return x.MustShallowClone()
+	}
+	return item
+}
+
+func (c *CacheDataset) cacheFile(idx int) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%d.gob", idx))
+}
+
+// fileItem decodes a cached sample from file or caches the sample of the dataset.
+func (c *CacheDataset) fileItem(idx int) (interface{}, error) {
+	path := c.cacheFile(idx)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		elem := reflect.New(c.ds.DType().Elem())
+		if err := gob.NewDecoder(bytes.NewReader(data)).DecodeValue(elem); err != nil {
+			err = fmt.Errorf("CacheDataset - decoding sample %v failed: %w", idx, err)
+			return nil, err
+		}
+		return elem.Elem().Interface(), nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	item, err := c.ds.Item(idx)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(item); err != nil {
+		err = fmt.Errorf("CacheDataset - encoding sample %v failed: %w", idx, err)
+		return nil, err
+	}
+	// Write to a temporary file first so that concurrent readers never see a partial file.
+	f, err := os.CreateTemp(c.dir, "tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// Len implements Dataset interface.
+func (c *CacheDataset) Len() int {
+	return c.ds.Len()
+}
+
+// DType implements Dataset interface.
+func (c *CacheDataset) DType() reflect.Type {
+	return c.ds.DType()
+}
+
+// Clear removes all cached samples.
+func (c *CacheDataset) Clear() error {
+	c.mu.Lock()
+	for _, item := range c.items {
+		if x, ok := item.(*ts.Tensor); ok && x != nil {
+			x.MustDrop()
+		}
+	}
+	c.items = make(map[int]interface{})
+	c.mu.Unlock()
+
+	if c.dir == "" {
+		return nil
+	}
+	for i := 0; i < c.ds.Len(); i++ {
+		if err := os.Remove(c.cacheFile(i)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ShuffleBuffer iterates samples of a dataset in approximately random order using a
+// bounded buffer: samples are read sequentially into the buffer and a random sample of the
+// buffer is returned. Unlike a RandomSampler, reads are sequential which suits datasets
+// with expensive random access (e.g. streamed from disk).
+type ShuffleBuffer struct {
+	ds     Dataset
+	size   int
+	r      *rand.Rand
+	buffer []interface{}
+	next   int // index of next sample to read into buffer.
+}
+
+// NewShuffleBuffer creates a ShuffleBuffer of given size. A size of 1 returns samples in order.
+func NewShuffleBuffer(ds Dataset, size int, seed int64) (*ShuffleBuffer, error) {
+	if size < 1 {
+		err := fmt.Errorf("NewShuffleBuffer() failed: buffer size should be at least 1. Got %v", size)
+		return nil, err
+	}
+
+	return &ShuffleBuffer{
+		ds:     ds,
+		size:   size,
+		r:      rand.New(rand.NewSource(seed)),
+		buffer: make([]interface{}, 0, size),
+	}, nil
+}
+
+// HasNext returns whether there is a next sample in the iteration.
+func (b *ShuffleBuffer) HasNext() bool {
+	return len(b.buffer) > 0 || b.next < b.ds.Len()
+}
+
+// Next returns next sample. It returns io.EOF at the end of the iteration.
+func (b *ShuffleBuffer) Next() (interface{}, error) {
+	for len(b.buffer) < b.size && b.next < b.ds.Len() {
+		item, err := b.ds.Item(b.next)
+		if err != nil {
+			return nil, err
+		}
+		b.buffer = append(b.buffer, item)
+		b.next++
+	}
+
+	n := len(b.buffer)
+	if n == 0 {
+		return nil, io.EOF
+	}
+	i := b.r.Intn(n)
+	item := b.buffer[i]
+	b.buffer[i] = b.buffer[n-1]
+	b.buffer = b.buffer[:n-1]
+
+	return item, nil
+}
+
+// Reset restarts the iteration. Random state continues so that every iteration is shuffled
+// differently.
+func (b *ShuffleBuffer) Reset() {
+	b.buffer = b.buffer[:0]
+	b.next = 0
+}
+
+// Len returns number of samples to be iterated.
+func (b *ShuffleBuffer) Len() int {
+	return b.ds.Len()
+}
@@ -0,0 +1,363 @@
+package dutil_test
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sugarme/gotch/dutil"
+	"github.com/sugarme/gotch/ts"
+)
+
+func newIntDataset(t *testing.T, n int) *dutil.SliceDataset {
+	t.Helper()
+	data := make([]int, n)
+	for i := range data {
+		data[i] = i
+	}
+	ds, err := dutil.NewSliceDataset(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ds
+}
+
+func items(t *testing.T, ds dutil.Dataset) []interface{} {
+	t.Helper()
+	var out []interface{}
+	for i := 0; i < ds.Len(); i++ {
+		item, err := ds.Item(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+func TestMapFilter(t *testing.T) {
+	ds := newIntDataset(t, 6)
+	mapped := dutil.Map(ds, func(x int) (string, error) {
+		return fmt.Sprintf("s%d", x), nil
+	})
+	if mapped.Len() != 6 || mapped.DType() != reflect.TypeOf([]string{}) {
+		t.Errorf("want 6 samples of []string, got %v of %v\n", mapped.Len(), mapped.DType())
+	}
+	if got, _ := mapped.Item(2); got != "s2" {
+		t.Errorf("want s2, got %v\n", got)
+	}
+
+	even, err := dutil.Filter(ds, func(x int) bool { return x%2 == 0 })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{0, 2, 4}
+	if got := items(t, even); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v\n", want, got)
+	}
+
+	if _, err := dutil.Filter(ds, func(x string) bool { return true }); err == nil {
+		t.Errorf("want error for mismatched item type\n")
+	}
+}
+
+func TestConcatSubset(t *testing.T) {
+	ds1 := newIntDataset(t, 3)
+	ds2 := newIntDataset(t, 2)
+	c, err := dutil.Concat(ds1, ds2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{0, 1, 2, 0, 1}
+	if got := items(t, c); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v\n", want, got)
+	}
+	if _, err := c.Item(5); err == nil {
+		t.Errorf("want out of range error\n")
+	}
+
+	strs, _ := dutil.NewSliceDataset([]string{"a"})
+	if _, err := dutil.Concat(ds1, strs); err == nil {
+		t.Errorf("want error for mismatched dataset types\n")
+	}
+
+	sub, err := dutil.Subset(c, []int{4, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []interface{}{1, 0}
+	if got := items(t, sub); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v\n", want, got)
+	}
+	if _, err := dutil.Subset(c, []int{5}); err == nil {
+		t.Errorf("want out of range error\n")
+	}
+}
+
+func TestFoldSubsets(t *testing.T) {
+	ds := newIntDataset(t, 10)
+	kf, err := dutil.NewKFold(ds.Len(), dutil.WithNFolds(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fold := range kf.Split() {
+		train, test, err := dutil.FoldSubsets(ds, fold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if train.Len() != 8 || test.Len() != 2 {
+			t.Errorf("want 8 train and 2 test samples, got %v and %v\n", train.Len(), test.Len())
+		}
+
+		dl, err := dutil.NewDataLoader(train, dutil.NewSequentialSampler(train.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		batch, err := dl.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if batch.([]int)[0] != fold.Train[0] {
+			t.Errorf("want first sample %v, got %v\n", fold.Train[0], batch)
+		}
+	}
+}
+
+func TestRandomSplit(t *testing.T) {
+	ds := newIntDataset(t, 10)
+	subsets, err := dutil.RandomSplit(ds, []float64{0.5, 0.25, 0.25}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sizes []int
+	var all []int
+	for _, s := range subsets {
+		sizes = append(sizes, s.Len())
+		all = append(all, s.Indices()...)
+	}
+	// 5, 2.5 and 2.5 samples: the left over sample goes to the first largest remainder.
+	if want := []int{5, 3, 2}; !reflect.DeepEqual(want, sizes) {
+		t.Errorf("want sizes %v, got %v\n", want, sizes)
+	}
+	sort.Ints(all)
+	if want := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}; !reflect.DeepEqual(want, all) {
+		t.Errorf("want all indices %v, got %v\n", want, all)
+	}
+
+	again, _ := dutil.RandomSplit(ds, []float64{0.5, 0.25, 0.25}, 1)
+	if !reflect.DeepEqual(subsets[0].Indices(), again[0].Indices()) {
+		t.Errorf("want same split for same seed\n")
+	}
+
+	// Zero fraction gets no left over samples.
+	small := newIntDataset(t, 3)
+	zero, err := dutil.RandomSplit(small, []float64{0, 0.5, 0.5}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := []int{zero[0].Len(), zero[1].Len(), zero[2].Len()}; !reflect.DeepEqual([]int{0, 2, 1}, got) {
+		t.Errorf("want sizes [0 2 1], got %v\n", got)
+	}
+
+	if _, err := dutil.RandomSplit(ds, []float64{0.5, 0.4}, 1); err == nil {
+		t.Errorf("want error for fractions not summing up to 1\n")
+	}
+}
+
+type point struct {
+	X, Y int
+}
+
+func TestCache(t *testing.T) {
+	for _, dir := range []string{"", t.TempDir()} {
+		calls := 0
+		ds := dutil.Map(newIntDataset(t, 4), func(x int) (point, error) {
+			calls++
+			return point{x, x * x}, nil
+		})
+		cached, err := dutil.Cache(ds, dutil.WithCacheDir(dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := items(t, cached)
+		second := items(t, cached)
+		if calls != 4 {
+			t.Errorf("want 4 calls of Map function, got %v\n", calls)
+		}
+		if !reflect.DeepEqual(first, second) || second[3] != (point{3, 9}) {
+			t.Errorf("want cached samples %v, got %v\n", first, second)
+		}
+
+		if err := cached.Clear(); err != nil {
+			t.Fatal(err)
+		}
+		items(t, cached)
+		if calls != 8 {
+			t.Errorf("want 8 calls of Map function after Clear, got %v\n", calls)
+		}
+	}
+}
+
+func TestCache_DataLoader(t *testing.T) {
+	data := make([]*ts.Tensor, 5)
+	for i := range data {
+		data[i] = ts.MustOfSlice([]float64{float64(i)})
+	}
+	ds, err := dutil.NewSliceDataset(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached, err := dutil.Cache(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := dutil.NewBatchSampler(cached.Len(), 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dl, err := dutil.NewDataLoader(cached, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Samples dropped by the loader or the caller leave cached samples valid.
+	for epoch := 0; epoch < 2; epoch++ {
+		var got []float64
+		for dl.HasNext() {
+			batch, err := dl.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, x := range batch.([]*ts.Tensor) {
+				got = append(got, x.Float64Values()[0])
+				x.MustDrop()
+			}
+		}
+		if !reflect.DeepEqual(got, []float64{0, 1, 2, 3, 4}) {
+			t.Errorf("epoch %v: want samples [0 1 2 3 4], got %v\n", epoch, got)
+		}
+		dl.Reset()
+	}
+
+	if err := cached.Clear(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCache_InterfaceDir(t *testing.T) {
+	ds, err := dutil.NewSliceDataset([]interface{}{1, "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dutil.Cache(ds, dutil.WithCacheDir(t.TempDir())); err == nil {
+		t.Errorf("want error caching samples of interface type to files\n")
+	}
+	// Memory cache supports any sample.
+	cached, err := dutil.Cache(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items(t, cached)
+	if got := items(t, cached); !reflect.DeepEqual(got, []interface{}{1, "a"}) {
+		t.Errorf("want cached samples [1 a], got %v\n", got)
+	}
+}
+
+// dropSample counts drops of samples.
+type dropSample struct {
+	drops *int32
+}
+
+func (s *dropSample) Drop() {
+	atomic.AddInt32(s.drops, 1)
+}
+
+func TestCache_Concurrent(t *testing.T) {
+	var (
+		drops   int32
+		started sync.WaitGroup
+	)
+	// Both callers load the sample before either caches it.
+	started.Add(2)
+	ds := dutil.Map(newIntDataset(t, 1), func(x int) (*dropSample, error) {
+		started.Done()
+		started.Wait()
+		return &dropSample{&drops}, nil
+	})
+	cached, err := dutil.Cache(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]interface{}, 2)
+	var wg sync.WaitGroup
+	for i := range got {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			item, err := cached.Item(0)
+			if err != nil {
+				t.Error(err)
+			}
+			got[i] = item
+		}(i)
+	}
+	wg.Wait()
+
+	if got[0] != got[1] {
+		t.Errorf("want callers to share the cached sample, got %p and %p\n", got[0], got[1])
+	}
+	if drops != 1 {
+		t.Errorf("want the duplicate sample dropped once, got %v drops\n", drops)
+	}
+}
+
+func TestShuffleBuffer(t *testing.T) {
+	ds := newIntDataset(t, 20)
+	b, err := dutil.NewShuffleBuffer(ds, 5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []int
+	for b.HasNext() {
+		item, err := b.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// A sample is returned at most size-1 positions before it is read.
+		if x := item.(int); x > len(got)+4 {
+			t.Errorf("sample %v returned at position %v\n", x, len(got))
+		}
+		got = append(got, item.(int))
+	}
+	if _, err := b.Next(); err != io.EOF {
+		t.Errorf("want io.EOF, got %v\n", err)
+	}
+
+	sorted := append([]int{}, got...)
+	sort.Ints(sorted)
+	if !reflect.DeepEqual(items(t, ds), toInterfaces(sorted)) {
+		t.Errorf("want all samples, got %v\n", got)
+	}
+	if reflect.DeepEqual(sorted, got) {
+		t.Errorf("want shuffled samples, got %v\n", got)
+	}
+
+	b.Reset()
+	if !b.HasNext() {
+		t.Errorf("want samples after Reset\n")
+	}
+}
+
+func toInterfaces(xs []int) []interface{} {
+	out := make([]interface{}, len(xs))
+	for i, x := range xs {
+		out[i] = x
+	}
+	return out
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"sort"
+	"time"
 )
 
 // KFold represents a struct helper to
@@ -12,7 +13,7 @@ type KFold struct {
 	n       int
 	nfolds  int
 	shuffle bool
-	//seed int
+	r       *rand.Rand
 }
 
 // Fold represents a partitions with
@@ -22,9 +23,16 @@ type Fold struct {
 	Test  []int
 }
 
+// Splitter is an interface to split data into train and test folds
+// for cross-validation.
+type Splitter interface {
+	Split() []Fold
+}
+
 type KFoldOptions struct {
-	NFolds  int  // number of folds
-	Shuffle bool // whether suffling before splitting
+	NFolds  int   // number of folds
+	Shuffle bool  // whether suffling before splitting
+	Seed    int64 // seed of random shuffling. Default=current time in nanoseconds
 }
 
 type KFoldOption func(*KFoldOptions)
@@ -33,6 +41,7 @@ func NewKFoldOptions(options ...KFoldOption) KFoldOptions {
 	opts := KFoldOptions{
 		NFolds:  5,
 		Shuffle: false,
+		Seed:    time.Now().UnixNano(),
 	}
 
 	for _, o := range options {
@@ -54,6 +63,12 @@ func WithKFoldShuffle(shuffle bool) KFoldOption {
 	}
 }
 
+func WithKFoldSeed(seed int64) KFoldOption {
+	return func(o *KFoldOptions) {
+		o.Seed = seed
+	}
+}
+
 // NewKFold creates a new KFold struct.
 func NewKFold(n int, opt ...KFoldOption) (*KFold, error) {
 	opts := NewKFoldOptions(opt...)
@@ -72,20 +87,27 @@ func NewKFold(n int, opt ...KFoldOption) (*KFold, error) {
 		n:       n,
 		nfolds:  opts.NFolds,
 		shuffle: opts.Shuffle,
+		r:       rand.New(rand.NewSource(opts.Seed)),
 	}, nil
 }
 
+// Split implements Splitter interface.
 func (kf *KFold) Split() []Fold {
-	odd := kf.n % kf.nfolds
-	nsamples := kf.n - odd
-	fsize := nsamples / kf.nfolds
+	return kfoldSplit(kf.r.Perm(kf.n), kf.nfolds, kf.shuffle)
+}
+
+// kfoldSplit splits permuted indices into nfolds folds. Last odd-time elements are dropped.
+func kfoldSplit(allIndices []int, nfolds int, shuffle bool) []Fold {
+	n := len(allIndices)
+	odd := n % nfolds
+	nsamples := n - odd
+	fsize := nsamples / nfolds
 	var indices []int
 
-	allIndices := rand.Perm(kf.n)
 	// Drop last odd-time elements
 	indices = allIndices[:nsamples]
 
-	if !kf.shuffle {
+	if !shuffle {
 		sort.Ints(indices)
 	}
 
@@ -103,7 +125,7 @@ func (kf *KFold) Split() []Fold {
 	}
 
 	var splits []Fold
-	for i := 0; i < kf.nfolds; i++ {
+	for i := 0; i < nfolds; i++ {
 		test := folds[i]
 		var trainFolds [][]int
 		trainFolds = append(trainFolds, folds[:i]...)
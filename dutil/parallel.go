@@ -0,0 +1,365 @@
+package dutil
+
+// Parallel, prefetching data loader.
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/ts"
+)
+
+// RandDataset is implemented by datasets with random transforms (e.g. image augmentations).
+// ParallelDataLoader workers call ItemRand with a random source of the batch seeded from
+// the loader seed, epoch and batch index so that loading is reproducible for a given seed
+// and sampler order, regardless of number of workers, prefetching and cancellation.
+type RandDataset[T any] interface {
+	TypedDataset[T]
+	ItemRand(idx int, r *rand.Rand) (T, error)
+}
+
+// ToDevicer is implemented by batches that can be transferred to a device.
+type ToDevicer[B any] interface {
+	ToDevice(device gotch.Device) (B, error)
+}
+
+type ParallelOptions struct {
+	NumWorkers int          // number of worker goroutines. Default=2
+	Prefetch   int          // number of batches buffered ahead of the consumer. Default=2*NumWorkers
+	Seed       int64        // seed of random sources of batches (see RandDataset). Default=0
+	ToDevice   bool         // whether to transfer collated batches to Device. Default=false
+	Device     gotch.Device // device to transfer batches to.
+}
+
+type ParallelOption func(*ParallelOptions)
+
+func NewParallelOptions(options ...ParallelOption) ParallelOptions {
+	opts := ParallelOptions{
+		NumWorkers: 2,
+		Seed:       0,
+		ToDevice:   false,
+		Device:     gotch.CPU,
+	}
+
+	for _, o := range options {
+		o(&opts)
+	}
+	if opts.Prefetch == 0 {
+		opts.Prefetch = 2 * opts.NumWorkers
+	}
+
+	return opts
+}
+
+func WithNumWorkers(n int) ParallelOption {
+	return func(o *ParallelOptions) {
+		o.NumWorkers = n
+	}
+}
+
+func WithPrefetch(n int) ParallelOption {
+	return func(o *ParallelOptions) {
+		o.Prefetch = n
+	}
+}
+
+func WithSeed(seed int64) ParallelOption {
+	return func(o *ParallelOptions) {
+		o.Seed = seed
+	}
+}
+
+// WithDevice transfers collated batches to device in worker goroutines.
+// Batches should be *ts.Tensor, []*ts.Tensor, map[string]*ts.Tensor or implement ToDevicer.
+func WithDevice(device gotch.Device) ParallelOption {
+	return func(o *ParallelOptions) {
+		o.ToDevice = true
+		o.Device = device
+	}
+}
+
+type batchResult[B any] struct {
+	batch B
+	err   error
+}
+
+// ParallelDataLoader loads batches with a pool of worker goroutines that fetch, transform
+// and collate samples ahead of the consumer.
+//
+// Batch k is loaded by worker k%NumWorkers so batches are returned in sampler order.
+// At most Prefetch batches are buffered ahead.
+type ParallelDataLoader[T, B any] struct {
+	dataset   TypedDataset[T]
+	sampler   Sampler
+	collate   Collate[T, B]
+	opts      ParallelOptions
+	batches   [][]int // sample indexes of batches of the current epoch.
+	indexes   []int
+	batchSize int
+	next      int // index of next batch to return.
+	epoch     int // number of Reset calls, used to seed batches.
+
+	// running workers.
+	cancel context.CancelFunc
+	outs   []chan batchResult[B]
+	wg     sync.WaitGroup
+}
+
+// NewParallelDataLoader creates a new ParallelDataLoader. If sampler is nil, samples are
+// drawn sequentially one by one. Batch size is taken from the sampler (e.g. BatchSampler).
+//
+// Workers start at the first call to Next of an epoch. Close should be called to stop
+// workers if the loader is not iterated to the end.
+func NewParallelDataLoader[T, B any](data TypedDataset[T], s Sampler, collate Collate[T, B], opts ...ParallelOption) (*ParallelDataLoader[T, B], error) {
+	o := NewParallelOptions(opts...)
+	if o.NumWorkers < 1 || o.Prefetch < 1 {
+		err := fmt.Errorf("NewParallelDataLoader() failed: invalid number of workers (%v) or prefetch (%v)", o.NumWorkers, o.Prefetch)
+		return nil, err
+	}
+	if collate == nil {
+		err := fmt.Errorf("NewParallelDataLoader() failed: nil collate function")
+		return nil, err
+	}
+	if s == nil {
+		s = NewSequentialSampler(data.Len())
+	}
+
+	dl := &ParallelDataLoader[T, B]{
+		dataset:   data,
+		sampler:   s,
+		collate:   collate,
+		opts:      o,
+		batchSize: s.BatchSize(),
+	}
+	dl.setIndexes(s.Sample())
+
+	return dl, nil
+}
+
+func (dl *ParallelDataLoader[T, B]) setIndexes(indexes []int) {
+	dl.indexes = indexes
+	dl.batches = nil
+	for start := 0; start < len(indexes); start += dl.batchSize {
+		end := start + dl.batchSize
+		if end > len(indexes) {
+			end = len(indexes)
+		}
+		dl.batches = append(dl.batches, indexes[start:end])
+	}
+	dl.next = 0
+}
+
+// start starts workers loading batches from dl.next.
+func (dl *ParallelDataLoader[T, B]) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	dl.cancel = cancel
+
+	n := dl.opts.NumWorkers
+	capacity := (dl.opts.Prefetch + n - 1) / n
+	dl.outs = make([]chan batchResult[B], n)
+	for w := 0; w < n; w++ {
+		dl.outs[w] = make(chan batchResult[B], capacity)
+
+		// First batch k >= dl.next with k%n == w.
+		first := dl.next + (w-dl.next%n+n)%n
+		dl.wg.Add(1)
+		go dl.work(ctx, w, first, dl.epoch)
+	}
+}
+
+// mix64 is the finalizer of splitmix64 hash.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// batchRand returns random source of batch k of an epoch.
+func batchRand(seed int64, epoch, k int) *rand.Rand {
+	x := mix64(mix64(mix64(uint64(seed))^uint64(epoch)) ^ uint64(k))
+	return rand.New(rand.NewSource(int64(x)))
+}
+
+func (dl *ParallelDataLoader[T, B]) work(ctx context.Context, w, first, epoch int) {
+	defer dl.wg.Done()
+	defer close(dl.outs[w])
+
+	for k := first; k < len(dl.batches); k += dl.opts.NumWorkers {
+		if ctx.Err() != nil {
+			return
+		}
+		batch, err := dl.load(dl.batches[k], batchRand(dl.opts.Seed, epoch, k))
+		select {
+		case dl.outs[w] <- batchResult[B]{batch, err}:
+		case <-ctx.Done():
+			dropBatch(batch)
+			return
+		}
+	}
+}
+
+// load fetches and collates samples of a batch.
+func (dl *ParallelDataLoader[T, B]) load(indexes []int, r *rand.Rand) (B, error) {
+	var zero B
+	rd, isRand := dl.dataset.(RandDataset[T])
+
+	items := make([]T, 0, len(indexes))
+	for _, idx := range indexes {
+		var (
+			item T
+			err  error
+		)
+		if isRand {
+			item, err = rd.ItemRand(idx, r)
+		} else {
+			item, err = dl.dataset.Item(idx)
+		}
+		if err != nil {
+			return zero, err
+		}
+		items = append(items, item)
+	}
+
+	batch, err := dl.collate(items)
+	if err != nil || !dl.opts.ToDevice {
+		return batch, err
+	}
+	return batchToDevice(batch, dl.opts.Device)
+}
+
+// stop stops running workers and drops prefetched batches.
+func (dl *ParallelDataLoader[T, B]) stop() {
+	if dl.cancel == nil {
+		return
+	}
+	dl.cancel()
+	for _, out := range dl.outs {
+		for res := range out {
+			dropBatch(res.batch)
+		}
+	}
+	dl.wg.Wait()
+	dl.cancel = nil
+	dl.outs = nil
+}
+
+// HasNext returns whether there is a next batch in the iteration.
+func (dl *ParallelDataLoader[T, B]) HasNext() bool {
+	return dl.next < len(dl.batches)
+}
+
+// Next returns next batch. If ctx is done before the batch is ready, workers are stopped
+// and ctx.Err() is returned; a later call resumes loading from the same batch.
+func (dl *ParallelDataLoader[T, B]) Next(ctx context.Context) (B, error) {
+	var zero B
+	if !dl.HasNext() {
+		err := fmt.Errorf("Next Error: no more item to iterate.\n")
+		return zero, err
+	}
+	if dl.cancel == nil {
+		dl.start()
+	}
+
+	select {
+	case res := <-dl.outs[dl.next%dl.opts.NumWorkers]:
+		dl.next++
+		if !dl.HasNext() {
+			dl.stop()
+		}
+		return res.batch, res.err
+	case <-ctx.Done():
+		dl.stop()
+		return zero, ctx.Err()
+	}
+}
+
+// Reset stops workers and resets index to start position for a new epoch. If shuffle is
+// true, indexes are re-sampled.
+func (dl *ParallelDataLoader[T, B]) Reset(shuffleOpt ...bool) {
+	dl.stop()
+	dl.epoch++
+	if len(shuffleOpt) > 0 && shuffleOpt[0] {
+		dl.setIndexes(dl.sampler.Sample())
+		return
+	}
+	dl.next = 0
+}
+
+// Close stops workers and drops prefetched batches.
+func (dl *ParallelDataLoader[T, B]) Close() {
+	dl.stop()
+}
+
+// Len returns number of samples to be iterated.
+func (dl *ParallelDataLoader[T, B]) Len() int {
+	return len(dl.indexes)
+}
+
+// batchToDevice transfers a batch to device. Tensors of the batch are dropped on success.
+// On error, the batch is returned unchanged and transferred tensors are dropped.
+func batchToDevice[B any](batch B, device gotch.Device) (B, error) {
+	switch b := any(batch).(type) {
+	case *ts.Tensor:
+		x, err := b.To(device, false)
+		if err != nil {
+			return batch, err
+		}
+		b.MustDrop()
+		return any(x).(B), nil
+	case []*ts.Tensor:
+		xs := make([]*ts.Tensor, 0, len(b))
+		for _, x := range b {
+			moved, err := x.To(device, false)
+			if err != nil {
+				dropBatch(xs)
+				return batch, err
+			}
+			xs = append(xs, moved)
+		}
+		dropBatch(b)
+		return any(xs).(B), nil
+	case map[string]*ts.Tensor:
+		xs := make(map[string]*ts.Tensor, len(b))
+		for k, x := range b {
+			moved, err := x.To(device, false)
+			if err != nil {
+				dropTensors(xs)
+				return batch, err
+			}
+			xs[k] = moved
+		}
+		dropTensors(b)
+		return any(xs).(B), nil
+	case ToDevicer[B]:
+		return b.ToDevice(device)
+	default:
+		err := fmt.Errorf("ToDevice failed: unsupported batch type %T. Batch should be *ts.Tensor, []*ts.Tensor, map[string]*ts.Tensor or implement ToDevicer", batch)
+		return batch, err
+	}
+}
+
+// dropBatch frees tensors of a discarded batch.
+func dropBatch(batch interface{}) {
+	switch b := batch.(type) {
+	case *ts.Tensor:
+		if b != nil {
+			b.MustDrop()
+		}
+	case []*ts.Tensor:
+		for _, x := range b {
+			if x != nil {
+				x.MustDrop()
+			}
+		}
+	case map[string]*ts.Tensor:
+		dropTensors(b)
+	case interface{ Drop() }:
+		b.Drop()
+	}
+}
@@ -0,0 +1,198 @@
+package dutil_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch/dutil"
+)
+
+// noisyDataset returns idx*100 plus a random offset drawn from the worker random source.
+type noisyDataset struct {
+	n int
+}
+
+func (ds *noisyDataset) Item(idx int) (int, error) {
+	if idx >= ds.n {
+		return 0, fmt.Errorf("Idx is out of range.")
+	}
+	return idx * 100, nil
+}
+
+func (ds *noisyDataset) ItemRand(idx int, r *rand.Rand) (int, error) {
+	item, err := ds.Item(idx)
+	return item + r.Intn(100), err
+}
+
+func (ds *noisyDataset) Len() int {
+	return ds.n
+}
+
+func loadAll(t *testing.T, dl *dutil.ParallelDataLoader[int, []int]) [][]int {
+	t.Helper()
+	var got [][]int
+	for dl.HasNext() {
+		b, err := dl.Next(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, b)
+	}
+	return got
+}
+
+func TestParallelDataLoader(t *testing.T) {
+	ds := &noisyDataset{10}
+	s, err := dutil.NewBatchSampler(ds.Len(), 3, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dl, err := dutil.NewParallelDataLoader[int, []int](ds, s, dutil.CollateSlice[int], dutil.WithNumWorkers(3), dutil.WithPrefetch(2), dutil.WithSeed(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := loadAll(t, dl)
+
+	// Batches are in sampler order.
+	if len(got) != 4 || len(got[3]) != 1 {
+		t.Fatalf("want 4 batches with last batch of 1 sample, got %v\n", got)
+	}
+	for i, b := range got {
+		for j, v := range b {
+			if idx := i*3 + j; v/100 != idx {
+				t.Errorf("want sample %v at batch %v position %v, got %v\n", idx, i, j, v)
+			}
+		}
+	}
+
+	// Same seed gives same samples regardless of number of workers.
+	dl2, err := dutil.NewParallelDataLoader[int, []int](ds, s, dutil.CollateSlice[int], dutil.WithNumWorkers(1), dutil.WithSeed(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got2 := loadAll(t, dl2); !reflect.DeepEqual(got, got2) {
+		t.Errorf("want same batches for same seed, got %v and %v\n", got, got2)
+	}
+
+	// Next epoch gives different random samples.
+	dl2.Reset()
+	if got2 := loadAll(t, dl2); reflect.DeepEqual(got, got2) {
+		t.Errorf("want different random samples in next epoch, got %v\n", got2)
+	}
+
+	if _, err := dl.Next(context.Background()); err == nil {
+		t.Errorf("want error after last batch\n")
+	}
+	dl.Reset()
+	if !dl.HasNext() {
+		t.Errorf("want next batch after Reset\n")
+	}
+	dl.Close()
+}
+
+func TestParallelDataLoader_Cancel(t *testing.T) {
+	ds := dutil.NewTypedSliceDataset([]int{0, 1, 2, 3, 4, 5})
+	dl, err := dutil.NewParallelDataLoader[int, []int](ds, nil, dutil.CollateSlice[int], dutil.WithNumWorkers(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dl.Close()
+
+	if b, err := dl.Next(context.Background()); err != nil || b[0] != 0 {
+		t.Fatalf("want first sample 0, got %v (%v)\n", b, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// Cancelled context may still race with a ready batch, so drain until cancelled.
+	next := 1
+	for {
+		b, err := dl.Next(ctx)
+		if err != nil {
+			if err != context.Canceled {
+				t.Fatalf("want context.Canceled, got %v\n", err)
+			}
+			break
+		}
+		next = b[0] + 1
+	}
+
+	// Loading resumes from the same batch.
+	for dl.HasNext() {
+		b, err := dl.Next(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b[0] != next {
+			t.Errorf("want sample %v after cancel, got %v\n", next, b[0])
+		}
+		next++
+	}
+	if next != 6 {
+		t.Errorf("want all samples loaded, got %v\n", next)
+	}
+}
+
+func TestParallelDataLoader_CancelDeterministic(t *testing.T) {
+	ds := &noisyDataset{20}
+	newLoader := func() *dutil.ParallelDataLoader[int, []int] {
+		dl, err := dutil.NewParallelDataLoader[int, []int](ds, nil, dutil.CollateSlice[int], dutil.WithNumWorkers(3), dutil.WithSeed(3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dl
+	}
+	want := loadAll(t, newLoader())
+
+	dl := newLoader()
+	defer dl.Close()
+	var got [][]int
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; dl.HasNext(); i++ {
+		// Cancel every third call after workers prefetched batches.
+		c := context.Background()
+		if i%3 == 2 {
+			c = ctx
+		}
+		b, err := dl.Next(c)
+		if err == context.Canceled {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, b)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want same samples with cancellation %v, got %v\n", want, got)
+	}
+}
+
+func TestParallelDataLoader_Error(t *testing.T) {
+	ds := &noisyDataset{3}
+	s := dutil.NewSequentialSampler(5) // out of range samples.
+	dl, err := dutil.NewParallelDataLoader[int, []int](ds, s, dutil.CollateSlice[int])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dl.Close()
+
+	var errs int
+	for dl.HasNext() {
+		if _, err := dl.Next(context.Background()); err != nil {
+			errs++
+		}
+	}
+	if errs != 2 {
+		t.Errorf("want 2 errors, got %v\n", errs)
+	}
+
+	if _, err := dutil.NewParallelDataLoader[int, []int](ds, nil, dutil.CollateSlice[int], dutil.WithNumWorkers(0)); err == nil {
+		t.Errorf("want error for 0 workers\n")
+	}
+}
@@ -2,7 +2,9 @@ package dutil
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
+	"sort"
 	"time"
 )
 
@@ -227,3 +229,335 @@ func (s *BatchSampler) Sample() []int {
 func (s *BatchSampler) BatchSize() int {
 	return s.batchSize
 }
+
+// WeightedRandomSampler draws samples with given probabilities (weights), e.g. to
+// over-sample minority classes of an imbalanced dataset.
+type WeightedRandomSampler struct {
+	weights     []float64
+	size        int
+	replacement bool
+	r           *rand.Rand
+	batchSize   int // always = 1
+}
+
+// NewWeightedRandomSampler creates a new WeightedRandomSampler.
+//
+// weights: non-negative sample weights. They do not need to sum up to 1.
+// size: number of samples to draw. It can't be greater than number of samples with
+// non-zero weight if replacement is false.
+// replacement: whether a sample can be drawn more than once.
+// seed: seed of random source. Successive calls to Sample draw from the same source.
+func NewWeightedRandomSampler(weights []float64, size int, replacement bool, seed int64) (*WeightedRandomSampler, error) {
+	var nonZero int
+	for _, w := range weights {
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			err := fmt.Errorf("Invalid weight: weights must be finite and non-negative. Got %v", w)
+			return nil, err
+		}
+		if w > 0 {
+			nonZero++
+		}
+	}
+	if nonZero == 0 {
+		err := fmt.Errorf("Invalid weights: at least one weight must be greater than 0.")
+		return nil, err
+	}
+	if size < 1 || (!replacement && size > nonZero) {
+		err := fmt.Errorf("Invalid sampling size (%v): must be greater than 0 and not greater than number of samples with non-zero weight (%v) without replacement.", size, nonZero)
+		return nil, err
+	}
+
+	return &WeightedRandomSampler{
+		weights:     weights,
+		size:        size,
+		replacement: replacement,
+		r:           rand.New(rand.NewSource(seed)),
+		batchSize:   1,
+	}, nil
+}
+
+// Sample implements Sampler interface.
+func (s *WeightedRandomSampler) Sample() []int {
+	indices := make([]int, s.size)
+
+	if s.replacement {
+		cumsum := make([]float64, len(s.weights))
+		var total float64
+		for i, w := range s.weights {
+			total += w
+			cumsum[i] = total
+		}
+		for i := range indices {
+			u := s.r.Float64() * total
+			idx := sort.SearchFloat64s(cumsum, u)
+			// skip zero-weight samples at equal cumulative sums.
+			for idx < len(cumsum)-1 && (cumsum[idx] <= u || s.weights[idx] == 0) {
+				idx++
+			}
+			indices[i] = idx
+		}
+		return indices
+	}
+
+	// Without replacement: take samples with largest keys log(u)/w.
+	// ref. Efraimidis & Spirakis (2006). Weighted random sampling with a reservoir.
+	type keyed struct {
+		idx int
+		key float64
+	}
+	var keys []keyed
+	for i, w := range s.weights {
+		if w == 0 {
+			continue
+		}
+		keys = append(keys, keyed{i, math.Log(1-s.r.Float64()) / w})
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].key > keys[j].key
+	})
+	for i := range indices {
+		indices[i] = keys[i].idx
+	}
+
+	return indices
+}
+
+// BatchSize implements Sampler interface.
+// It's always return 1.
+func (s *WeightedRandomSampler) BatchSize() int {
+	return s.batchSize
+}
+
+// SubsetRandomSampler draws samples randomly from a subset of dataset indices
+// without replacement, e.g. for train/validation split of the same dataset.
+type SubsetRandomSampler struct {
+	indices   []int
+	r         *rand.Rand
+	batchSize int // always = 1
+}
+
+// NewSubsetRandomSampler creates a new SubsetRandomSampler.
+//
+// indices: dataset indices to draw from.
+// seed: seed of random source. Successive calls to Sample draw from the same source.
+func NewSubsetRandomSampler(indices []int, seed int64) *SubsetRandomSampler {
+	return &SubsetRandomSampler{
+		indices:   indices,
+		r:         rand.New(rand.NewSource(seed)),
+		batchSize: 1,
+	}
+}
+
+// Sample implements Sampler interface.
+func (s *SubsetRandomSampler) Sample() []int {
+	perm := s.r.Perm(len(s.indices))
+	indices := make([]int, len(perm))
+	for i, p := range perm {
+		indices[i] = s.indices[p]
+	}
+
+	return indices
+}
+
+// BatchSize implements Sampler interface.
+// It's always return 1.
+func (s *SubsetRandomSampler) BatchSize() int {
+	return s.batchSize
+}
+
+// DistributedSampler draws a partition of dataset samples for a process (rank) in
+// distributed training so that each of numReplicas processes sees a disjoint subset.
+//
+// All processes should use the same seed and call SetEpoch with the same epoch before
+// sampling so that samples are reshuffled consistently every epoch.
+type DistributedSampler struct {
+	n           int
+	numReplicas int
+	rank        int
+	shuffle     bool
+	dropLast    bool
+	seed        int64
+	epoch       int
+	batchSize   int // always = 1
+}
+
+// NewDistributedSampler creates a new DistributedSampler.
+//
+// n: number of samples in dataset.
+// numReplicas: number of processes (world size).
+// rank: rank of current process in [0, numReplicas).
+// shuffle: whether to shuffle samples with seed + epoch.
+// dropLast: whether to drop tail samples to make them evenly divisible among replicas.
+// Otherwise, samples are repeated from the start to pad the tail.
+func NewDistributedSampler(n, numReplicas, rank int, shuffle, dropLast bool, seed int64) (*DistributedSampler, error) {
+	if numReplicas < 1 || rank < 0 || rank >= numReplicas {
+		err := fmt.Errorf("Invalid rank (%v) or number of replicas (%v): rank must be in range [0, %v).", rank, numReplicas, numReplicas)
+		return nil, err
+	}
+	if n < 1 || (dropLast && n < numReplicas) {
+		err := fmt.Errorf("Invalid number of samples (%v) for %v replicas.", n, numReplicas)
+		return nil, err
+	}
+
+	return &DistributedSampler{
+		n:           n,
+		numReplicas: numReplicas,
+		rank:        rank,
+		shuffle:     shuffle,
+		dropLast:    dropLast,
+		seed:        seed,
+		batchSize:   1,
+	}, nil
+}
+
+// SetEpoch sets epoch used to reshuffle samples.
+func (s *DistributedSampler) SetEpoch(epoch int) {
+	s.epoch = epoch
+}
+
+// Len returns number of samples drawn for each replica.
+func (s *DistributedSampler) Len() int {
+	if s.dropLast {
+		return s.n / s.numReplicas
+	}
+	return (s.n + s.numReplicas - 1) / s.numReplicas
+}
+
+// Sample implements Sampler interface.
+func (s *DistributedSampler) Sample() []int {
+	var indices []int
+	if s.shuffle {
+		r := rand.New(rand.NewSource(s.seed + int64(s.epoch)))
+		indices = r.Perm(s.n)
+	} else {
+		indices = intRange(s.n)
+	}
+
+	total := s.Len() * s.numReplicas
+	if total <= len(indices) {
+		indices = indices[:total]
+	} else {
+		for i := 0; len(indices) < total; i++ {
+			indices = append(indices, indices[i%s.n])
+		}
+	}
+
+	var sub []int
+	for i := s.rank; i < total; i += s.numReplicas {
+		sub = append(sub, indices[i])
+	}
+
+	return sub
+}
+
+// BatchSize implements Sampler interface.
+// It's always return 1.
+func (s *DistributedSampler) BatchSize() int {
+	return s.batchSize
+}
+
+// BucketBatchSampler draws batches of samples with similar lengths (e.g. text sequences)
+// to minimize padding.
+//
+// Samples are shuffled and split into pools of 100 batches. Each pool is sorted by length
+// and chunked into batches, then batch order is shuffled. Incomplete batches are merged
+// into the last batch(es) so that only the final batch can be smaller than batch size.
+type BucketBatchSampler struct {
+	lengths   []int
+	batchSize int
+	dropLast  bool
+	shuffle   bool
+	r         *rand.Rand
+}
+
+// bucketPoolBatches is number of batches of a pool sorted by length.
+const bucketPoolBatches = 100
+
+// NewBucketBatchSampler creates a new BucketBatchSampler.
+//
+// lengths: length of each sample in dataset.
+// seed: seed of random source. Successive calls to Sample draw from the same source.
+// shuffleOpt: Optional (default=true). If false, samples are sorted by length.
+func NewBucketBatchSampler(lengths []int, batchSize int, dropLast bool, seed int64, shuffleOpt ...bool) (*BucketBatchSampler, error) {
+	n := len(lengths)
+	if batchSize > n || batchSize < 1 {
+		err := fmt.Errorf("Invalid batch size: batch size must be equal or greater than 1 and less or equal to number of samples(%v). Got %v", n, batchSize)
+		return nil, err
+	}
+
+	shuffle := true
+	if len(shuffleOpt) > 0 {
+		shuffle = shuffleOpt[0]
+	}
+
+	return &BucketBatchSampler{
+		lengths:   lengths,
+		batchSize: batchSize,
+		dropLast:  dropLast,
+		shuffle:   shuffle,
+		r:         rand.New(rand.NewSource(seed)),
+	}, nil
+}
+
+// Sample implements Sampler interface.
+func (s *BucketBatchSampler) Sample() []int {
+	byLength := func(indices []int) {
+		sort.SliceStable(indices, func(i, j int) bool {
+			return s.lengths[indices[i]] < s.lengths[indices[j]]
+		})
+	}
+
+	n := len(s.lengths)
+	if !s.shuffle {
+		indices := intRange(n)
+		byLength(indices)
+		if s.dropLast {
+			indices = indices[:n/s.batchSize*s.batchSize]
+		}
+		return indices
+	}
+
+	var (
+		batches [][]int
+		rest    []int
+	)
+	indices := s.r.Perm(n)
+	poolSize := s.batchSize * bucketPoolBatches
+	for start := 0; start < n; start += poolSize {
+		end := start + poolSize
+		if end > n {
+			end = n
+		}
+		pool := indices[start:end]
+		byLength(pool)
+		for ; len(pool) >= s.batchSize; pool = pool[s.batchSize:] {
+			batches = append(batches, pool[:s.batchSize])
+		}
+		rest = append(rest, pool...)
+	}
+
+	// Batches of pool remainders.
+	byLength(rest)
+	for ; len(rest) >= s.batchSize; rest = rest[s.batchSize:] {
+		batches = append(batches, rest[:s.batchSize])
+	}
+
+	s.r.Shuffle(len(batches), func(i, j int) {
+		batches[i], batches[j] = batches[j], batches[i]
+	})
+
+	samples := make([]int, 0, n)
+	for _, b := range batches {
+		samples = append(samples, b...)
+	}
+	if !s.dropLast {
+		samples = append(samples, rest...)
+	}
+
+	return samples
+}
+
+// BatchSize implements Sampler interface.
+func (s *BucketBatchSampler) BatchSize() int {
+	return s.batchSize
+}
@@ -3,6 +3,7 @@ package dutil_test
 import (
 	// "fmt"
 	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/sugarme/gotch/dutil"
@@ -142,3 +143,134 @@ func seq(n int) []int {
 	}
 	return s
 }
+
+func TestWeightedRandomSampler(t *testing.T) {
+	s, err := dutil.NewWeightedRandomSampler([]float64{0, 1, 3}, 4000, true, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make([]int, 3)
+	for _, idx := range s.Sample() {
+		counts[idx]++
+	}
+	if counts[0] != 0 || counts[2] < 2*counts[1] {
+		t.Errorf("Unexpected sample counts: %v\n", counts)
+	}
+
+	// Without replacement
+	s1, err := dutil.NewWeightedRandomSampler([]float64{1, 0, 2, 5}, 3, false, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indices := s1.Sample()
+	if isDup(indices) || len(indices) != 3 {
+		t.Errorf("Unexpected samples: %+v\n", indices)
+	}
+	for _, idx := range indices {
+		if idx == 1 {
+			t.Errorf("Unexpected zero-weight sample: %+v\n", indices)
+		}
+	}
+	if _, err := dutil.NewWeightedRandomSampler([]float64{1, 0, 2, 5}, 4, false, 42); err == nil {
+		t.Errorf("Want error for sampling size greater than non-zero weights\n")
+	}
+
+	// Reproducible with seed.
+	s2, _ := dutil.NewWeightedRandomSampler([]float64{1, 0, 2, 5}, 3, false, 42)
+	if got := s2.Sample(); !reflect.DeepEqual(indices, got) {
+		t.Errorf("Want %v, got %v for same seed\n", indices, got)
+	}
+}
+
+func TestSubsetRandomSampler(t *testing.T) {
+	subset := []int{7, 3, 9, 1}
+	s := dutil.NewSubsetRandomSampler(subset, 1)
+	indices := s.Sample()
+	got := append([]int{}, indices...)
+	sort.Ints(got)
+	if want := []int{1, 3, 7, 9}; !reflect.DeepEqual(want, got) {
+		t.Errorf("Want %v, got %v\n", want, got)
+	}
+
+	if got := dutil.NewSubsetRandomSampler(subset, 1).Sample(); !reflect.DeepEqual(indices, got) {
+		t.Errorf("Want %v, got %v for same seed\n", indices, got)
+	}
+}
+
+func TestDistributedSampler(t *testing.T) {
+	// Padded: 10 samples for 3 replicas -> 4 each.
+	var all []int
+	for rank := 0; rank < 3; rank++ {
+		s, err := dutil.NewDistributedSampler(10, 3, rank, true, false, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.SetEpoch(2)
+		indices := s.Sample()
+		if len(indices) != 4 || s.Len() != 4 {
+			t.Errorf("Want 4 samples for rank %v, got %v\n", rank, indices)
+		}
+		all = append(all, indices...)
+	}
+	seen := make(map[int]bool)
+	for _, idx := range all {
+		seen[idx] = true
+	}
+	if len(seen) != 10 {
+		t.Errorf("Want all samples covered, got %v\n", all)
+	}
+
+	// Drop last: 10 samples for 3 replicas -> 3 each, no duplicates.
+	s, err := dutil.NewDistributedSampler(10, 3, 1, false, true, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := []int{1, 4, 7}, s.Sample(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Want %v, got %v\n", want, got)
+	}
+
+	// Epochs reshuffle.
+	s1, _ := dutil.NewDistributedSampler(100, 2, 0, true, false, 5)
+	e0 := s1.Sample()
+	s1.SetEpoch(1)
+	if reflect.DeepEqual(e0, s1.Sample()) {
+		t.Errorf("Want different samples for different epochs\n")
+	}
+
+	if _, err := dutil.NewDistributedSampler(10, 2, 2, false, false, 0); err == nil {
+		t.Errorf("Want error for invalid rank\n")
+	}
+}
+
+func TestBucketBatchSampler(t *testing.T) {
+	lengths := []int{5, 1, 9, 2, 8, 1, 7, 3, 6}
+	s, err := dutil.NewBucketBatchSampler(lengths, 2, false, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indices := s.Sample()
+	if len(indices) != len(lengths) || isDup(indices) {
+		t.Fatalf("Unexpected samples: %v\n", indices)
+	}
+	// Sorted pool: lengths 1,1,2,3,5,6,7,8 in batches of 2 and remainder 9.
+	for i := 0; i+1 < 8; i += 2 {
+		a, b := lengths[indices[i]], lengths[indices[i+1]]
+		if a > b || b-a > 2 {
+			t.Errorf("Want similar lengths in batch, got %v and %v\n", a, b)
+		}
+	}
+	if lengths[indices[8]] != 9 {
+		t.Errorf("Want incomplete batch last, got %v\n", indices)
+	}
+
+	s1, _ := dutil.NewBucketBatchSampler(lengths, 2, true, 3)
+	if got := s1.Sample(); !reflect.DeepEqual(indices[:8], got) {
+		t.Errorf("Want %v, got %v\n", indices[:8], got)
+	}
+
+	// No shuffle: sorted by length.
+	s2, _ := dutil.NewBucketBatchSampler(lengths, 4, true, 3, false)
+	if want, got := []int{1, 5, 3, 7}, s2.Sample()[:4]; !reflect.DeepEqual(want, got) {
+		t.Errorf("Want %v, got %v\n", want, got)
+	}
+}
@@ -0,0 +1,484 @@
+package dutil
+
+// Streaming dataset of samples stored in tar shards (WebDataset format).
+//
+// Samples are groups of consecutive tar members with the same key, i.e. file path up
+// to the first dot of the file name:
+//
+//	0001.jpg, 0001.cls, 0001.json -> sample "0001" with fields "jpg", "cls", "json"
+//	dir/0002.seg.png              -> sample "dir/0002" with field "seg.png"
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sugarme/gotch/ts"
+)
+
+// Decoder decodes raw bytes of a sample field.
+type Decoder func(data []byte) (interface{}, error)
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]Decoder{
+		"npy":   decodeNpy,
+		"txt":   decodeText,
+		"text":  decodeText,
+		"json":  decodeJSON,
+		"cls":   decodeInt,
+		"cls2":  decodeInt,
+		"index": decodeInt,
+		"id":    decodeInt,
+	}
+)
+
+// RegisterDecoder registers a decoder for fields with given extension (e.g. "jpg",
+// "seg.png"). It replaces existing decoder of the extension.
+//
+// Default decoders:
+//   - "npy": *ts.Tensor (see ts.ReadNpyFrom)
+//   - "txt", "text": string
+//   - "json": interface{} decoded by encoding/json
+//   - "cls", "cls2", "index", "id": int
+//
+// Image decoders (*ts.Tensor) are registered by importing package vision.
+func RegisterDecoder(ext string, decoder Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[strings.ToLower(ext)] = decoder
+}
+
+// lookupDecoder returns decoder of field extension or its last part (e.g. "png" of "seg.png").
+func lookupDecoder(field string) (Decoder, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+
+	ext := strings.ToLower(field)
+	if d, ok := decoders[ext]; ok {
+		return d, true
+	}
+	if i := strings.LastIndex(ext, "."); i >= 0 {
+		d, ok := decoders[ext[i+1:]]
+		return d, ok
+	}
+	return nil, false
+}
+
+func decodeNpy(data []byte) (interface{}, error) {
+	return ts.ReadNpyFrom(bytes.NewReader(data))
+}
+
+func decodeText(data []byte) (interface{}, error) {
+	return string(data), nil
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	var v interface{}
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+func decodeInt(data []byte) (interface{}, error) {
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// ShardSample is a sample of a ShardDataset.
+type ShardSample struct {
+	Key    string                 // sample key.
+	Shard  string                 // shard file path.
+	Fields map[string]interface{} // decoded fields by extension. Fields without decoder are []byte.
+}
+
+// Drop drops tensor fields of the sample.
+func (s ShardSample) Drop() {
+	for _, v := range s.Fields {
+		dropBatch(v)
+	}
+}
+
+type ShardOptions struct {
+	Workers       int   // number of goroutines reading shards. Default=1
+	ShuffleBuffer int   // size of shuffle buffer of samples. Default=0 (no shuffle)
+	ShuffleShards bool  // whether to shuffle shard order every epoch. Default=false
+	Seed          int64 // seed of shard and sample shuffling. Default=0
+	Rank          int   // rank of process in distributed training. Default=0
+	WorldSize     int   // number of processes in distributed training. Default=1
+	Decode        bool  // whether to decode fields with registered decoders. Default=true
+}
+
+type ShardOption func(*ShardOptions)
+
+func NewShardOptions(options ...ShardOption) ShardOptions {
+	opts := ShardOptions{
+		Workers:       1,
+		ShuffleBuffer: 0,
+		ShuffleShards: false,
+		Seed:          0,
+		Rank:          0,
+		WorldSize:     1,
+		Decode:        true,
+	}
+
+	for _, o := range options {
+		o(&opts)
+	}
+
+	return opts
+}
+
+func WithShardWorkers(n int) ShardOption {
+	return func(o *ShardOptions) {
+		o.Workers = n
+	}
+}
+
+func WithShuffleBuffer(size int) ShardOption {
+	return func(o *ShardOptions) {
+		o.ShuffleBuffer = size
+	}
+}
+
+func WithShardShuffle(shuffle bool) ShardOption {
+	return func(o *ShardOptions) {
+		o.ShuffleShards = shuffle
+	}
+}
+
+func WithShardSeed(seed int64) ShardOption {
+	return func(o *ShardOptions) {
+		o.Seed = seed
+	}
+}
+
+// WithShardSplit reads only shards i with i%worldSize == rank, e.g. for distributed training.
+func WithShardSplit(rank, worldSize int) ShardOption {
+	return func(o *ShardOptions) {
+		o.Rank = rank
+		o.WorldSize = worldSize
+	}
+}
+
+func WithDecode(decode bool) ShardOption {
+	return func(o *ShardOptions) {
+		o.Decode = decode
+	}
+}
+
+// ShardDataset is an iterable dataset streaming samples from tar shards (optionally
+// gzip compressed).
+//
+// Shards are split across worker goroutines (shard k is read by worker k%Workers) and
+// samples are taken from workers in turn so that sample order is deterministic for given
+// seed, epoch and number of workers.
+type ShardDataset struct {
+	shards []string
+	opts   ShardOptions
+	epoch  int
+}
+
+// NewShardDataset creates a new ShardDataset. Shards are file paths or glob patterns
+// (e.g. "data/train-*.tar").
+func NewShardDataset(shards []string, opts ...ShardOption) (*ShardDataset, error) {
+	o := NewShardOptions(opts...)
+	if o.Workers < 1 || o.ShuffleBuffer < 0 {
+		err := fmt.Errorf("NewShardDataset() failed: invalid number of workers (%v) or shuffle buffer size (%v)", o.Workers, o.ShuffleBuffer)
+		return nil, err
+	}
+	if o.WorldSize < 1 || o.Rank < 0 || o.Rank >= o.WorldSize {
+		err := fmt.Errorf("NewShardDataset() failed: invalid rank (%v) for world size (%v)", o.Rank, o.WorldSize)
+		return nil, err
+	}
+
+	var paths []string
+	for _, pattern := range shards {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			err = fmt.Errorf("NewShardDataset() failed: %w", err)
+			return nil, err
+		}
+		if len(matches) == 0 {
+			err := fmt.Errorf("NewShardDataset() failed: no shard files found for %q", pattern)
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+
+	var selected []string
+	for i, p := range paths {
+		if i%o.WorldSize == o.Rank {
+			selected = append(selected, p)
+		}
+	}
+	if len(selected) == 0 {
+		err := fmt.Errorf("NewShardDataset() failed: no shards for rank %v of %v shards", o.Rank, len(paths))
+		return nil, err
+	}
+
+	return &ShardDataset{
+		shards: selected,
+		opts:   o,
+	}, nil
+}
+
+// Shards returns shard files read by this dataset.
+func (ds *ShardDataset) Shards() []string {
+	return ds.shards
+}
+
+// SetEpoch sets epoch used to shuffle shards and samples.
+func (ds *ShardDataset) SetEpoch(epoch int) {
+	ds.epoch = epoch
+}
+
+type shardResult struct {
+	sample ShardSample
+	err    error
+}
+
+// ShardIterator iterates samples of a ShardDataset.
+type ShardIterator struct {
+	ctx     context.Context // context given to Iter, not cancelled by Close.
+	cancel  context.CancelFunc
+	outs    []chan shardResult
+	wg      sync.WaitGroup
+	next    int // worker to take next sample from.
+	done    []bool
+	ndone   int
+	r       *rand.Rand
+	buffer  []ShardSample
+	bufSize int
+	err     error
+}
+
+// Iter starts reading an epoch of samples. Iteration stops when ctx is done.
+// Close should be called if the iterator is not iterated to the end.
+func (ds *ShardDataset) Iter(ctx context.Context) *ShardIterator {
+	seed := ds.opts.Seed + int64(ds.epoch)
+	shards := append([]string{}, ds.shards...)
+	if ds.opts.ShuffleShards {
+		r := rand.New(rand.NewSource(seed))
+		r.Shuffle(len(shards), func(i, j int) {
+			shards[i], shards[j] = shards[j], shards[i]
+		})
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	n := ds.opts.Workers
+	if n > len(shards) {
+		n = len(shards)
+	}
+	it := &ShardIterator{
+		ctx:     parent,
+		cancel:  cancel,
+		outs:    make([]chan shardResult, n),
+		done:    make([]bool, n),
+		r:       rand.New(rand.NewSource(seed)),
+		bufSize: ds.opts.ShuffleBuffer,
+	}
+	for w := 0; w < n; w++ {
+		it.outs[w] = make(chan shardResult, 8)
+		var assigned []string
+		for k := w; k < len(shards); k += n {
+			assigned = append(assigned, shards[k])
+		}
+		it.wg.Add(1)
+		go func(out chan shardResult, assigned []string) {
+			defer it.wg.Done()
+			defer close(out)
+			for _, shard := range assigned {
+				if err := readShard(ctx, shard, ds.opts.Decode, out); err != nil {
+					select {
+					case out <- shardResult{err: err}:
+					case <-ctx.Done():
+					}
+					return
+				}
+			}
+		}(it.outs[w], assigned)
+	}
+
+	return it
+}
+
+// readShard reads samples of a shard and sends them to out.
+func readShard(ctx context.Context, shard string, decode bool, out chan<- shardResult) error {
+	f, err := os.Open(shard)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var reader io.Reader = r
+	if magic, err := r.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("reading shard %q failed: %w", shard, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	var sample *ShardSample
+	send := func() bool {
+		if sample == nil {
+			return true
+		}
+		select {
+		case out <- shardResult{sample: *sample}:
+			return true
+		case <-ctx.Done():
+			sample.Drop()
+			return false
+		}
+	}
+
+	tr := tar.NewReader(reader)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if sample != nil {
+				sample.Drop()
+			}
+			return fmt.Errorf("reading shard %q failed: %w", shard, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		key, field := splitKey(hdr.Name)
+		if field == "" {
+			continue
+		}
+		if sample == nil || sample.Key != key {
+			if !send() {
+				return nil
+			}
+			sample = &ShardSample{Key: key, Shard: shard, Fields: make(map[string]interface{})}
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			sample.Drop()
+			return fmt.Errorf("reading shard %q failed: %w", shard, err)
+		}
+		var value interface{} = data
+		if d, ok := lookupDecoder(field); ok && decode {
+			if value, err = d(data); err != nil {
+				sample.Drop()
+				return fmt.Errorf("decoding field %q of sample %q in shard %q failed: %w", field, key, shard, err)
+			}
+		}
+		sample.Fields[field] = value
+	}
+	send()
+
+	return nil
+}
+
+// splitKey splits a tar member name into sample key and field extension at the first dot
+// of the file name.
+func splitKey(name string) (key, field string) {
+	dir, file := path.Split(name)
+	i := strings.Index(file, ".")
+	if i <= 0 {
+		return name, ""
+	}
+	return dir + file[:i], file[i+1:]
+}
+
+// receive returns next sample from workers in turn. Workers stop when ctx is done, so
+// ctx error is checked before every sample.
+func (it *ShardIterator) receive() (ShardSample, bool) {
+	for {
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return ShardSample{}, false
+		}
+		if it.ndone == len(it.outs) {
+			return ShardSample{}, false
+		}
+		w := it.next
+		it.next = (it.next + 1) % len(it.outs)
+		if it.done[w] {
+			continue
+		}
+		res, ok := <-it.outs[w]
+		if !ok {
+			it.done[w] = true
+			it.ndone++
+			continue
+		}
+		if res.err != nil {
+			it.err = res.err
+			return ShardSample{}, false
+		}
+		return res.sample, true
+	}
+}
+
+// Next returns next sample. It returns io.EOF at the end of the epoch, or an error of
+// reading shards or of ctx.
+func (it *ShardIterator) Next() (ShardSample, error) {
+	if it.err == nil {
+		for len(it.buffer) < it.bufSize {
+			sample, ok := it.receive()
+			if !ok {
+				break
+			}
+			it.buffer = append(it.buffer, sample)
+		}
+	}
+	if it.err != nil {
+		return ShardSample{}, it.err
+	}
+
+	if it.bufSize == 0 {
+		if sample, ok := it.receive(); ok {
+			return sample, nil
+		}
+	} else if n := len(it.buffer); n > 0 {
+		i := it.r.Intn(n)
+		sample := it.buffer[i]
+		it.buffer[i] = it.buffer[n-1]
+		it.buffer = it.buffer[:n-1]
+		return sample, nil
+	}
+
+	if it.err != nil {
+		return ShardSample{}, it.err
+	}
+	return ShardSample{}, io.EOF
+}
+
+// Close stops reading shards and drops buffered samples.
+func (it *ShardIterator) Close() {
+	it.cancel()
+	for _, out := range it.outs {
+		for res := range out {
+			res.sample.Drop()
+		}
+	}
+	it.wg.Wait()
+	for _, sample := range it.buffer {
+		sample.Drop()
+	}
+	it.buffer = nil
+}
@@ -0,0 +1,236 @@
+package dutil_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sugarme/gotch/dutil"
+)
+
+// writeShard writes samples [start, end) to a tar shard, gzip compressed if name ends with ".gz".
+func writeShard(t *testing.T, dir, name string, start, end int) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var w io.Writer = f
+	if strings.HasSuffix(name, ".gz") {
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+
+	files := func(i int) map[string]string {
+		return map[string]string{
+			"cls":       fmt.Sprintf("%d\n", i%3),
+			"txt":       fmt.Sprintf("sample %d", i),
+			"meta.json": fmt.Sprintf(`{"id": %d}`, i),
+			"bin":       "raw",
+		}
+	}
+	for i := start; i < end; i++ {
+		fields := files(i)
+		exts := make([]string, 0, len(fields))
+		for ext := range fields {
+			exts = append(exts, ext)
+		}
+		sort.Strings(exts)
+		for _, ext := range exts {
+			data := fields[ext]
+			hdr := &tar.Header{
+				Name:     fmt.Sprintf("data/%04d.%s", i, ext),
+				Mode:     0644,
+				Size:     int64(len(data)),
+				Typeflag: tar.TypeReg,
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tw.Write([]byte(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return path
+}
+
+func readAllShards(t *testing.T, ds *dutil.ShardDataset) []dutil.ShardSample {
+	t.Helper()
+	it := ds.Iter(context.Background())
+	defer it.Close()
+
+	var samples []dutil.ShardSample
+	for {
+		s, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples = append(samples, s)
+	}
+	return samples
+}
+
+func sampleKeys(samples []dutil.ShardSample) []string {
+	keys := make([]string, len(samples))
+	for i, s := range samples {
+		keys[i] = s.Key
+	}
+	return keys
+}
+
+func TestShardDataset(t *testing.T) {
+	dir := t.TempDir()
+	writeShard(t, dir, "shard-0.tar", 0, 4)
+	writeShard(t, dir, "shard-1.tar.gz", 4, 7)
+
+	ds, err := dutil.NewShardDataset([]string{filepath.Join(dir, "shard-*")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := readAllShards(t, ds)
+	if len(samples) != 7 {
+		t.Fatalf("want 7 samples, got %v\n", len(samples))
+	}
+
+	s := samples[5]
+	if s.Key != "data/0005" {
+		t.Errorf("want key data/0005, got %v\n", s.Key)
+	}
+	want := map[string]interface{}{
+		"cls":       2,
+		"txt":       "sample 5",
+		"meta.json": map[string]interface{}{"id": float64(5)},
+		"bin":       []byte("raw"),
+	}
+	if !reflect.DeepEqual(want, s.Fields) {
+		t.Errorf("want %v, got %v\n", want, s.Fields)
+	}
+
+	if _, err := dutil.NewShardDataset([]string{filepath.Join(dir, "missing-*.tar")}); err == nil {
+		t.Errorf("want error for no matching shards\n")
+	}
+}
+
+func TestShardDataset_Shuffle(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 4; i++ {
+		writeShard(t, dir, fmt.Sprintf("shard-%d.tar", i), i*5, (i+1)*5)
+	}
+	pattern := filepath.Join(dir, "shard-*.tar")
+	opts := []dutil.ShardOption{
+		dutil.WithShardWorkers(3),
+		dutil.WithShuffleBuffer(6),
+		dutil.WithShardShuffle(true),
+		dutil.WithShardSeed(42),
+	}
+
+	ds1, err := dutil.NewShardDataset([]string{pattern}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds2, err := dutil.NewShardDataset([]string{pattern}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys1 := sampleKeys(readAllShards(t, ds1))
+	keys2 := sampleKeys(readAllShards(t, ds2))
+	if !reflect.DeepEqual(keys1, keys2) {
+		t.Errorf("want same order for same seed, got %v and %v\n", keys1, keys2)
+	}
+
+	sorted := append([]string{}, keys1...)
+	sort.Strings(sorted)
+	if len(sorted) != 20 || sorted[0] != "data/0000" || sorted[19] != "data/0019" {
+		t.Errorf("want all 20 samples, got %v\n", sorted)
+	}
+	if reflect.DeepEqual(sorted, keys1) {
+		t.Errorf("want shuffled samples, got %v\n", keys1)
+	}
+
+	ds1.SetEpoch(1)
+	if reflect.DeepEqual(keys1, sampleKeys(readAllShards(t, ds1))) {
+		t.Errorf("want different order for different epoch\n")
+	}
+}
+
+func TestShardDataset_Split(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 3; i++ {
+		writeShard(t, dir, fmt.Sprintf("shard-%d.tar", i), i*2, (i+1)*2)
+	}
+	pattern := filepath.Join(dir, "shard-*.tar")
+
+	var all []string
+	for rank := 0; rank < 2; rank++ {
+		ds, err := dutil.NewShardDataset([]string{pattern}, dutil.WithShardSplit(rank, 2), dutil.WithDecode(false))
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples := readAllShards(t, ds)
+		if _, ok := samples[0].Fields["cls"].([]byte); !ok {
+			t.Errorf("want raw bytes without decoding, got %T\n", samples[0].Fields["cls"])
+		}
+		all = append(all, sampleKeys(samples)...)
+	}
+	want := []string{"data/0000", "data/0001", "data/0004", "data/0005", "data/0002", "data/0003"}
+	if !reflect.DeepEqual(want, all) {
+		t.Errorf("want %v, got %v\n", want, all)
+	}
+}
+
+func TestShardIterator_Close(t *testing.T) {
+	dir := t.TempDir()
+	writeShard(t, dir, "shard-0.tar", 0, 50)
+	writeShard(t, dir, "shard-1.tar", 50, 100)
+
+	ds, err := dutil.NewShardDataset([]string{filepath.Join(dir, "*.tar")}, dutil.WithShardWorkers(2), dutil.WithShuffleBuffer(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := ds.Iter(context.Background())
+	if _, err := it.Next(); err != nil {
+		t.Fatal(err)
+	}
+	it.Close()
+}
+
+func TestShardIterator_Cancel(t *testing.T) {
+	dir := t.TempDir()
+	writeShard(t, dir, "shard-0.tar", 0, 50)
+	writeShard(t, dir, "shard-1.tar", 50, 100)
+
+	for _, bufSize := range []int{0, 10} {
+		ds, err := dutil.NewShardDataset([]string{filepath.Join(dir, "*.tar")}, dutil.WithShardWorkers(2), dutil.WithShuffleBuffer(bufSize))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		it := ds.Iter(ctx)
+		if _, err := it.Next(); err != nil {
+			t.Fatal(err)
+		}
+		cancel()
+		if _, err := it.Next(); err != context.Canceled {
+			t.Errorf("buffer %v: want %v after cancel, got %v\n", bufSize, context.Canceled, err)
+		}
+		it.Close()
+	}
+}
@@ -0,0 +1,432 @@
+package dutil
+
+// Cross-validation splitters.
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// StratifiedKFold represents a KFold splitter that preserves
+// proportions of labels in each fold.
+type StratifiedKFold struct {
+	labels  []int
+	nfolds  int
+	shuffle bool
+	r       *rand.Rand
+}
+
+// NewStratifiedKFold creates a new StratifiedKFold.
+//
+// labels: label of each sample.
+func NewStratifiedKFold(labels []int, opt ...KFoldOption) (*StratifiedKFold, error) {
+	opts := NewKFoldOptions(opt...)
+	if err := checkNFolds(opts.NFolds, len(labels)); err != nil {
+		return nil, err
+	}
+
+	return &StratifiedKFold{
+		labels:  labels,
+		nfolds:  opts.NFolds,
+		shuffle: opts.Shuffle,
+		r:       rand.New(rand.NewSource(opts.Seed)),
+	}, nil
+}
+
+// Split implements Splitter interface.
+//
+// Samples of each label (shuffled if Shuffle option is true) are assigned to
+// folds in turn so that each fold has (almost) the same label proportions and size.
+func (kf *StratifiedKFold) Split() []Fold {
+	fold := make([]int, len(kf.labels))
+	var i int
+	for _, indices := range groupIndices(kf.labels) {
+		if kf.shuffle {
+			kf.r.Shuffle(len(indices), func(a, b int) {
+				indices[a], indices[b] = indices[b], indices[a]
+			})
+		}
+		for _, idx := range indices {
+			fold[idx] = i % kf.nfolds
+			i++
+		}
+	}
+
+	return assignedFolds(fold, kf.nfolds)
+}
+
+// GroupKFold represents a KFold splitter that keeps samples of the same
+// group (e.g. same patient or same user) in the same fold so that no group
+// appears in both train and test sets.
+type GroupKFold struct {
+	groups  []int
+	nfolds  int
+	shuffle bool
+	r       *rand.Rand
+}
+
+// NewGroupKFold creates a new GroupKFold.
+//
+// groups: group of each sample. nfolds cannot be greater than number of groups.
+func NewGroupKFold(groups []int, opt ...KFoldOption) (*GroupKFold, error) {
+	opts := NewKFoldOptions(opt...)
+	ngroups := len(groupIndices(groups))
+	if err := checkNFolds(opts.NFolds, ngroups); err != nil {
+		err = fmt.Errorf("NewGroupKFold() failed - number of groups: %w", err)
+		return nil, err
+	}
+
+	return &GroupKFold{
+		groups:  groups,
+		nfolds:  opts.NFolds,
+		shuffle: opts.Shuffle,
+		r:       rand.New(rand.NewSource(opts.Seed)),
+	}, nil
+}
+
+// Split implements Splitter interface.
+//
+// Groups are assigned from the largest to the smallest to the fold with the fewest
+// samples to balance fold sizes. If Shuffle option is true, groups of equal size are
+// assigned in random order.
+func (kf *GroupKFold) Split() []Fold {
+	groups := groupIndices(kf.groups)
+	if kf.shuffle {
+		kf.r.Shuffle(len(groups), func(a, b int) {
+			groups[a], groups[b] = groups[b], groups[a]
+		})
+	}
+	sort.SliceStable(groups, func(a, b int) bool {
+		return len(groups[a]) > len(groups[b])
+	})
+
+	fold := make([]int, len(kf.groups))
+	sizes := make([]int, kf.nfolds)
+	for _, indices := range groups {
+		lightest := 0
+		for i, size := range sizes {
+			if size < sizes[lightest] {
+				lightest = i
+			}
+		}
+		for _, idx := range indices {
+			fold[idx] = lightest
+		}
+		sizes[lightest] += len(indices)
+	}
+
+	return assignedFolds(fold, kf.nfolds)
+}
+
+// RepeatedKFold represents a KFold splitter repeated
+// with different shuffling each time.
+type RepeatedKFold struct {
+	n       int
+	nfolds  int
+	repeats int
+	r       *rand.Rand
+}
+
+// NewRepeatedKFold creates a new RepeatedKFold. Samples are always shuffled
+// and Shuffle option is ignored.
+//
+// n: number of samples.
+// repeats: number of times KFold is repeated.
+func NewRepeatedKFold(n, repeats int, opt ...KFoldOption) (*RepeatedKFold, error) {
+	opts := NewKFoldOptions(opt...)
+	if err := checkNFolds(opts.NFolds, n); err != nil {
+		return nil, err
+	}
+	if repeats < 1 {
+		err := fmt.Errorf("repeats must be at least 1. Got: %v\n", repeats)
+		return nil, err
+	}
+
+	return &RepeatedKFold{
+		n:       n,
+		nfolds:  opts.NFolds,
+		repeats: repeats,
+		r:       rand.New(rand.NewSource(opts.Seed)),
+	}, nil
+}
+
+// Split implements Splitter interface. It returns nfolds * repeats folds
+// with folds of each repetition in a row.
+func (kf *RepeatedKFold) Split() []Fold {
+	var splits []Fold
+	for i := 0; i < kf.repeats; i++ {
+		splits = append(splits, kfoldSplit(kf.r.Perm(kf.n), kf.nfolds, true)...)
+	}
+
+	return splits
+}
+
+// TimeSeriesSplit represents a splitter for time-ordered samples. In each split,
+// test set follows train set in time and train set grows (expanding window).
+type TimeSeriesSplit struct {
+	n            int
+	nsplits      int
+	gap          int
+	testSize     int
+	maxTrainSize int
+}
+
+type TimeSeriesOptions struct {
+	NSplits      int // number of splits. Default=5
+	Gap          int // number of samples excluded between train and test sets. Default=0
+	TestSize     int // number of test samples. Default=n/(NSplits+1)
+	MaxTrainSize int // maximum number of train samples (rolling window). Default=0 (unlimited)
+}
+
+type TimeSeriesOption func(*TimeSeriesOptions)
+
+func NewTimeSeriesOptions(options ...TimeSeriesOption) TimeSeriesOptions {
+	opts := TimeSeriesOptions{
+		NSplits:      5,
+		Gap:          0,
+		TestSize:     0,
+		MaxTrainSize: 0,
+	}
+
+	for _, o := range options {
+		o(&opts)
+	}
+
+	return opts
+}
+
+func WithNSplits(nsplits int) TimeSeriesOption {
+	return func(o *TimeSeriesOptions) {
+		o.NSplits = nsplits
+	}
+}
+
+func WithGap(gap int) TimeSeriesOption {
+	return func(o *TimeSeriesOptions) {
+		o.Gap = gap
+	}
+}
+
+func WithTestSize(size int) TimeSeriesOption {
+	return func(o *TimeSeriesOptions) {
+		o.TestSize = size
+	}
+}
+
+func WithMaxTrainSize(size int) TimeSeriesOption {
+	return func(o *TimeSeriesOptions) {
+		o.MaxTrainSize = size
+	}
+}
+
+// NewTimeSeriesSplit creates a new TimeSeriesSplit. Splits are deterministic
+// as samples are never shuffled.
+//
+// n: number of samples in time order.
+func NewTimeSeriesSplit(n int, opt ...TimeSeriesOption) (*TimeSeriesSplit, error) {
+	opts := NewTimeSeriesOptions(opt...)
+	if opts.NSplits < 2 {
+		err := fmt.Errorf("nsplits must be at least 2. Got: %v\n", opts.NSplits)
+		return nil, err
+	}
+	if opts.Gap < 0 || opts.TestSize < 0 || opts.MaxTrainSize < 0 {
+		err := fmt.Errorf("gap, test size and max train size must be non-negative. Got: %v, %v, %v\n", opts.Gap, opts.TestSize, opts.MaxTrainSize)
+		return nil, err
+	}
+
+	testSize := opts.TestSize
+	if testSize == 0 {
+		testSize = n / (opts.NSplits + 1)
+	}
+	if testSize == 0 || n-opts.Gap-testSize*opts.NSplits <= 0 {
+		err := fmt.Errorf("Too many splits (%v) or too large gap (%v) for number of samples (%v) with test size (%v).\n", opts.NSplits, opts.Gap, n, testSize)
+		return nil, err
+	}
+
+	return &TimeSeriesSplit{
+		n:            n,
+		nsplits:      opts.NSplits,
+		gap:          opts.Gap,
+		testSize:     testSize,
+		maxTrainSize: opts.MaxTrainSize,
+	}, nil
+}
+
+// Split implements Splitter interface.
+func (s *TimeSeriesSplit) Split() []Fold {
+	var splits []Fold
+	for start := s.n - s.nsplits*s.testSize; start < s.n; start += s.testSize {
+		trainEnd := start - s.gap
+		trainStart := 0
+		if s.maxTrainSize > 0 && trainEnd > s.maxTrainSize {
+			trainStart = trainEnd - s.maxTrainSize
+		}
+		splits = append(splits, Fold{
+			Train: indexRange(trainStart, trainEnd),
+			Test:  indexRange(start, start+s.testSize),
+		})
+	}
+
+	return splits
+}
+
+type SplitOptions struct {
+	Shuffle  bool  // whether to shuffle samples before splitting. Default=true
+	Stratify []int // labels to stratify split by. Default=nil (no stratification)
+	Seed     int64 // seed of random shuffling. Default=current time in nanoseconds
+}
+
+type SplitOption func(*SplitOptions)
+
+func NewSplitOptions(options ...SplitOption) SplitOptions {
+	opts := SplitOptions{
+		Shuffle:  true,
+		Stratify: nil,
+		Seed:     time.Now().UnixNano(),
+	}
+
+	for _, o := range options {
+		o(&opts)
+	}
+
+	return opts
+}
+
+func WithSplitShuffle(shuffle bool) SplitOption {
+	return func(o *SplitOptions) {
+		o.Shuffle = shuffle
+	}
+}
+
+func WithStratify(labels []int) SplitOption {
+	return func(o *SplitOptions) {
+		o.Stratify = labels
+	}
+}
+
+func WithSplitSeed(seed int64) SplitOption {
+	return func(o *SplitOptions) {
+		o.Seed = seed
+	}
+}
+
+// TrainTestSplit splits n samples into a train set and a test set.
+//
+// testSize: fraction of test samples if in range (0, 1), otherwise number of test samples.
+// Number of test samples is rounded up.
+// If Stratify option is set, label proportions of both sets are preserved. Stratified
+// split always shuffles samples.
+func TrainTestSplit(n int, testSize float64, opt ...SplitOption) (Fold, error) {
+	opts := NewSplitOptions(opt...)
+
+	ntest := int(testSize)
+	if testSize > 0 && testSize < 1 {
+		ntest = int(math.Ceil(testSize * float64(n)))
+	}
+	if ntest < 1 || ntest >= n || (testSize >= 1 && testSize != math.Trunc(testSize)) {
+		err := fmt.Errorf("Invalid test size (%v): test set and train set must not be empty for number of samples (%v).\n", testSize, n)
+		return Fold{}, err
+	}
+	if opts.Stratify != nil && len(opts.Stratify) != n {
+		err := fmt.Errorf("Invalid stratify labels: expected %v labels, got %v.\n", n, len(opts.Stratify))
+		return Fold{}, err
+	}
+
+	r := rand.New(rand.NewSource(opts.Seed))
+	if opts.Stratify == nil {
+		indices := intRange(n)
+		if opts.Shuffle {
+			indices = r.Perm(n)
+		}
+		return Fold{Train: indices[ntest:], Test: indices[:ntest]}, nil
+	}
+
+	// Allocate test samples to labels by largest remainder.
+	groups := groupIndices(opts.Stratify)
+	counts := make([]int, len(groups))
+	remainders := make([]float64, len(groups))
+	allocated := 0
+	for i, indices := range groups {
+		exact := float64(ntest) * float64(len(indices)) / float64(n)
+		counts[i] = int(exact)
+		remainders[i] = exact - float64(counts[i])
+		allocated += counts[i]
+	}
+	order := intRange(len(groups))
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for _, i := range order[:ntest-allocated] {
+		counts[i]++
+	}
+
+	var fold Fold
+	for i, indices := range groups {
+		r.Shuffle(len(indices), func(a, b int) {
+			indices[a], indices[b] = indices[b], indices[a]
+		})
+		fold.Test = append(fold.Test, indices[:counts[i]]...)
+		fold.Train = append(fold.Train, indices[counts[i]:]...)
+	}
+	sort.Ints(fold.Train)
+	sort.Ints(fold.Test)
+
+	return fold, nil
+}
+
+func checkNFolds(nfolds, n int) error {
+	if nfolds < 2 {
+		err := fmt.Errorf("nfolds must be at least 2. Got: %v\n", nfolds)
+		return err
+	}
+	if nfolds > n {
+		err := fmt.Errorf("nfolds cannot be greater than number of samples (%v). Got: %v\n", n, nfolds)
+		return err
+	}
+	return nil
+}
+
+// groupIndices groups sample indices by key (label or group) in ascending key order.
+func groupIndices(keys []int) [][]int {
+	byKey := make(map[int][]int)
+	for i, k := range keys {
+		byKey[k] = append(byKey[k], i)
+	}
+	var sorted []int
+	for k := range byKey {
+		sorted = append(sorted, k)
+	}
+	sort.Ints(sorted)
+
+	groups := make([][]int, len(sorted))
+	for i, k := range sorted {
+		groups[i] = byKey[k]
+	}
+	return groups
+}
+
+// assignedFolds creates folds from fold assignment of each sample.
+func assignedFolds(fold []int, nfolds int) []Fold {
+	splits := make([]Fold, nfolds)
+	for idx, f := range fold {
+		for i := range splits {
+			if i == f {
+				splits[i].Test = append(splits[i].Test, idx)
+			} else {
+				splits[i].Train = append(splits[i].Train, idx)
+			}
+		}
+	}
+	return splits
+}
+
+func indexRange(start, end int) []int {
+	indices := make([]int, 0, end-start)
+	for i := start; i < end; i++ {
+		indices = append(indices, i)
+	}
+	return indices
+}
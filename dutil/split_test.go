@@ -0,0 +1,142 @@
+package dutil_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch/dutil"
+)
+
+func TestStratifiedKFold(t *testing.T) {
+	labels := []int{0, 0, 0, 0, 0, 0, 1, 1, 1, 2, 2, 2}
+	kf, err := dutil.NewStratifiedKFold(labels, dutil.WithNFolds(3), dutil.WithKFoldShuffle(true), dutil.WithKFoldSeed(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	folds := kf.Split()
+	if len(folds) != 3 {
+		t.Fatalf("Want 3 folds, got %v\n", len(folds))
+	}
+	for _, f := range folds {
+		counts := make([]int, 3)
+		for _, idx := range f.Test {
+			counts[labels[idx]]++
+		}
+		if want := []int{2, 1, 1}; !reflect.DeepEqual(want, counts) {
+			t.Errorf("Want test label counts %v, got %v\n", want, counts)
+		}
+		if len(f.Train)+len(f.Test) != len(labels) {
+			t.Errorf("Want all samples in train and test, got %v\n", f)
+		}
+	}
+
+	// Reproducible with seed.
+	kf1, _ := dutil.NewStratifiedKFold(labels, dutil.WithNFolds(3), dutil.WithKFoldShuffle(true), dutil.WithKFoldSeed(1))
+	if !reflect.DeepEqual(folds, kf1.Split()) {
+		t.Errorf("Want same folds for same seed\n")
+	}
+}
+
+func TestGroupKFold(t *testing.T) {
+	groups := []int{1, 1, 1, 2, 2, 3, 4, 4, 4, 4, 5}
+	kf, err := dutil.NewGroupKFold(groups, dutil.WithNFolds(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sizes []int
+	for _, f := range kf.Split() {
+		test := make(map[int]bool)
+		for _, idx := range f.Test {
+			test[groups[idx]] = true
+		}
+		for _, idx := range f.Train {
+			if test[groups[idx]] {
+				t.Errorf("Group %v in both train and test sets\n", groups[idx])
+			}
+		}
+		sizes = append(sizes, len(f.Test))
+	}
+	// Groups 4 (4 samples), 1 (3), 2 (2), 3 (1), 5 (1) assigned to lightest fold.
+	if want := []int{4, 4, 3}; !reflect.DeepEqual(want, sizes) {
+		t.Errorf("Want fold sizes %v, got %v\n", want, sizes)
+	}
+
+	if _, err := dutil.NewGroupKFold([]int{1, 1, 2}, dutil.WithNFolds(3)); err == nil {
+		t.Errorf("Want error for nfolds greater than number of groups\n")
+	}
+}
+
+func TestRepeatedKFold(t *testing.T) {
+	kf, err := dutil.NewRepeatedKFold(10, 3, dutil.WithNFolds(5), dutil.WithKFoldSeed(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	folds := kf.Split()
+	if len(folds) != 15 {
+		t.Fatalf("Want 15 folds, got %v\n", len(folds))
+	}
+	if reflect.DeepEqual(folds[:5], folds[5:10]) {
+		t.Errorf("Want different folds for each repetition\n")
+	}
+
+	kf1, _ := dutil.NewRepeatedKFold(10, 3, dutil.WithNFolds(5), dutil.WithKFoldSeed(7))
+	if !reflect.DeepEqual(folds, kf1.Split()) {
+		t.Errorf("Want same folds for same seed\n")
+	}
+}
+
+func TestTimeSeriesSplit(t *testing.T) {
+	s, err := dutil.NewTimeSeriesSplit(10, dutil.WithNSplits(3), dutil.WithGap(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []dutil.Fold{
+		{Train: []int{0, 1, 2}, Test: []int{4, 5}},
+		{Train: []int{0, 1, 2, 3, 4}, Test: []int{6, 7}},
+		{Train: []int{0, 1, 2, 3, 4, 5, 6}, Test: []int{8, 9}},
+	}
+	if got := s.Split(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Want %v, got %v\n", want, got)
+	}
+
+	s1, err := dutil.NewTimeSeriesSplit(10, dutil.WithNSplits(3), dutil.WithMaxTrainSize(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s1.Split()[2].Train; !reflect.DeepEqual([]int{5, 6, 7}, got) {
+		t.Errorf("Want rolling train window [5 6 7], got %v\n", got)
+	}
+
+	if _, err := dutil.NewTimeSeriesSplit(5, dutil.WithNSplits(5)); err == nil {
+		t.Errorf("Want error for too many splits\n")
+	}
+}
+
+func TestTrainTestSplit(t *testing.T) {
+	f, err := dutil.TrainTestSplit(10, 0.25, dutil.WithSplitSeed(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Test) != 3 || len(f.Train) != 7 {
+		t.Errorf("Want 7 train and 3 test samples, got %v\n", f)
+	}
+
+	labels := []int{0, 0, 0, 0, 0, 0, 1, 1, 1, 1}
+	f, err = dutil.TrainTestSplit(10, 5, dutil.WithStratify(labels), dutil.WithSplitSeed(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make([]int, 2)
+	for _, idx := range f.Test {
+		counts[labels[idx]]++
+	}
+	if want := []int{3, 2}; !reflect.DeepEqual(want, counts) {
+		t.Errorf("Want test label counts %v, got %v\n", want, counts)
+	}
+
+	if _, err := dutil.TrainTestSplit(10, 10); err == nil {
+		t.Errorf("Want error for empty train set\n")
+	}
+}
@@ -0,0 +1,742 @@
+package dutil
+
+// Tabular (CSV) dataset with schema and feature preprocessing.
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sugarme/gotch/ts"
+)
+
+type ColumnKind int
+
+const (
+	NumericColumn ColumnKind = iota
+	CategoricalColumn
+	IgnoredColumn
+)
+
+func (k ColumnKind) String() string {
+	switch k {
+	case NumericColumn:
+		return "numeric"
+	case CategoricalColumn:
+		return "categorical"
+	case IgnoredColumn:
+		return "ignored"
+	default:
+		return fmt.Sprintf("ColumnKind(%d)", int(k))
+	}
+}
+
+// Column describes a CSV column.
+type Column struct {
+	Name string
+	Kind ColumnKind
+}
+
+// Schema describes CSV columns to use as features and label.
+//
+// Columns in the CSV file not listed in the schema are ignored. Label column should be
+// listed in Columns: a categorical label yields int64 class indexes, a numeric label
+// yields float32 targets.
+type Schema struct {
+	Columns []Column
+	Label   string // label column name. Empty if no label.
+}
+
+// Scaling is a scaling method of numeric features.
+type Scaling int
+
+const (
+	NoScaling   Scaling = iota
+	Standardize         // (x - mean) / std
+	MinMax              // (x - min) / (max - min)
+)
+
+// Imputation is a method to fill missing numeric values.
+type Imputation int
+
+const (
+	ImputeMean Imputation = iota
+	ImputeMedian
+	ImputeZero
+)
+
+type TabularOptions struct {
+	Comma         rune       // field delimiter. Default=','
+	Header        bool       // whether first record is a header. Default=true
+	InferRows     int        // number of records used to infer schema. Default=1000
+	MissingValues []string   // values considered missing. Default="", "NA", "NaN", "nan", "null", "?"
+	Scaling       Scaling    // scaling of numeric features. Default=Standardize
+	Imputation    Imputation // filling of missing numeric values. Default=ImputeMean
+	OneHot        bool       // whether to one-hot encode categorical features instead of index. Default=false
+}
+
+type TabularOption func(*TabularOptions)
+
+func NewTabularOptions(options ...TabularOption) TabularOptions {
+	opts := TabularOptions{
+		Comma:         ',',
+		Header:        true,
+		InferRows:     1000,
+		MissingValues: []string{"", "NA", "NaN", "nan", "null", "?"},
+		Scaling:       Standardize,
+		Imputation:    ImputeMean,
+		OneHot:        false,
+	}
+
+	for _, o := range options {
+		o(&opts)
+	}
+
+	return opts
+}
+
+func WithComma(comma rune) TabularOption {
+	return func(o *TabularOptions) {
+		o.Comma = comma
+	}
+}
+
+func WithHeader(header bool) TabularOption {
+	return func(o *TabularOptions) {
+		o.Header = header
+	}
+}
+
+func WithInferRows(n int) TabularOption {
+	return func(o *TabularOptions) {
+		o.InferRows = n
+	}
+}
+
+func WithMissingValues(values ...string) TabularOption {
+	return func(o *TabularOptions) {
+		o.MissingValues = values
+	}
+}
+
+func WithScaling(scaling Scaling) TabularOption {
+	return func(o *TabularOptions) {
+		o.Scaling = scaling
+	}
+}
+
+func WithImputation(imputation Imputation) TabularOption {
+	return func(o *TabularOptions) {
+		o.Imputation = imputation
+	}
+}
+
+func WithOneHot(oneHot bool) TabularOption {
+	return func(o *TabularOptions) {
+		o.OneHot = oneHot
+	}
+}
+
+// ColumnStats holds fitted statistics of a column.
+type ColumnStats struct {
+	Name  string     `json:"name"`
+	Kind  ColumnKind `json:"kind"`
+	Mean  float64    `json:"mean,omitempty"`
+	Std   float64    `json:"std,omitempty"`
+	Min   float64    `json:"min,omitempty"`
+	Max   float64    `json:"max,omitempty"`
+	Fill  float64    `json:"fill,omitempty"`  // imputed value of missing numeric values.
+	Vocab []string   `json:"vocab,omitempty"` // categories. Feature index i+1 is Vocab[i], 0 is unknown or missing.
+}
+
+// TabularStats holds fitted preprocessing statistics of a TabularDataset.
+type TabularStats struct {
+	Scaling    Scaling       `json:"scaling"`
+	Imputation Imputation    `json:"imputation"`
+	OneHot     bool          `json:"one_hot"`
+	Features   []ColumnStats `json:"features"`
+	Label      *ColumnStats  `json:"label,omitempty"` // label categories are class indexes without unknown.
+}
+
+// TabularSample is a sample of a TabularDataset.
+type TabularSample struct {
+	Features *ts.Tensor // float32 features of shape [NumFeatures]
+	Label    *ts.Tensor // scalar int64 class index or float32 target. Nil if no label.
+}
+
+// TabularDataset is a dataset of CSV records streamed from file. Only byte offsets of
+// records are kept in memory.
+//
+// It implements TypedDataset[TabularSample] and can be used with TypedDataLoader,
+// ParallelDataLoader (see CollateStruct) or, via ToDataset, with DataLoader.
+// Preprocessing statistics should be fitted (Fit) or loaded (LoadStats) before
+// getting samples.
+type TabularDataset struct {
+	file    *os.File
+	schema  *Schema
+	opts    TabularOptions
+	header  []string
+	offsets []int64 // offsets[i] is start of record i, offsets[len] is end of last record.
+	columns []int   // CSV column index of features.
+	label   int     // CSV column index of label. -1 if no label.
+	missing map[string]bool
+	stats   *TabularStats
+	vocabs  []map[string]int // category index of categorical features.
+	labels  map[string]int   // class index of categorical label.
+}
+
+// NewTabularDataset creates a TabularDataset from a CSV file. If schema is nil, it is
+// inferred (see InferSchema) without label.
+func NewTabularDataset(path string, schema *Schema, opts ...TabularOption) (*TabularDataset, error) {
+	o := NewTabularOptions(opts...)
+
+	var err error
+	if schema == nil {
+		schema, err = InferSchema(path, "", opts...)
+		if err != nil {
+			err = fmt.Errorf("NewTabularDataset() failed: %w", err)
+			return nil, err
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		err = fmt.Errorf("NewTabularDataset() failed: %w", err)
+		return nil, err
+	}
+
+	ds := &TabularDataset{
+		file:    f,
+		schema:  schema,
+		opts:    o,
+		label:   -1,
+		missing: make(map[string]bool),
+	}
+	for _, v := range o.MissingValues {
+		ds.missing[v] = true
+	}
+
+	if err := ds.index(); err != nil {
+		f.Close()
+		err = fmt.Errorf("NewTabularDataset() failed: %w", err)
+		return nil, err
+	}
+
+	return ds, nil
+}
+
+// index reads record offsets and maps schema columns to CSV columns.
+func (ds *TabularDataset) index() error {
+	r := newCSVReader(ds.file, ds.opts)
+	var prev int64
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if ds.header == nil {
+			ds.header = columnNames(record, ds.opts.Header)
+			if ds.opts.Header {
+				prev = r.InputOffset()
+				continue
+			}
+		}
+		ds.offsets = append(ds.offsets, prev)
+		prev = r.InputOffset()
+	}
+	ds.offsets = append(ds.offsets, prev)
+	if ds.header == nil {
+		return fmt.Errorf("empty CSV file")
+	}
+
+	position := make(map[string]int)
+	for i, name := range ds.header {
+		position[name] = i
+	}
+	for _, col := range ds.schema.Columns {
+		idx, ok := position[col.Name]
+		if !ok {
+			return fmt.Errorf("schema column %q not found in CSV columns %v", col.Name, ds.header)
+		}
+		switch {
+		case col.Name == ds.schema.Label:
+			if col.Kind == IgnoredColumn {
+				return fmt.Errorf("label column %q can not be ignored", col.Name)
+			}
+			ds.label = idx
+		case col.Kind != IgnoredColumn:
+			ds.columns = append(ds.columns, idx)
+		}
+	}
+	if ds.schema.Label != "" && ds.label < 0 {
+		return fmt.Errorf("label column %q not found in schema columns", ds.schema.Label)
+	}
+
+	return nil
+}
+
+// record reads CSV record idx.
+func (ds *TabularDataset) record(idx int) ([]string, error) {
+	if idx < 0 || idx >= ds.Len() {
+		err := fmt.Errorf("Idx is out of range.")
+		return nil, err
+	}
+	start, end := ds.offsets[idx], ds.offsets[idx+1]
+	r := newCSVReader(io.NewSectionReader(ds.file, start, end-start), ds.opts)
+	record, err := r.Read()
+	if err != nil {
+		err = fmt.Errorf("reading record %v failed: %w", idx, err)
+		return nil, err
+	}
+	if len(record) != len(ds.header) {
+		err := fmt.Errorf("record %v has %v fields, expected %v", idx, len(record), len(ds.header))
+		return nil, err
+	}
+	return record, nil
+}
+
+// Len implements TypedDataset interface.
+func (ds *TabularDataset) Len() int {
+	return len(ds.offsets) - 1
+}
+
+// Schema returns dataset schema.
+func (ds *TabularDataset) Schema() *Schema {
+	return ds.schema
+}
+
+// Fit fits preprocessing statistics on records of given indexes (e.g. train set of a Fold)
+// or all records if indexes is nil.
+func (ds *TabularDataset) Fit(indexes []int) error {
+	if indexes == nil {
+		indexes = intRange(ds.Len())
+	}
+
+	stats := &TabularStats{
+		Scaling:    ds.opts.Scaling,
+		Imputation: ds.opts.Imputation,
+		OneHot:     ds.opts.OneHot,
+	}
+	values := make([][]float64, len(ds.columns))
+	categories := make([]map[string]bool, len(ds.columns))
+	for i := range categories {
+		categories[i] = make(map[string]bool)
+	}
+	labels := make(map[string]bool)
+
+	for _, idx := range indexes {
+		record, err := ds.record(idx)
+		if err != nil {
+			err = fmt.Errorf("Fit() failed: %w", err)
+			return err
+		}
+		for i, col := range ds.columns {
+			v := strings.TrimSpace(record[col])
+			if ds.missing[v] {
+				continue
+			}
+			if ds.kind(col) == CategoricalColumn {
+				categories[i][v] = true
+				continue
+			}
+			x, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				err = fmt.Errorf("Fit() failed: record %v column %q: %w", idx, ds.header[col], err)
+				return err
+			}
+			values[i] = append(values[i], x)
+		}
+		if ds.label >= 0 && ds.kind(ds.label) == CategoricalColumn {
+			labels[strings.TrimSpace(record[ds.label])] = true
+		}
+	}
+
+	for i, col := range ds.columns {
+		cs := ColumnStats{Name: ds.header[col], Kind: ds.kind(col)}
+		if cs.Kind == CategoricalColumn {
+			cs.Vocab = sortedKeys(categories[i])
+		} else {
+			fitNumeric(&cs, values[i], ds.opts.Imputation)
+		}
+		stats.Features = append(stats.Features, cs)
+	}
+	if ds.label >= 0 {
+		stats.Label = &ColumnStats{Name: ds.header[ds.label], Kind: ds.kind(ds.label)}
+		if stats.Label.Kind == CategoricalColumn {
+			stats.Label.Vocab = sortedLabels(labels)
+		}
+	}
+
+	return ds.setStats(stats)
+}
+
+func (ds *TabularDataset) kind(col int) ColumnKind {
+	for _, c := range ds.schema.Columns {
+		if c.Name == ds.header[col] {
+			return c.Kind
+		}
+	}
+	return IgnoredColumn
+}
+
+func fitNumeric(cs *ColumnStats, values []float64, imputation Imputation) {
+	if len(values) == 0 {
+		cs.Std = 1
+		return
+	}
+
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	cs.Min, cs.Max = sorted[0], sorted[len(sorted)-1]
+	var sum float64
+	for _, x := range values {
+		sum += x
+	}
+	cs.Mean = sum / float64(len(values))
+	var ss float64
+	for _, x := range values {
+		ss += (x - cs.Mean) * (x - cs.Mean)
+	}
+	cs.Std = math.Sqrt(ss / float64(len(values)))
+
+	switch imputation {
+	case ImputeMean:
+		cs.Fill = cs.Mean
+	case ImputeMedian:
+		n := len(sorted)
+		cs.Fill = sorted[n/2]
+		if n%2 == 0 {
+			cs.Fill = (sorted[n/2-1] + sorted[n/2]) / 2
+		}
+	case ImputeZero:
+		cs.Fill = 0
+	}
+}
+
+func (ds *TabularDataset) setStats(stats *TabularStats) error {
+	if len(stats.Features) != len(ds.columns) {
+		err := fmt.Errorf("stats have %v feature columns, expected %v", len(stats.Features), len(ds.columns))
+		return err
+	}
+	vocabs := make([]map[string]int, len(ds.columns))
+	for i, col := range ds.columns {
+		cs := stats.Features[i]
+		if cs.Name != ds.header[col] || cs.Kind != ds.kind(col) {
+			err := fmt.Errorf("stats column %q (%v) mismatched with schema column %q (%v)", cs.Name, cs.Kind, ds.header[col], ds.kind(col))
+			return err
+		}
+		vocabs[i] = make(map[string]int)
+		for j, v := range cs.Vocab {
+			vocabs[i][v] = j + 1
+		}
+	}
+	if (stats.Label != nil) != (ds.label >= 0) {
+		err := fmt.Errorf("stats label mismatched with schema label %q", ds.schema.Label)
+		return err
+	}
+	var labels map[string]int
+	if stats.Label != nil {
+		labels = make(map[string]int)
+		for j, v := range stats.Label.Vocab {
+			labels[v] = j
+		}
+	}
+
+	ds.stats = stats
+	ds.vocabs = vocabs
+	ds.labels = labels
+	return nil
+}
+
+// Stats returns fitted preprocessing statistics. Nil if not fitted.
+func (ds *TabularDataset) Stats() *TabularStats {
+	return ds.stats
+}
+
+// SaveStats saves fitted preprocessing statistics to a JSON file.
+func (ds *TabularDataset) SaveStats(path string) error {
+	if ds.stats == nil {
+		err := fmt.Errorf("SaveStats() failed: dataset is not fitted")
+		return err
+	}
+	data, err := json.MarshalIndent(ds.stats, "", "  ")
+	if err != nil {
+		err = fmt.Errorf("SaveStats() failed: %w", err)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadStats loads preprocessing statistics saved by SaveStats, e.g. statistics fitted on
+// a train set to preprocess a test set. Statistics options (scaling, imputation, one-hot)
+// override dataset options.
+func (ds *TabularDataset) LoadStats(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		err = fmt.Errorf("LoadStats() failed: %w", err)
+		return err
+	}
+	var stats TabularStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		err = fmt.Errorf("LoadStats() failed: %w", err)
+		return err
+	}
+	if err := ds.setStats(&stats); err != nil {
+		err = fmt.Errorf("LoadStats() failed: %w", err)
+		return err
+	}
+	ds.opts.Scaling, ds.opts.Imputation, ds.opts.OneHot = stats.Scaling, stats.Imputation, stats.OneHot
+	return nil
+}
+
+// NumFeatures returns number of features of a sample.
+func (ds *TabularDataset) NumFeatures() int {
+	if ds.stats == nil || !ds.stats.OneHot {
+		return len(ds.columns)
+	}
+	var n int
+	for _, cs := range ds.stats.Features {
+		if cs.Kind == CategoricalColumn {
+			n += len(cs.Vocab) + 1
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// NumClasses returns number of label classes of categorical label, otherwise 0.
+func (ds *TabularDataset) NumClasses() int {
+	if ds.stats == nil || ds.stats.Label == nil {
+		return 0
+	}
+	return len(ds.stats.Label.Vocab)
+}
+
+// Item implements TypedDataset interface.
+func (ds *TabularDataset) Item(idx int) (TabularSample, error) {
+	var sample TabularSample
+	if ds.stats == nil {
+		err := fmt.Errorf("Item() failed: dataset is not fitted. Call Fit() or LoadStats() first")
+		return sample, err
+	}
+	record, err := ds.record(idx)
+	if err != nil {
+		return sample, err
+	}
+
+	features := make([]float32, 0, ds.NumFeatures())
+	for i, col := range ds.columns {
+		cs := ds.stats.Features[i]
+		v := strings.TrimSpace(record[col])
+		if cs.Kind == CategoricalColumn {
+			category := ds.vocabs[i][v] // unknown or missing: 0
+			if !ds.stats.OneHot {
+				features = append(features, float32(category))
+				continue
+			}
+			oneHot := make([]float32, len(cs.Vocab)+1)
+			oneHot[category] = 1
+			features = append(features, oneHot...)
+			continue
+		}
+
+		x := cs.Fill
+		if !ds.missing[v] {
+			if x, err = strconv.ParseFloat(v, 64); err != nil {
+				err = fmt.Errorf("Item() failed: record %v column %q: %w", idx, cs.Name, err)
+				return sample, err
+			}
+		}
+		features = append(features, float32(scale(x, cs, ds.stats.Scaling)))
+	}
+
+	if ds.label >= 0 {
+		v := strings.TrimSpace(record[ds.label])
+		var label *ts.Tensor
+		if ds.stats.Label.Kind == CategoricalColumn {
+			class, ok := ds.labels[v]
+			if !ok {
+				err := fmt.Errorf("Item() failed: record %v has unknown label %q", idx, v)
+				return sample, err
+			}
+			label = ts.MustOfSlice([]int64{int64(class)})
+		} else {
+			y, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				err = fmt.Errorf("Item() failed: record %v label: %w", idx, err)
+				return sample, err
+			}
+			label = ts.MustOfSlice([]float32{float32(y)})
+		}
+		sample.Label = label.MustView([]int64{}, true)
+	}
+	sample.Features = ts.MustOfSlice(features)
+
+	return sample, nil
+}
+
+func scale(x float64, cs ColumnStats, scaling Scaling) float64 {
+	switch scaling {
+	case Standardize:
+		if cs.Std == 0 {
+			return x - cs.Mean
+		}
+		return (x - cs.Mean) / cs.Std
+	case MinMax:
+		if cs.Max == cs.Min {
+			return x - cs.Min
+		}
+		return (x - cs.Min) / (cs.Max - cs.Min)
+	default:
+		return x
+	}
+}
+
+// Close closes CSV file.
+func (ds *TabularDataset) Close() error {
+	return ds.file.Close()
+}
+
+// InferSchema infers a schema from the first InferRows records of a CSV file. A column is
+// numeric if all its non-missing values are numbers, otherwise categorical. Label column
+// (if not empty) is categorical unless it has non-integer numbers.
+func InferSchema(path string, label string, opts ...TabularOption) (*Schema, error) {
+	o := NewTabularOptions(opts...)
+	missing := make(map[string]bool)
+	for _, v := range o.MissingValues {
+		missing[v] = true
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		err = fmt.Errorf("InferSchema() failed: %w", err)
+		return nil, err
+	}
+	defer f.Close()
+
+	r := newCSVReader(f, o)
+	var (
+		names   []string
+		numeric []bool
+		integer []bool
+	)
+	for n := 0; n < o.InferRows; {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			err = fmt.Errorf("InferSchema() failed: %w", err)
+			return nil, err
+		}
+		if names == nil {
+			names = columnNames(record, o.Header)
+			numeric = make([]bool, len(names))
+			integer = make([]bool, len(names))
+			for i := range names {
+				numeric[i], integer[i] = true, true
+			}
+			if o.Header {
+				continue
+			}
+		}
+		if len(record) != len(names) {
+			err := fmt.Errorf("InferSchema() failed: record %v has %v fields, expected %v", n, len(record), len(names))
+			return nil, err
+		}
+		for i, v := range record {
+			if v = strings.TrimSpace(v); missing[v] {
+				continue
+			}
+			x, err := strconv.ParseFloat(v, 64)
+			numeric[i] = numeric[i] && err == nil
+			integer[i] = integer[i] && err == nil && x == math.Trunc(x)
+		}
+		n++
+	}
+	if names == nil {
+		err := fmt.Errorf("InferSchema() failed: empty CSV file")
+		return nil, err
+	}
+
+	schema := &Schema{Label: label}
+	for i, name := range names {
+		kind := CategoricalColumn
+		if numeric[i] && !(name == label && integer[i]) {
+			kind = NumericColumn
+		}
+		schema.Columns = append(schema.Columns, Column{name, kind})
+	}
+	if label != "" && indexOf(names, label) < 0 {
+		err := fmt.Errorf("InferSchema() failed: label column %q not found in CSV columns %v", label, names)
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+func newCSVReader(r io.Reader, opts TabularOptions) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.Comma = opts.Comma
+	reader.FieldsPerRecord = -1
+	return reader
+}
+
+// columnNames returns header names or "col0", "col1", ... if no header.
+func columnNames(record []string, header bool) []string {
+	names := make([]string, len(record))
+	for i, v := range record {
+		if header {
+			names[i] = strings.TrimSpace(v)
+		} else {
+			names[i] = fmt.Sprintf("col%d", i)
+		}
+	}
+	return names
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedLabels returns label classes sorted numerically if all are integers, otherwise
+// lexically, so that integer labels keep their order (e.g. "2" before "10").
+func sortedLabels(m map[string]bool) []string {
+	keys := sortedKeys(m)
+	ints := make(map[string]int64, len(keys))
+	for _, k := range keys {
+		x, err := strconv.ParseInt(k, 10, 64)
+		if err != nil {
+			return keys
+		}
+		ints[k] = x
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return ints[keys[i]] < ints[keys[j]]
+	})
+	return keys
+}
+
+func indexOf(data []string, item string) int {
+	for i, el := range data {
+		if el == item {
+			return i
+		}
+	}
+	return -1
+}
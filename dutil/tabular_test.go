@@ -0,0 +1,215 @@
+package dutil_test
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch/dutil"
+)
+
+const tabularCSV = `age,city,income,"note, quoted",label
+20,hanoi,1.5,a,yes
+30,paris,NA,b,no
+40,hanoi,4.5,"multi
+line",yes
+,tokyo,3.0,d,no
+`
+
+func writeCSV(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestInferSchema(t *testing.T) {
+	path := writeCSV(t, tabularCSV)
+	schema, err := dutil.InferSchema(path, "label")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []dutil.Column{
+		{"age", dutil.NumericColumn},
+		{"city", dutil.CategoricalColumn},
+		{"income", dutil.NumericColumn},
+		{"note, quoted", dutil.CategoricalColumn},
+		{"label", dutil.CategoricalColumn},
+	}
+	if !reflect.DeepEqual(want, schema.Columns) {
+		t.Errorf("want %v, got %v\n", want, schema.Columns)
+	}
+
+	if _, err := dutil.InferSchema(path, "missing"); err == nil {
+		t.Errorf("want error for missing label column\n")
+	}
+
+	ragged := writeCSV(t, "a,b\n1,2\n3,4,5\n")
+	if _, err := dutil.InferSchema(ragged, ""); err == nil {
+		t.Errorf("want error for record with more fields than header\n")
+	}
+}
+
+func TestTabularDataset_IntegerLabels(t *testing.T) {
+	path := writeCSV(t, "x,label\n1,10\n2,2\n3,1\n4,2\n")
+	schema, err := dutil.InferSchema(path, "label")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := dutil.NewTabularDataset(path, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ds.Close()
+	if err := ds.Fit(nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"1", "2", "10"}; !reflect.DeepEqual(want, ds.Stats().Label.Vocab) {
+		t.Errorf("want label classes %v, got %v\n", want, ds.Stats().Label.Vocab)
+	}
+	for idx, want := range []int64{2, 1, 0, 1} {
+		sample, err := ds.Item(idx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sample.Label.Int64Values()[0]; got != want {
+			t.Errorf("record %v: want class %v, got %v\n", idx, want, got)
+		}
+	}
+}
+
+func TestTabularDataset_Unlabeled(t *testing.T) {
+	path := writeCSV(t, "x,y\n1,2\n3,4\n")
+	ds, err := dutil.NewTabularDataset(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ds.Close()
+	if err := ds.Fit(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	samples := make([]dutil.TabularSample, ds.Len())
+	for i := range samples {
+		if samples[i], err = ds.Item(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	batch, err := dutil.CollateStruct(samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := batch["Label"]; ok || len(batch) != 1 {
+		t.Errorf("want features only without label column, got %v\n", batch)
+	}
+	if want, got := []int64{2, 2}, batch["Features"].MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want features shape %v, got %v\n", want, got)
+	}
+}
+
+func TestTabularDataset(t *testing.T) {
+	path := writeCSV(t, tabularCSV)
+	schema := &dutil.Schema{
+		Columns: []dutil.Column{
+			{"age", dutil.NumericColumn},
+			{"city", dutil.CategoricalColumn},
+			{"income", dutil.NumericColumn},
+			{"label", dutil.CategoricalColumn},
+		},
+		Label: "label",
+	}
+	ds, err := dutil.NewTabularDataset(path, schema, dutil.WithScaling(dutil.MinMax), dutil.WithImputation(dutil.ImputeMedian))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ds.Close()
+
+	if ds.Len() != 4 {
+		t.Fatalf("want 4 records, got %v\n", ds.Len())
+	}
+	if _, err := ds.Item(0); err == nil {
+		t.Errorf("want error before fitting\n")
+	}
+
+	// Fit on first 3 records: tokyo is unknown.
+	if err := ds.Fit([]int{0, 1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if ds.NumFeatures() != 3 || ds.NumClasses() != 2 {
+		t.Errorf("want 3 features and 2 classes, got %v and %v\n", ds.NumFeatures(), ds.NumClasses())
+	}
+
+	check := func(idx int, wantFeatures []float64, wantLabel int64) {
+		t.Helper()
+		sample, err := ds.Item(idx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := sample.Features.Float64Values()
+		for i := range wantFeatures {
+			if math.Abs(got[i]-wantFeatures[i]) > 1e-6 {
+				t.Errorf("record %v: want features %v, got %v\n", idx, wantFeatures, got)
+				break
+			}
+		}
+		if label := sample.Label.Int64Values()[0]; label != wantLabel {
+			t.Errorf("record %v: want label %v, got %v\n", idx, wantLabel, label)
+		}
+	}
+	// age min-max [20, 40], city vocab [hanoi paris], income [1.5, 4.5] median 3.0, label vocab [no yes].
+	check(1, []float64{0.5, 2, 0.5}, 0)
+	check(2, []float64{1, 1, 1}, 1)
+	check(3, []float64{0.5, 0, 0.5}, 0)
+
+	// Persisted stats applied to another dataset.
+	statsPath := filepath.Join(t.TempDir(), "stats.json")
+	if err := ds.SaveStats(statsPath); err != nil {
+		t.Fatal(err)
+	}
+	ds1, err := dutil.NewTabularDataset(path, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ds1.Close()
+	if err := ds1.LoadStats(statsPath); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ds.Stats(), ds1.Stats()) {
+		t.Errorf("want loaded stats %+v, got %+v\n", ds.Stats(), ds1.Stats())
+	}
+
+	// One-hot categorical features.
+	ds2, err := dutil.NewTabularDataset(path, schema, dutil.WithOneHot(true), dutil.WithScaling(dutil.NoScaling))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ds2.Close()
+	if err := ds2.Fit(nil); err != nil {
+		t.Fatal(err)
+	}
+	sample, err := ds2.Item(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// city vocab [hanoi paris tokyo] + unknown.
+	if want, got := []float64{30, 0, 0, 1, 0, 3}, sample.Features.Float64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want features %v, got %v\n", want, got)
+	}
+
+	// Typed data loader batching with CollateStruct.
+	dl, err := dutil.NewTypedDataLoader[dutil.TabularSample](ds, nil, dutil.CollateStruct[dutil.TabularSample])
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch, err := dl.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := []int64{1, 3}, batch["Features"].MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want features shape %v, got %v\n", want, got)
+	}
+}
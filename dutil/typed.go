@@ -0,0 +1,178 @@
+package dutil
+
+// Type-safe datasets and data loader using generics.
+//
+// NOTE. They are named `TypedDataset` and `TypedDataLoader` to keep the existing
+// `Dataset` and `DataLoader` API.
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// TypedDataset is a dataset of samples of type T.
+type TypedDataset[T any] interface {
+	Item(idx int) (T, error)
+	Len() int
+}
+
+// TypedSliceDataset is a slice of samples of type T.
+type TypedSliceDataset[T any] struct {
+	data []T
+}
+
+// NewTypedSliceDataset creates a new TypedSliceDataset.
+func NewTypedSliceDataset[T any](data []T) *TypedSliceDataset[T] {
+	return &TypedSliceDataset[T]{data}
+}
+
+// Item implements TypedDataset interface.
+func (ds *TypedSliceDataset[T]) Item(idx int) (T, error) {
+	if idx < 0 || idx >= len(ds.data) {
+		var zero T
+		err := fmt.Errorf("Idx is out of range.")
+		return zero, err
+	}
+	return ds.data[idx], nil
+}
+
+// Len implements TypedDataset interface.
+func (ds *TypedSliceDataset[T]) Len() int {
+	return len(ds.data)
+}
+
+// datasetAdapter adapts a Dataset to TypedDataset.
+type datasetAdapter[T any] struct {
+	ds Dataset
+}
+
+// FromDataset adapts a Dataset (e.g. SliceDataset, MapDataset) to a TypedDataset[T].
+// Item returns an error if a sample is not of type T.
+func FromDataset[T any](ds Dataset) TypedDataset[T] {
+	return &datasetAdapter[T]{ds}
+}
+
+func (a *datasetAdapter[T]) Item(idx int) (T, error) {
+	var zero T
+	item, err := a.ds.Item(idx)
+	if err != nil {
+		return zero, err
+	}
+	v, ok := item.(T)
+	if !ok {
+		err := fmt.Errorf("Invalid item type: expected %T, got %T", zero, item)
+		return zero, err
+	}
+	return v, nil
+}
+
+func (a *datasetAdapter[T]) Len() int {
+	return a.ds.Len()
+}
+
+// typedAdapter adapts a TypedDataset to Dataset.
+type typedAdapter[T any] struct {
+	ds TypedDataset[T]
+}
+
+// ToDataset adapts a TypedDataset[T] to a Dataset of slice kind so that it can be used
+// with DataLoader and other Dataset based helpers.
+func ToDataset[T any](ds TypedDataset[T]) Dataset {
+	return &typedAdapter[T]{ds}
+}
+
+func (a *typedAdapter[T]) Item(idx int) (interface{}, error) {
+	return a.ds.Item(idx)
+}
+
+func (a *typedAdapter[T]) Len() int {
+	return a.ds.Len()
+}
+
+func (a *typedAdapter[T]) DType() reflect.Type {
+	return reflect.TypeOf([]T(nil))
+}
+
+// Collate merges samples into a batch.
+type Collate[T, B any] func(items []T) (B, error)
+
+// CollateSlice is a Collate returning samples as they are.
+func CollateSlice[T any](items []T) ([]T, error) {
+	return items, nil
+}
+
+// TypedDataLoader combines a typed dataset, a sampler and a collate function and provides
+// an iterable over batches of type B.
+type TypedDataLoader[T, B any] struct {
+	dataset   TypedDataset[T]
+	sampler   Sampler
+	collate   Collate[T, B]
+	indexes   []int // order of samples in dataset for iteration.
+	batchSize int
+	currIdx   int
+}
+
+// NewTypedDataLoader creates a new TypedDataLoader. If sampler is nil, samples are drawn
+// sequentially one by one. Batch size is taken from the sampler (e.g. BatchSampler).
+func NewTypedDataLoader[T, B any](data TypedDataset[T], s Sampler, collate Collate[T, B]) (*TypedDataLoader[T, B], error) {
+	if collate == nil {
+		err := fmt.Errorf("NewTypedDataLoader() failed: nil collate function")
+		return nil, err
+	}
+	if s == nil {
+		s = NewSequentialSampler(data.Len())
+	}
+
+	return &TypedDataLoader[T, B]{
+		dataset:   data,
+		sampler:   s,
+		collate:   collate,
+		indexes:   s.Sample(),
+		batchSize: s.BatchSize(),
+		currIdx:   0,
+	}, nil
+}
+
+// HasNext returns whether there is a next batch in the iteration.
+func (dl *TypedDataLoader[T, B]) HasNext() bool {
+	return dl.currIdx < len(dl.indexes)
+}
+
+// Next returns next batch.
+func (dl *TypedDataLoader[T, B]) Next() (B, error) {
+	var zero B
+	if !dl.HasNext() {
+		err := fmt.Errorf("Next Error: no more item to iterate.\n")
+		return zero, err
+	}
+
+	nextIndex := dl.currIdx + dl.batchSize
+	if nextIndex >= len(dl.indexes) {
+		nextIndex = len(dl.indexes)
+	}
+
+	items := make([]T, 0, nextIndex-dl.currIdx)
+	for _, idx := range dl.indexes[dl.currIdx:nextIndex] {
+		item, err := dl.dataset.Item(idx)
+		if err != nil {
+			return zero, err
+		}
+		items = append(items, item)
+	}
+	dl.currIdx = nextIndex
+
+	return dl.collate(items)
+}
+
+// Reset resets index to start position. If shuffle is true, indexes are re-sampled.
+func (dl *TypedDataLoader[T, B]) Reset(shuffleOpt ...bool) {
+	if len(shuffleOpt) > 0 && shuffleOpt[0] {
+		dl.indexes = dl.sampler.Sample()
+	}
+	dl.currIdx = 0
+}
+
+// Len returns number of samples to be iterated.
+func (dl *TypedDataLoader[T, B]) Len() int {
+	return len(dl.indexes)
+}
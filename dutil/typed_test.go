@@ -0,0 +1,102 @@
+package dutil_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch/dutil"
+)
+
+type sample struct {
+	X float64
+	Y int
+}
+
+func TestTypedDataLoader(t *testing.T) {
+	data := dutil.NewTypedSliceDataset([]sample{{0.1, 0}, {0.2, 1}, {0.3, 0}, {0.4, 1}, {0.5, 0}})
+	s, err := dutil.NewBatchSampler(data.Len(), 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Collate samples into (features, labels).
+	type batch struct {
+		xs []float64
+		ys []int
+	}
+	collate := func(items []sample) (batch, error) {
+		var b batch
+		for _, it := range items {
+			b.xs = append(b.xs, it.X)
+			b.ys = append(b.ys, it.Y)
+		}
+		return b, nil
+	}
+
+	dl, err := dutil.NewTypedDataLoader[sample, batch](data, s, collate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got [][]int
+	for dl.HasNext() {
+		b, err := dl.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, b.ys)
+	}
+	want := [][]int{{0, 1}, {0, 1}, {0}}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v\n", want, got)
+	}
+
+	if _, err := dl.Next(); err == nil {
+		t.Errorf("want error after last batch\n")
+	}
+	dl.Reset()
+	if b, _ := dl.Next(); !reflect.DeepEqual([]float64{0.1, 0.2}, b.xs) {
+		t.Errorf("want first batch after Reset, got %v\n", b.xs)
+	}
+}
+
+func TestTypedDataset_Adapters(t *testing.T) {
+	mds, err := dutil.NewMapDataset(map[string]int{"b": 2, "a": 1, "c": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dl, err := dutil.NewTypedDataLoader(dutil.FromDataset[int](mds), nil, dutil.CollateSlice[int])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	for dl.HasNext() {
+		b, err := dl.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, b...)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v\n", want, got)
+	}
+
+	if _, err := dutil.FromDataset[string](mds).Item(0); err == nil {
+		t.Errorf("want error for mismatched item type\n")
+	}
+
+	// Typed dataset used with untyped DataLoader.
+	ds := dutil.ToDataset[int](dutil.NewTypedSliceDataset([]int{7, 8, 9}))
+	udl, err := dutil.NewDataLoader(ds, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, err := udl.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{7}; !reflect.DeepEqual(want, item) {
+		t.Errorf("want %v, got %v\n", want, item)
+	}
+}
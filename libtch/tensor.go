@@ -6,6 +6,10 @@ package libtch
 //#include "stdlib.h"
 //void callback_fn(void *, char *, tensor);
 //typedef void (*f)(void *, char *, tensor);
+//void callback_module_fn(void *, char *, module);
+//typedef void (*fm)(void *, char *, module);
+//void callback_name_fn(void *, char *);
+//typedef void (*fn)(void *, char *);
 import "C"
 
 import (
@@ -140,6 +144,12 @@ func GetAndResetLastErr() *C.char {
 	return C.get_and_reset_last_err()
 }
 
+// void at_manual_seed(int64_t);
+func AtManualSeed(seed int64) {
+	cseed := *(*C.int64_t)(unsafe.Pointer(&seed))
+	C.at_manual_seed(cseed)
+}
+
 // int atc_cuda_device_count();
 func AtcCudaDeviceCount() int32 {
 	result := C.atc_cuda_device_count()
@@ -359,6 +369,36 @@ func callback_fn(dataPtr unsafe.Pointer, name *C.char, ctensor C.tensor) {
 	data.NamedCtensors = append(data.NamedCtensors, namedCtensor)
 }
 
+type NamedCmodule struct {
+	Name    string
+	Cmodule C.module
+}
+
+type LoadModuleData struct {
+	NamedCmodules []NamedCmodule
+}
+
+//export callback_module_fn
+func callback_module_fn(dataPtr unsafe.Pointer, name *C.char, cmodule C.module) {
+	namedCmodule := NamedCmodule{
+		Name:    C.GoString(name),
+		Cmodule: cmodule,
+	}
+
+	data := PStore.Get(dataPtr).(*LoadModuleData)
+	data.NamedCmodules = append(data.NamedCmodules, namedCmodule)
+}
+
+type NameData struct {
+	Names []string
+}
+
+//export callback_name_fn
+func callback_name_fn(dataPtr unsafe.Pointer, name *C.char) {
+	data := PStore.Get(dataPtr).(*NameData)
+	data.Names = append(data.Names, C.GoString(name))
+}
+
 /*
  * void at_load_callback_with_device(char *filename, void *data, void (*f)(void *, char *, tensor), int device_id) {
  *   PROTECT(
@@ -587,6 +627,15 @@ func AtLoadImage(path string) Ctensor {
 	return C.at_load_image(cpath)
 }
 
+// tensor at_load_image_from_memory(unsigned char *img_data, size_t img_size);
+func AtLoadImageFromMemory(data []byte) Ctensor {
+	cdata := C.CBytes(data)
+	defer C.free(cdata)
+	size := len(data)
+	csize := *(*C.size_t)(unsafe.Pointer(&size))
+	return C.at_load_image_from_memory((*C.uchar)(cdata), csize)
+}
+
 // int at_save_image(tensor, char *filename);
 func AtSaveImage(ts Ctensor, path string) {
 	cpath := C.CString(path)
@@ -816,6 +865,95 @@ func AtiFree(val Civalue) {
 	C.ati_free(val)
 }
 
+// ivalue ati_device(int);
+func AtiDevice(device int32) Civalue {
+	cdevice := *(*C.int)(unsafe.Pointer(&device))
+	return C.ati_device(cdevice)
+}
+
+// int ati_to_device(ivalue);
+func AtiToDevice(val Civalue) int32 {
+	retVal := C.ati_to_device(val)
+	return *(*int32)(unsafe.Pointer(&retVal))
+}
+
+// ivalue ati_object_getattr_(ivalue i, char *attr_name);
+func AtiObjectGetattr_(val Civalue, attrName string) Civalue {
+	cattrName := C.CString(attrName)
+	defer C.free(unsafe.Pointer(cattrName))
+	return C.ati_object_getattr_(val, cattrName)
+}
+
+// char *ati_object_type_name(ivalue);
+func AtiObjectTypeName(val Civalue) string {
+	cstr := C.ati_object_type_name(val)
+	if cstr == nil {
+		return ""
+	}
+	defer C.free(unsafe.Pointer(cstr))
+
+	return C.GoString(cstr)
+}
+
+// void ati_object_attribute_names(ivalue, void *data, void (*f)(void *, char *));
+func AtiObjectAttributeNames(val Civalue, dataPtr unsafe.Pointer) {
+	C.ati_object_attribute_names(val, dataPtr, C.fn(C.callback_name_fn))
+}
+
+// void ati_tuple_field_names(ivalue, void *data, void (*f)(void *, char *));
+func AtiTupleFieldNames(val Civalue, dataPtr unsafe.Pointer) {
+	C.ati_tuple_field_names(val, dataPtr, C.fn(C.callback_name_fn))
+}
+
+// void atq_set_engine(int);
+func AtqSetEngine(engine int32) {
+	cengine := *(*C.int)(unsafe.Pointer(&engine))
+	C.atq_set_engine(cengine)
+}
+
+// int atq_engine();
+func AtqEngine() int32 {
+	cengine := C.atq_engine()
+	return *(*int32)(unsafe.Pointer(&cengine))
+}
+
+// ivalue atq_linear_prepack(tensor weight, tensor bias);
+func AtqLinearPrepack(weight Ctensor, bias Ctensor) Civalue {
+	return C.atq_linear_prepack(weight, bias)
+}
+
+// tensor atq_linear(tensor qinput, ivalue packed, double scale, int64_t zero_point);
+func AtqLinear(qinput Ctensor, packed Civalue, scale float64, zeroPoint int64) Ctensor {
+	cscale := *(*C.double)(unsafe.Pointer(&scale))
+	czeroPoint := *(*C.int64_t)(unsafe.Pointer(&zeroPoint))
+	return C.atq_linear(qinput, packed, cscale, czeroPoint)
+}
+
+// tensor atq_linear_dynamic(tensor input, ivalue packed, int reduce_range);
+func AtqLinearDynamic(input Ctensor, packed Civalue, reduceRange int32) Ctensor {
+	creduceRange := *(*C.int)(unsafe.Pointer(&reduceRange))
+	return C.atq_linear_dynamic(input, packed, creduceRange)
+}
+
+// ivalue atq_conv2d_prepack(tensor weight, tensor bias, int64_t *stride, int stride_len, int64_t *padding, int padding_len, int64_t *dilation, int dilation_len, int64_t groups);
+func AtqConv2dPrepack(weight Ctensor, bias Ctensor, strideData []int64, strideLen int, paddingData []int64, paddingLen int, dilationData []int64, dilationLen int, groups int64) Civalue {
+	cstrideDataPtr := (*C.int64_t)(unsafe.Pointer(&strideData[0]))
+	cstrideLen := *(*C.int)(unsafe.Pointer(&strideLen))
+	cpaddingDataPtr := (*C.int64_t)(unsafe.Pointer(&paddingData[0]))
+	cpaddingLen := *(*C.int)(unsafe.Pointer(&paddingLen))
+	cdilationDataPtr := (*C.int64_t)(unsafe.Pointer(&dilationData[0]))
+	cdilationLen := *(*C.int)(unsafe.Pointer(&dilationLen))
+	cgroups := *(*C.int64_t)(unsafe.Pointer(&groups))
+	return C.atq_conv2d_prepack(weight, bias, cstrideDataPtr, cstrideLen, cpaddingDataPtr, cpaddingLen, cdilationDataPtr, cdilationLen, cgroups)
+}
+
+// tensor atq_conv2d(tensor qinput, ivalue packed, double scale, int64_t zero_point);
+func AtqConv2d(qinput Ctensor, packed Civalue, scale float64, zeroPoint int64) Ctensor {
+	cscale := *(*C.double)(unsafe.Pointer(&scale))
+	czeroPoint := *(*C.int64_t)(unsafe.Pointer(&zeroPoint))
+	return C.atq_conv2d(qinput, packed, cscale, czeroPoint)
+}
+
 // module atm_load(char *);
 func AtmLoad(path string) Cmodule {
 	ptr := C.CString(path)
@@ -846,17 +984,124 @@ func AtmLoadStrOnDevice(val string, sz int, device int32) Cmodule {
 	return C.atm_load_str_on_device(ptr, csz, cdevice)
 }
 
+// module atm_load_with_extra_files(char *filename, int device, bool on_device, char **names, char **contents, size_t *sizes, int nnames);
+func AtmLoadWithExtraFiles(path string, device int32, onDevice bool, names []string) (Cmodule, []string) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	cdevice := *(*C.int)(unsafe.Pointer(&device))
+	conDevice := *(*C.bool)(unsafe.Pointer(&onDevice))
+
+	nnames := len(names)
+	if nnames == 0 {
+		return C.atm_load_with_extra_files(cpath, cdevice, conDevice, nil, nil, nil, 0), nil
+	}
+
+	cnames := make([]*C.char, nnames)
+	for i, name := range names {
+		cname := C.CString(name)
+		defer C.free(unsafe.Pointer(cname))
+		cnames[i] = cname
+	}
+	ccontents := make([]*C.char, nnames)
+	csizes := make([]C.size_t, nnames)
+	cnnames := *(*C.int)(unsafe.Pointer(&nnames))
+
+	m := C.atm_load_with_extra_files(cpath, cdevice, conDevice, &cnames[0], &ccontents[0], &csizes[0], cnnames)
+
+	contents := make([]string, nnames)
+	for i, ccontent := range ccontents {
+		if ccontent == nil {
+			continue
+		}
+		contents[i] = C.GoStringN(ccontent, C.int(csizes[i]))
+		C.free(unsafe.Pointer(ccontent))
+	}
+
+	return m, contents
+}
+
+// module atm_compile(char *src);
+func AtmCompile(src string) Cmodule {
+	csrc := C.CString(src)
+	defer C.free(unsafe.Pointer(csrc))
+	return C.atm_compile(csrc)
+}
+
 // void atm_save(module m, char *);
 func AtmSave(m Cmodule, path string) {
 	ptr := C.CString(path)
 	C.atm_save(m, ptr)
 }
 
+// void atm_save_with_extra_files(module m, char *filename, char **names, char **contents, size_t *sizes, int nnames);
+func AtmSaveWithExtraFiles(m Cmodule, path string, names []string, contents []string) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+
+	nnames := len(names)
+	if nnames == 0 {
+		C.atm_save_with_extra_files(m, cpath, nil, nil, nil, 0)
+		return
+	}
+
+	cnames := make([]*C.char, nnames)
+	ccontents := make([]*C.char, nnames)
+	csizes := make([]C.size_t, nnames)
+	for i := range names {
+		cname := C.CString(names[i])
+		defer C.free(unsafe.Pointer(cname))
+		cnames[i] = cname
+		ccontent := C.CString(contents[i])
+		defer C.free(unsafe.Pointer(ccontent))
+		ccontents[i] = ccontent
+		csizes[i] = C.size_t(len(contents[i]))
+	}
+	cnnames := *(*C.int)(unsafe.Pointer(&nnames))
+
+	C.atm_save_with_extra_files(m, cpath, &cnames[0], &ccontents[0], &csizes[0], cnnames)
+}
+
 // void atm_named_parameters(module, void *data, void (*f)(void *, char *, tensor));
 func AtmNamedParameters(m Cmodule, dataPtr unsafe.Pointer) {
 	C.atm_named_parameters(m, dataPtr, C.f(C.callback_fn))
 }
 
+// void atm_named_buffers(module, void *data, void (*f)(void *, char *, tensor));
+func AtmNamedBuffers(m Cmodule, dataPtr unsafe.Pointer) {
+	C.atm_named_buffers(m, dataPtr, C.f(C.callback_fn))
+}
+
+// void atm_named_modules(module, void *data, void (*f)(void *, char *, module));
+func AtmNamedModules(m Cmodule, dataPtr unsafe.Pointer) {
+	C.atm_named_modules(m, dataPtr, C.fm(C.callback_module_fn))
+}
+
+// void atm_method_names(module, void *data, void (*f)(void *, char *));
+func AtmMethodNames(m Cmodule, dataPtr unsafe.Pointer) {
+	C.atm_method_names(m, dataPtr, C.fn(C.callback_name_fn))
+}
+
+// module atm_get_submodule(module, char *name);
+func AtmGetSubmodule(m Cmodule, name string) Cmodule {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return C.atm_get_submodule(m, cname)
+}
+
+// ivalue atm_get_attribute(module, char *name);
+func AtmGetAttribute(m Cmodule, name string) Civalue {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return C.atm_get_attribute(m, cname)
+}
+
+// void atm_set_attribute(module, char *name, ivalue);
+func AtmSetAttribute(m Cmodule, name string, val Civalue) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	C.atm_set_attribute(m, cname, val)
+}
+
 // tensor atm_forward(module, tensor *tensors, int ntensors);
 func AtmForward(m Cmodule, tensors *Ctensor, ntensors int) Ctensor {
 	cntensors := *(*C.int)(unsafe.Pointer(&ntensors))
@@ -869,6 +1114,94 @@ func AtmForward_(m Cmodule, ivalues *Civalue, nivalues int) Civalue {
 	return C.atm_forward_(m, ivalues, cnivalues)
 }
 
+// cStrings copies Go strings to C memory. The returned func frees the C memory.
+func cStrings(strs []string) (**C.char, func()) {
+	if len(strs) == 0 {
+		return nil, func() {}
+	}
+
+	cstrs := make([]*C.char, len(strs))
+	for i, str := range strs {
+		cstr := C.CString(str)
+		cstrs[i] = cstr
+	}
+
+	return &cstrs[0], func() {
+		for _, cstr := range cstrs {
+			C.free(unsafe.Pointer(cstr))
+		}
+	}
+}
+
+// module atm_freeze(module, char **preserved_attrs, int npreserved_attrs, bool optimize_numerics);
+func AtmFreeze(m Cmodule, preservedAttrs []string, optimizeNumerics bool) Cmodule {
+	cattrs, free := cStrings(preservedAttrs)
+	defer free()
+	nattrs := len(preservedAttrs)
+	cnattrs := *(*C.int)(unsafe.Pointer(&nattrs))
+	coptimizeNumerics := *(*C.bool)(unsafe.Pointer(&optimizeNumerics))
+
+	return C.atm_freeze(m, cattrs, cnattrs, coptimizeNumerics)
+}
+
+// module atm_optimize_for_inference(module, char **other_methods, int nother_methods);
+func AtmOptimizeForInference(m Cmodule, otherMethods []string) Cmodule {
+	cmethods, free := cStrings(otherMethods)
+	defer free()
+	nmethods := len(otherMethods)
+	cnmethods := *(*C.int)(unsafe.Pointer(&nmethods))
+
+	return C.atm_optimize_for_inference(m, cmethods, cnmethods)
+}
+
+// char *atm_graph_string(module, char *method_name);
+func AtmGraphString(m Cmodule, methodName string) string {
+	cmethodName := C.CString(methodName)
+	defer C.free(unsafe.Pointer(cmethodName))
+	cstr := C.atm_graph_string(m, cmethodName)
+	if cstr == nil {
+		return ""
+	}
+	defer C.free(unsafe.Pointer(cstr))
+
+	return C.GoString(cstr)
+}
+
+// void at_set_graph_executor_optimize(bool);
+func AtSetGraphExecutorOptimize(b bool) {
+	cbool := *(*C.bool)(unsafe.Pointer(&b))
+	C.at_set_graph_executor_optimize(cbool)
+}
+
+// bool at_get_graph_executor_optimize();
+func AtGetGraphExecutorOptimize() bool {
+	retVal := C.at_get_graph_executor_optimize()
+	return *(*bool)(unsafe.Pointer(&retVal))
+}
+
+// void atm_set_tensor_expr_fuser_enabled(int);
+func AtmSetTensorExprFuserEnabled(b bool) {
+	var cenabled C.int = 0
+	if b {
+		cenabled = 1
+	}
+	C.atm_set_tensor_expr_fuser_enabled(cenabled)
+}
+
+// bool atm_get_tensor_expr_fuser_enabled();
+func AtmGetTensorExprFuserEnabled() bool {
+	retVal := C.atm_get_tensor_expr_fuser_enabled()
+	return *(*bool)(unsafe.Pointer(&retVal))
+}
+
+// ivalue atm_method_(module, char *method_name, ivalue *ivalues, int nivalues);
+func AtmMethod_(m Cmodule, methodName string, ivalues *Civalue, nivalues int) Civalue {
+	cmethodName := C.CString(methodName)
+	defer C.free(unsafe.Pointer(cmethodName))
+	cnivalues := *(*C.int)(unsafe.Pointer(&nivalues))
+	return C.atm_method_(m, cmethodName, ivalues, cnivalues)
+}
+
 // void atm_free(module);
 func AtmFree(m Cmodule) {
 	C.atm_free(m)
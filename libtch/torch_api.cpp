@@ -1158,6 +1158,41 @@ module atm_load_str_on_device(char *data, size_t sz, int device) {
   return nullptr;
 }
 
+module atm_load_with_extra_files(char *filename, int device, bool on_device,
+                                 char **names, char **contents, size_t *sizes, int nnames) {
+  PROTECT(
+    torch::jit::ExtraFilesMap extra_files;
+    for (int i = 0; i < nnames; ++i) {
+      contents[i] = nullptr;
+      sizes[i] = 0;
+      extra_files[names[i]] = "";
+    }
+    c10::optional<at::Device> d = c10::nullopt;
+    if (on_device)
+      d = device_of_int(device);
+    torch::jit::script::Module m = torch::jit::load(filename, d, extra_files);
+    for (int i = 0; i < nnames; ++i) {
+      const std::string &content = extra_files[names[i]];
+      sizes[i] = content.size();
+      contents[i] = (char *)malloc(content.size() + 1);
+      memcpy(contents[i], content.data(), content.size());
+      contents[i][content.size()] = '\0';
+    }
+    return new torch::jit::script::Module(m);
+  )
+  return nullptr;
+}
+
+module atm_compile(char *src) {
+  PROTECT(
+    torch::jit::script::Module m(c10::QualifiedName("__torch__.CompiledModule"));
+    m.register_attribute("training", c10::BoolType::get(), true);
+    m.define(src);
+    return new torch::jit::script::Module(m);
+  )
+  return nullptr;
+}
+
 tensor atm_forward(module m, tensor *tensors, int ntensors) {
   PROTECT(
     std::vector<torch::jit::IValue> inputs;
@@ -1243,6 +1278,15 @@ void atm_save(module m, char *filename) {
   )
 }
 
+void atm_save_with_extra_files(module m, char *filename, char **names, char **contents, size_t *sizes, int nnames) {
+  PROTECT(
+    torch::jit::ExtraFilesMap extra_files;
+    for (int i = 0; i < nnames; ++i)
+      extra_files[names[i]] = std::string(contents[i], sizes[i]);
+    m->save(filename, extra_files);
+  )
+}
+
 void atm_to(module m, int device, int dtype, bool non_blocking) {
   PROTECT(
     m->to(device_of_int(device), at::ScalarType(dtype), non_blocking);
@@ -1336,6 +1380,87 @@ void atm_named_parameters(module m, void *data, void (*f)(void *, char *, tensor
   )
 }
 
+void atm_named_buffers(module m, void *data, void (*f)(void *, char *, tensor)) {
+  PROTECT(
+    for (const auto &p : m->named_buffers()) {
+      auto v = p.value;
+      f(data, (char*)p.name.c_str(), new torch::Tensor(v));
+    }
+  )
+}
+
+void atm_named_modules(module m, void *data, void (*f)(void *, char *, module)) {
+  PROTECT(
+    for (const auto &p : m->named_modules()) {
+      // skip the module itself.
+      if (p.name.empty())
+        continue;
+      f(data, (char*)p.name.c_str(), new torch::jit::script::Module(p.value));
+    }
+  )
+}
+
+void atm_method_names(module m, void *data, void (*f)(void *, char *)) {
+  PROTECT(
+    for (const auto &method : m->get_methods()) {
+      f(data, (char*)method.name().c_str());
+    }
+  )
+}
+
+module atm_get_submodule(module m, char *name) {
+  PROTECT(
+    torch::jit::script::Module sub = *m;
+    std::stringstream ss(name);
+    std::string item;
+    while (std::getline(ss, item, '.'))
+      sub = sub.attr(item).toModule();
+    return new torch::jit::script::Module(sub);
+  )
+  return nullptr;
+}
+
+ivalue atm_get_attribute(module m, char *name) {
+  PROTECT(
+    return new torch::jit::IValue(m->attr(name));
+  )
+  return nullptr;
+}
+
+void atm_set_attribute(module m, char *name, ivalue v) {
+  PROTECT(
+    m->setattr(name, *v);
+  )
+}
+
+module atm_freeze(module m, char **preserved_attrs, int npreserved_attrs, bool optimize_numerics) {
+  PROTECT(
+    std::vector<std::string> attrs;
+    for (int i = 0; i < npreserved_attrs; ++i)
+      attrs.push_back(std::string(preserved_attrs[i]));
+    return new torch::jit::script::Module(torch::jit::freeze(*m, attrs, optimize_numerics));
+  )
+  return nullptr;
+}
+
+module atm_optimize_for_inference(module m, char **other_methods, int nother_methods) {
+  PROTECT(
+    std::vector<std::string> methods;
+    for (int i = 0; i < nother_methods; ++i)
+      methods.push_back(std::string(other_methods[i]));
+    return new torch::jit::script::Module(torch::jit::optimize_for_inference(*m, methods));
+  )
+  return nullptr;
+}
+
+char *atm_graph_string(module m, char *method_name) {
+  PROTECT(
+    auto str = m->get_method(method_name).graph()->toString();
+    return strdup(str.c_str());
+  )
+  return nullptr;
+}
+
 ivalue ati_tensor(tensor t) {
   PROTECT(
     return new torch::jit::IValue(*t);
@@ -1495,6 +1620,7 @@ int ati_tag(ivalue i) {
     else if (i->isList()) return 12;
     else if (i->isGenericDict()) return 13;
     else if (i->isObject()) return 14;
+    else if (i->isDevice()) return 15;
     throw std::invalid_argument(("unsupported tag " + i->tagKind()).c_str());
     return -1;
   )
@@ -1674,6 +1800,43 @@ ivalue ati_object_getattr_(ivalue i, char *attr_name) {
   return nullptr;
 }
 
+char *ati_object_type_name(ivalue i) {
+  PROTECT(
+    auto name = i->toObjectRef().type()->name()->qualifiedName();
+    return strdup(name.c_str());
+  )
+  return nullptr;
+}
+
+void ati_object_attribute_names(ivalue i, void *data, void (*f)(void *, char *)) {
+  PROTECT(
+    auto type = i->toObjectRef().type();
+    for (size_t k = 0; k < type->numAttributes(); ++k)
+      f(data, (char*)type->getAttributeName(k).c_str());
+  )
+}
+
+void ati_tuple_field_names(ivalue i, void *data, void (*f)(void *, char *)) {
+  PROTECT(
+    auto type = i->type()->expect<c10::TupleType>();
+    if (type->schema()) {
+      for (const auto &arg : type->schema()->arguments())
+        f(data, (char*)arg.name().c_str());
+    }
+  )
+}
+
+int ati_to_device(ivalue i) {
+  PROTECT(
+    auto device = i->toDevice();
+    if (device.is_cuda()) return device.index() < 0 ? 0 : device.index();
+    if (device.is_mps()) return -2;
+    if (device.is_vulkan()) return -3;
+    return -1;
+  )
+  return -1;
+}
+
 ivalue ati_clone(ivalue i) {
   PROTECT(
     return new torch::jit::IValue(*i);
@@ -1685,8 +1848,88 @@ void ati_free(ivalue i) {
   delete(i);
 }
 
+static torch::jit::Stack call_quantized_op(const char *name, const char *overload, torch::jit::Stack stack) {
+  auto op = c10::Dispatcher::singleton().findSchemaOrThrow(name, overload);
+  op.callBoxed(&stack);
+  return stack;
+}
+
+static c10::optional<at::Tensor> optional_tensor(tensor t) {
+  if (t == nullptr || !t->defined())
+    return c10::nullopt;
+  return *t;
+}
+
+void atq_set_engine(int e) {
+  PROTECT(
+    at::globalContext().setQEngine(static_cast<at::QEngine>(e));
+  )
+}
+
+int atq_engine() {
+  PROTECT(
+    return static_cast<int>(at::globalContext().qEngine());
+  )
+  return -1;
+}
+
+ivalue atq_linear_prepack(tensor weight, tensor bias) {
+  PROTECT(
+    auto outputs = call_quantized_op("quantized::linear_prepack", "", {*weight, optional_tensor(bias)});
+    return new torch::jit::IValue(outputs[0]);
+  )
+  return nullptr;
+}
+
+tensor atq_linear(tensor qinput, ivalue packed, double scale, int64_t zero_point) {
+  PROTECT(
+    auto outputs = call_quantized_op("quantized::linear", "", {*qinput, *packed, scale, zero_point});
+    return new torch::Tensor(outputs[0].toTensor());
+  )
+  return nullptr;
+}
+
+tensor atq_linear_dynamic(tensor input, ivalue packed, int reduce_range) {
+  PROTECT(
+    auto outputs = call_quantized_op("quantized::linear_dynamic", "", {*input, *packed, (bool)reduce_range});
+    return new torch::Tensor(outputs[0].toTensor());
+  )
+  return nullptr;
+}
+
+ivalue atq_conv2d_prepack(tensor weight, tensor bias,
+                          int64_t *stride, int stride_len,
+                          int64_t *padding, int padding_len,
+                          int64_t *dilation, int dilation_len,
+                          int64_t groups) {
+  PROTECT(
+    auto outputs = call_quantized_op("quantized::conv2d_prepack", "", {
+      *weight,
+      optional_tensor(bias),
+      std::vector<int64_t>(stride, stride + stride_len),
+      std::vector<int64_t>(padding, padding + padding_len),
+      std::vector<int64_t>(dilation, dilation + dilation_len),
+      groups
+    });
+    return new torch::jit::IValue(outputs[0]);
+  )
+  return nullptr;
+}
+
+tensor atq_conv2d(tensor qinput, ivalue packed, double scale, int64_t zero_point) {
+  PROTECT(
+    auto outputs = call_quantized_op("quantized::conv2d", "new", {*qinput, *packed, scale, zero_point});
+    return new torch::Tensor(outputs[0].toTensor());
+  )
+  return nullptr;
+}
+
 void at_set_graph_executor_optimize(bool o) {
   torch::jit::setGraphExecutorOptimize(o);
 }
 
+bool at_get_graph_executor_optimize() {
+  return torch::jit::getGraphExecutorOptimize();
+}
+
 #include "torch_api_generated.cpp.h"
\ No newline at end of file
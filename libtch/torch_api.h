@@ -202,6 +202,12 @@ module atm_load(char *);
 module atm_load_on_device(char *, int device);
 module atm_load_str(char *, size_t sz);
 module atm_load_str_on_device(char *, size_t sz, int device);
+// Contents of extra files are allocated with malloc and should be freed by the caller.
+// The saved device is kept if on_device is false.
+module atm_load_with_extra_files(char *filename, int device, bool on_device,
+                                 char **names, char **contents, size_t *sizes, int nnames);
+// Methods defined in src should take `self` as first argument.
+module atm_compile(char *src);
 tensor atm_forward(module, tensor *tensors, int ntensors);
 ivalue atm_forward_(module,
                     ivalue *ivalues,
@@ -223,11 +229,24 @@ void atm_train(module);
 void atm_free(module);
 void atm_to(module m, int device, int dtype, bool non_blocking);
 void atm_save(module m, char*);
+void atm_save_with_extra_files(module m, char *filename, char **names, char **contents, size_t *sizes, int nnames);
 int atm_get_profiling_mode();
 void atm_set_profiling_mode(int);
 void atm_fuser_cuda_set_enabled(bool);
 bool atm_fuser_cuda_is_enabled();
 void atm_named_parameters(module, void *data, void (*f)(void *, char *, tensor));
+void atm_named_buffers(module, void *data, void (*f)(void *, char *, tensor));
+// Submodules are returned as new modules and should be freed with atm_free.
+void atm_named_modules(module, void *data, void (*f)(void *, char *, module));
+void atm_method_names(module, void *data, void (*f)(void *, char *));
+module atm_get_submodule(module, char *name);
+ivalue atm_get_attribute(module, char *name);
+void atm_set_attribute(module, char *name, ivalue);
+// Freezing/optimizing returns a new module. The input module should be in eval mode.
+module atm_freeze(module, char **preserved_attrs, int npreserved_attrs, bool optimize_numerics);
+module atm_optimize_for_inference(module, char **other_methods, int nother_methods);
+// Returned string is allocated with malloc and should be freed by the caller.
+char *atm_graph_string(module, char *method_name);
 
 // This function has to be followed by a call to atm_end_tracing.
 module atm_create_for_tracing(char *modl_name, tensor *inputs, int ninputs);
@@ -271,12 +290,34 @@ int ati_tag(ivalue);
 
 ivalue ati_object_method_(ivalue i, char *method_name, ivalue *ivalues, int nivalues);
 ivalue ati_object_getattr_(ivalue i, char *attr_name);
+// Returned string is allocated with malloc and should be freed by the caller.
+char *ati_object_type_name(ivalue);
+void ati_object_attribute_names(ivalue, void *data, void (*f)(void *, char *));
+// Field names of a named tuple. Nothing is returned for a plain tuple.
+void ati_tuple_field_names(ivalue, void *data, void (*f)(void *, char *));
+int ati_to_device(ivalue);
 
 ivalue ati_clone(ivalue);
 void ati_free(ivalue);
 
+// Quantized operators (quantized::*) are called via the dispatcher.
+// Packed weights are returned as custom class ivalues and should be
+// freed with ati_free.
+void atq_set_engine(int);
+int atq_engine();
+ivalue atq_linear_prepack(tensor weight, tensor bias);
+tensor atq_linear(tensor qinput, ivalue packed, double scale, int64_t zero_point);
+tensor atq_linear_dynamic(tensor input, ivalue packed, int reduce_range);
+ivalue atq_conv2d_prepack(tensor weight, tensor bias,
+                          int64_t *stride, int stride_len,
+                          int64_t *padding, int padding_len,
+                          int64_t *dilation, int dilation_len,
+                          int64_t groups);
+tensor atq_conv2d(tensor qinput, ivalue packed, double scale, int64_t zero_point);
+
 /// Enables or disables the graph executor optimizer for the current thread.
 void at_set_graph_executor_optimize(bool);
+bool at_get_graph_executor_optimize();
 
 int atc_get_device();
 void atc_set_device(int device_index);
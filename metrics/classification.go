@@ -0,0 +1,370 @@
+package metrics
+
+// Classification metrics.
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/ts"
+)
+
+// ConfusionMatrix accumulates a multiclass confusion matrix.
+type ConfusionMatrix struct {
+	numClasses int
+	counts     []int64 // row-major [target, pred]
+}
+
+// NewConfusionMatrix creates a confusion matrix for numClasses classes.
+func NewConfusionMatrix(numClasses int) *ConfusionMatrix {
+	return &ConfusionMatrix{
+		numClasses: numClasses,
+		counts:     make([]int64, numClasses*numClasses),
+	}
+}
+
+// Update accumulates a batch. Preds can be class labels of shape [N] or scores/logits
+// of shape [N, C]. Targets are class labels of shape [N].
+func (cm *ConfusionMatrix) Update(preds, targets *ts.Tensor) error {
+	labels, err := predLabels(preds)
+	if err != nil {
+		return fmt.Errorf("ConfusionMatrix.Update() failed: %w", err)
+	}
+	if err := checkTargets(targets, len(labels)); err != nil {
+		return fmt.Errorf("ConfusionMatrix.Update() failed: %w", err)
+	}
+
+	n := int64(cm.numClasses)
+	for i, t := range targets.Int64Values() {
+		p := labels[i]
+		if t < 0 || t >= n || p < 0 || p >= n {
+			return fmt.Errorf("ConfusionMatrix.Update() failed: label out of range [0, %v): target %v, prediction %v", n, t, p)
+		}
+		cm.counts[t*n+p]++
+	}
+
+	return nil
+}
+
+// Matrix returns the confusion matrix. Rows are target classes and columns are predicted classes.
+func (cm *ConfusionMatrix) Matrix() [][]int64 {
+	m := make([][]int64, cm.numClasses)
+	for i := range m {
+		m[i] = append([]int64{}, cm.counts[i*cm.numClasses:(i+1)*cm.numClasses]...)
+	}
+	return m
+}
+
+// Tensor returns the confusion matrix as a tensor of shape [C, C].
+func (cm *ConfusionMatrix) Tensor() *ts.Tensor {
+	n := int64(cm.numClasses)
+	return ts.MustOfSlice(cm.counts).MustView([]int64{n, n}, true)
+}
+
+// Reset clears accumulated counts.
+func (cm *ConfusionMatrix) Reset() {
+	for i := range cm.counts {
+		cm.counts[i] = 0
+	}
+}
+
+// stats returns per-class true positives, false positives and false negatives.
+func (cm *ConfusionMatrix) stats() (tp, fp, fn []float64) {
+	n := cm.numClasses
+	tp, fp, fn = make([]float64, n), make([]float64, n), make([]float64, n)
+	for t := 0; t < n; t++ {
+		for p := 0; p < n; p++ {
+			c := float64(cm.counts[t*n+p])
+			if t == p {
+				tp[t] += c
+			} else {
+				fp[p] += c
+				fn[t] += c
+			}
+		}
+	}
+	return tp, fp, fn
+}
+
+// Average is a method of averaging per-class metrics.
+type Average int
+
+const (
+	// Macro averages per-class metrics with equal weights.
+	Macro Average = iota
+	// Micro computes metrics from true/false positives and false negatives summed over classes.
+	Micro
+	// Weighted averages per-class metrics weighted by class supports (number of targets).
+	Weighted
+)
+
+// ClassificationOpts are options for Precision, Recall and F1:
+//   - Average: averaging method. Default=Macro
+//   - Multilabel: preds and targets are of shape [N, L] with targets in {0, 1}. Default=false
+//   - Threshold: score threshold of a positive multilabel prediction. Default=0.5
+//
+// In Macro and Weighted averages, classes never seen in targets or predictions are ignored.
+type ClassificationOpts struct {
+	Average    Average
+	Multilabel bool
+	Threshold  float64
+}
+
+type ClassificationOpt func(*ClassificationOpts)
+
+func OptAverage(v Average) ClassificationOpt {
+	return func(o *ClassificationOpts) {
+		o.Average = v
+	}
+}
+
+// OptMultilabel sets multilabel mode with score threshold of positive predictions.
+func OptMultilabel(threshold float64) ClassificationOpt {
+	return func(o *ClassificationOpts) {
+		o.Multilabel = true
+		o.Threshold = threshold
+	}
+}
+
+func DefaultClassificationOpts() *ClassificationOpts {
+	return &ClassificationOpts{
+		Average:    Macro,
+		Multilabel: false,
+		Threshold:  0.5,
+	}
+}
+
+// statScores accumulates per-class true positives, false positives and false negatives.
+type statScores struct {
+	opts *ClassificationOpts
+	cm   *ConfusionMatrix // multiclass
+	tp   []float64        // multilabel
+	fp   []float64
+	fn   []float64
+}
+
+func newStatScores(numClasses int, opts []ClassificationOpt) *statScores {
+	o := DefaultClassificationOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	s := &statScores{opts: o}
+	if o.Multilabel {
+		s.tp = make([]float64, numClasses)
+		s.fp = make([]float64, numClasses)
+		s.fn = make([]float64, numClasses)
+	} else {
+		s.cm = NewConfusionMatrix(numClasses)
+	}
+	return s
+}
+
+func (s *statScores) update(preds, targets *ts.Tensor) error {
+	if !s.opts.Multilabel {
+		return s.cm.Update(preds, targets)
+	}
+
+	numLabels := int64(len(s.tp))
+	psize, tsize := preds.MustSize(), targets.MustSize()
+	if len(psize) != 2 || psize[1] != numLabels || len(tsize) != 2 || tsize[0] != psize[0] || tsize[1] != numLabels {
+		return fmt.Errorf("expected multilabel predictions and targets of shape [N, %v], got %v and %v", numLabels, psize, tsize)
+	}
+
+	p := preds.MustGe(ts.FloatScalar(s.opts.Threshold), false).MustTotype(gotch.Double, true)
+	t := targets.MustTotype(gotch.Double, false)
+	pt := p.MustMul(t, false)
+	tp, npred, ntarget := sumDim0(pt), sumDim0(p), sumDim0(t)
+	pt.MustDrop()
+	p.MustDrop()
+	t.MustDrop()
+
+	for i := range tp {
+		s.tp[i] += tp[i]
+		s.fp[i] += npred[i] - tp[i]
+		s.fn[i] += ntarget[i] - tp[i]
+	}
+	return nil
+}
+
+func (s *statScores) reset() {
+	if s.cm != nil {
+		s.cm.Reset()
+		return
+	}
+	for i := range s.tp {
+		s.tp[i], s.fp[i], s.fn[i] = 0, 0, 0
+	}
+}
+
+// reduce applies a metric function to accumulated scores and averages the results.
+func (s *statScores) reduce(score func(tp, fp, fn float64) float64) float64 {
+	tp, fp, fn := s.tp, s.fp, s.fn
+	if s.cm != nil {
+		tp, fp, fn = s.cm.stats()
+	}
+
+	if s.opts.Average == Micro {
+		var stp, sfp, sfn float64
+		for i := range tp {
+			stp += tp[i]
+			sfp += fp[i]
+			sfn += fn[i]
+		}
+		return score(stp, sfp, sfn)
+	}
+
+	var sum, weights float64
+	for i := range tp {
+		if tp[i]+fp[i]+fn[i] == 0 {
+			continue
+		}
+		w := 1.0
+		if s.opts.Average == Weighted {
+			w = tp[i] + fn[i]
+		}
+		sum += w * score(tp[i], fp[i], fn[i])
+		weights += w
+	}
+	if weights == 0 {
+		return math.NaN()
+	}
+	return sum / weights
+}
+
+func precisionOf(tp, fp, fn float64) float64 { return safeDiv(tp, tp+fp) }
+func recallOf(tp, fp, fn float64) float64    { return safeDiv(tp, tp+fn) }
+func f1Of(tp, fp, fn float64) float64        { return safeDiv(2*tp, 2*tp+fp+fn) }
+
+// Precision is the ratio of true positives to predicted positives.
+type Precision struct {
+	stats *statScores
+}
+
+// NewPrecision creates a precision metric of numClasses classes (or labels in multilabel mode).
+func NewPrecision(numClasses int, opts ...ClassificationOpt) *Precision {
+	return &Precision{newStatScores(numClasses, opts)}
+}
+
+// Update accumulates a batch. In multiclass mode, preds can be class labels of shape [N]
+// or scores/logits of shape [N, C] and targets are class labels of shape [N].
+func (m *Precision) Update(preds, targets *ts.Tensor) error {
+	if err := m.stats.update(preds, targets); err != nil {
+		return fmt.Errorf("Precision.Update() failed: %w", err)
+	}
+	return nil
+}
+
+// Compute implements Metric interface. It returns NaN if no class has been seen.
+func (m *Precision) Compute() float64 {
+	return m.stats.reduce(precisionOf)
+}
+
+// Reset implements Metric interface.
+func (m *Precision) Reset() {
+	m.stats.reset()
+}
+
+// Recall is the ratio of true positives to target positives.
+type Recall struct {
+	stats *statScores
+}
+
+// NewRecall creates a recall metric of numClasses classes (or labels in multilabel mode).
+func NewRecall(numClasses int, opts ...ClassificationOpt) *Recall {
+	return &Recall{newStatScores(numClasses, opts)}
+}
+
+// Update implements Metric interface. See Precision.Update for input shapes.
+func (m *Recall) Update(preds, targets *ts.Tensor) error {
+	if err := m.stats.update(preds, targets); err != nil {
+		return fmt.Errorf("Recall.Update() failed: %w", err)
+	}
+	return nil
+}
+
+// Compute implements Metric interface. It returns NaN if no class has been seen.
+func (m *Recall) Compute() float64 {
+	return m.stats.reduce(recallOf)
+}
+
+// Reset implements Metric interface.
+func (m *Recall) Reset() {
+	m.stats.reset()
+}
+
+// F1 is the harmonic mean of precision and recall.
+type F1 struct {
+	stats *statScores
+}
+
+// NewF1 creates a F1 metric of numClasses classes (or labels in multilabel mode).
+func NewF1(numClasses int, opts ...ClassificationOpt) *F1 {
+	return &F1{newStatScores(numClasses, opts)}
+}
+
+// Update implements Metric interface. See Precision.Update for input shapes.
+func (m *F1) Update(preds, targets *ts.Tensor) error {
+	if err := m.stats.update(preds, targets); err != nil {
+		return fmt.Errorf("F1.Update() failed: %w", err)
+	}
+	return nil
+}
+
+// Compute implements Metric interface. It returns NaN if no class has been seen.
+func (m *F1) Compute() float64 {
+	return m.stats.reduce(f1Of)
+}
+
+// Reset implements Metric interface.
+func (m *F1) Reset() {
+	m.stats.reset()
+}
+
+// TopKAccuracy is the ratio of samples whose target is among the k highest scored classes.
+type TopKAccuracy struct {
+	k       int64
+	correct int64
+	total   int64
+}
+
+// NewTopKAccuracy creates a top-k accuracy metric.
+func NewTopKAccuracy(k int) *TopKAccuracy {
+	return &TopKAccuracy{k: int64(k)}
+}
+
+// Update accumulates a batch of scores/logits of shape [N, C] and class labels of shape [N].
+func (m *TopKAccuracy) Update(preds, targets *ts.Tensor) error {
+	size := preds.MustSize()
+	if len(size) != 2 || size[1] < m.k {
+		return fmt.Errorf("TopKAccuracy.Update() failed: expected predictions of shape [N, C] with C >= %v, got %v", m.k, size)
+	}
+	if err := checkTargets(targets, int(size[0])); err != nil {
+		return fmt.Errorf("TopKAccuracy.Update() failed: %w", err)
+	}
+
+	values, indices := preds.MustTopk(m.k, 1, true, false, false)
+	values.MustDrop()
+	t := targets.MustUnsqueeze(1, false)
+	hits := indices.MustEqTensor(t, true).MustSum(gotch.Int64, true)
+	t.MustDrop()
+
+	// Indices are unique per row so number of hits is number of correct samples.
+	m.correct += hits.Int64Values(true)[0]
+	m.total += size[0]
+	return nil
+}
+
+// Compute implements Metric interface. It returns NaN if no sample has been seen.
+func (m *TopKAccuracy) Compute() float64 {
+	if m.total == 0 {
+		return math.NaN()
+	}
+	return float64(m.correct) / float64(m.total)
+}
+
+// Reset implements Metric interface.
+func (m *TopKAccuracy) Reset() {
+	m.correct, m.total = 0, 0
+}
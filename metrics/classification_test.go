@@ -0,0 +1,124 @@
+package metrics_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch/metrics"
+	"github.com/sugarme/gotch/ts"
+)
+
+func assertClose(t *testing.T, name string, want, got float64) {
+	t.Helper()
+	if math.Abs(want-got) > 1e-4 {
+		t.Errorf("%v: want %v, got %v\n", name, want, got)
+	}
+}
+
+// Reference values are from scikit-learn.
+func TestMulticlassPrecisionRecallF1(t *testing.T) {
+	targets := ts.MustOfSlice([]int64{0, 1, 2, 0, 1, 2})
+	preds := ts.MustOfSlice([]int64{0, 2, 1, 0, 0, 1})
+
+	tests := []struct {
+		name   string
+		metric metrics.Metric
+		want   float64
+	}{
+		{"precision macro", metrics.NewPrecision(3), 0.2222},
+		{"precision micro", metrics.NewPrecision(3, metrics.OptAverage(metrics.Micro)), 0.3333},
+		{"precision weighted", metrics.NewPrecision(3, metrics.OptAverage(metrics.Weighted)), 0.2222},
+		{"recall macro", metrics.NewRecall(3), 0.3333},
+		{"f1 macro", metrics.NewF1(3), 0.2667},
+		{"f1 micro", metrics.NewF1(3, metrics.OptAverage(metrics.Micro)), 0.3333},
+		{"f1 weighted", metrics.NewF1(3, metrics.OptAverage(metrics.Weighted)), 0.2667},
+	}
+
+	for _, tt := range tests {
+		// Accumulate in 2 batches.
+		for _, idx := range []*ts.Narrow{ts.NewNarrow(0, 4), ts.NewNarrow(4, 6)} {
+			p, y := preds.Idx(idx), targets.Idx(idx)
+			if err := tt.metric.Update(p, y); err != nil {
+				t.Fatal(err)
+			}
+		}
+		assertClose(t, tt.name, tt.want, tt.metric.Compute())
+
+		tt.metric.Reset()
+		if v := tt.metric.Compute(); !math.IsNaN(v) {
+			t.Errorf("%v: want NaN after Reset, got %v\n", tt.name, v)
+		}
+	}
+}
+
+func TestMultilabelPrecisionRecallF1(t *testing.T) {
+	targets := ts.MustOfSlice([]float32{1, 0, 1, 0, 1, 0}).MustView([]int64{2, 3}, true)
+	scores := ts.MustOfSlice([]float32{0.9, 0.2, 0.4, 0.1, 0.7, 0.6}).MustView([]int64{2, 3}, true)
+
+	p := metrics.NewPrecision(3, metrics.OptMultilabel(0.5))
+	r := metrics.NewRecall(3, metrics.OptMultilabel(0.5), metrics.OptAverage(metrics.Micro))
+	f := metrics.NewF1(3, metrics.OptMultilabel(0.5))
+	for _, m := range []metrics.Metric{p, r, f} {
+		if err := m.Update(scores, targets); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertClose(t, "precision macro", 0.6667, p.Compute())
+	assertClose(t, "recall micro", 0.6667, r.Compute())
+	assertClose(t, "f1 macro", 0.6667, f.Compute())
+
+	if err := p.Update(scores, ts.MustOfSlice([]int64{0, 1})); err == nil {
+		t.Errorf("want error for multiclass targets in multilabel mode\n")
+	}
+}
+
+func TestConfusionMatrix(t *testing.T) {
+	cm := metrics.NewConfusionMatrix(3)
+	logits := ts.MustOfSlice([]float32{
+		0.9, 0.0, 0.1,
+		0.2, 0.1, 0.7,
+		0.1, 0.8, 0.1,
+		0.6, 0.3, 0.1,
+		0.5, 0.4, 0.1,
+		0.2, 0.7, 0.1,
+	}).MustView([]int64{6, 3}, true)
+	targets := ts.MustOfSlice([]int64{0, 1, 2, 0, 1, 2})
+	if err := cm.Update(logits, targets); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]int64{{2, 0, 0}, {1, 0, 1}, {0, 2, 0}}
+	if got := cm.Matrix(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v\n", want, got)
+	}
+	if got := cm.Tensor().Int64Values(); !reflect.DeepEqual([]int64{2, 0, 0, 1, 0, 1, 0, 2, 0}, got) {
+		t.Errorf("unexpected confusion matrix tensor %v\n", got)
+	}
+
+	if err := cm.Update(ts.MustOfSlice([]int64{3}), ts.MustOfSlice([]int64{0})); err == nil {
+		t.Errorf("want error for out of range label\n")
+	}
+}
+
+func TestTopKAccuracy(t *testing.T) {
+	preds := ts.MustOfSlice([]float32{
+		0.1, 0.5, 0.4,
+		0.3, 0.2, 0.5,
+		0.6, 0.3, 0.1,
+	}).MustView([]int64{3, 3}, true)
+	targets := ts.MustOfSlice([]int64{2, 1, 0})
+
+	top1, top2 := metrics.NewTopKAccuracy(1), metrics.NewTopKAccuracy(2)
+	for _, m := range []metrics.Metric{top1, top2} {
+		if err := m.Update(preds, targets); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertClose(t, "top-1", 1.0/3, top1.Compute())
+	assertClose(t, "top-2", 2.0/3, top2.Compute())
+
+	if err := metrics.NewTopKAccuracy(5).Update(preds, targets); err == nil {
+		t.Errorf("want error for k greater than number of classes\n")
+	}
+}
@@ -0,0 +1,218 @@
+package metrics
+
+// Area under ROC and precision-recall curves.
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/ts"
+)
+
+// scoreStore keeps scores and binary targets of every column (class or label).
+type scoreStore struct {
+	scores  [][]float64
+	targets [][]float64
+}
+
+// update stores a batch. Supported shapes are:
+//   - binary: preds [N] and targets [N] in {0, 1}
+//   - multiclass (one-vs-rest): preds [N, C] and targets class labels [N]
+//   - multilabel: preds [N, L] and targets [N, L] in {0, 1}
+func (s *scoreStore) update(preds, targets *ts.Tensor) error {
+	psize, tsize := preds.MustSize(), targets.MustSize()
+	if len(psize) == 0 || len(psize) > 2 || len(tsize) == 0 || tsize[0] != psize[0] {
+		return fmt.Errorf("unsupported predictions and targets of shape %v and %v", psize, tsize)
+	}
+	n := int(psize[0])
+	cols := 1
+	if len(psize) == 2 {
+		cols = int(psize[1])
+	}
+	if s.scores == nil {
+		s.scores = make([][]float64, cols)
+		s.targets = make([][]float64, cols)
+	}
+	if len(s.scores) != cols {
+		return fmt.Errorf("expected predictions with %v columns, got shape %v", len(s.scores), psize)
+	}
+
+	scores := preds.MustTotype(gotch.Double, false).Float64Values(true)
+	var binTargets []float64
+	switch {
+	case len(psize) == 1 && len(tsize) == 1, len(psize) == 2 && len(tsize) == 2 && tsize[1] == psize[1]:
+		binTargets = targets.Float64Values()
+	case len(psize) == 2 && len(tsize) == 1:
+		binTargets = make([]float64, n*cols)
+		for i, t := range targets.Int64Values() {
+			if t < 0 || t >= int64(cols) {
+				return fmt.Errorf("target label %v out of range [0, %v)", t, cols)
+			}
+			binTargets[i*cols+int(t)] = 1
+		}
+	default:
+		return fmt.Errorf("unsupported predictions and targets of shape %v and %v", psize, tsize)
+	}
+
+	for i := 0; i < n; i++ {
+		for c := 0; c < cols; c++ {
+			s.scores[c] = append(s.scores[c], scores[i*cols+c])
+			s.targets[c] = append(s.targets[c], binTargets[i*cols+c])
+		}
+	}
+	return nil
+}
+
+func (s *scoreStore) reset() {
+	s.scores, s.targets = nil, nil
+}
+
+// macro averages a binary curve metric over columns. Columns for which the metric is
+// undefined (NaN) are ignored.
+func (s *scoreStore) macro(fn func(scores, targets []float64) float64) float64 {
+	var sum float64
+	var n int
+	for c := range s.scores {
+		v := fn(s.scores[c], s.targets[c])
+		if math.IsNaN(v) {
+			continue
+		}
+		sum += v
+		n++
+	}
+	if n == 0 {
+		return math.NaN()
+	}
+	return sum / float64(n)
+}
+
+// curvePoint is cumulative true and false positives at a score threshold.
+type curvePoint struct {
+	tp, fp float64
+}
+
+// curve returns cumulative true/false positives at every distinct score threshold in
+// descending order and total number of positives and negatives.
+func curve(scores, targets []float64) (points []curvePoint, pos, neg float64) {
+	idx := make([]int, len(scores))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return scores[idx[i]] > scores[idx[j]]
+	})
+
+	var tp, fp float64
+	for i, k := range idx {
+		if targets[k] > 0 {
+			tp++
+		} else {
+			fp++
+		}
+		if i == len(idx)-1 || scores[idx[i+1]] != scores[k] {
+			points = append(points, curvePoint{tp, fp})
+		}
+	}
+	return points, tp, fp
+}
+
+// binaryROCAUC returns area under ROC curve using trapezoidal rule. Ties are handled
+// by grouping equal scores. It returns NaN if targets are all positive or all negative.
+func binaryROCAUC(scores, targets []float64) float64 {
+	points, pos, neg := curve(scores, targets)
+	if pos == 0 || neg == 0 {
+		return math.NaN()
+	}
+
+	var area float64
+	var prev curvePoint
+	for _, p := range points {
+		area += (p.fp - prev.fp) * (p.tp + prev.tp) / 2
+		prev = p
+	}
+	return area / (pos * neg)
+}
+
+// binaryAveragePrecision returns average precision `sum_n (R_n - R_{n-1}) * P_n`.
+// It returns NaN if there are no positive targets.
+func binaryAveragePrecision(scores, targets []float64) float64 {
+	points, pos, _ := curve(scores, targets)
+	if pos == 0 {
+		return math.NaN()
+	}
+
+	var ap, prevRecall float64
+	for _, p := range points {
+		recall := p.tp / pos
+		ap += (recall - prevRecall) * p.tp / (p.tp + p.fp)
+		prevRecall = recall
+	}
+	return ap
+}
+
+// ROCAUC is the area under receiver operating characteristic curve.
+//
+// Multiclass and multilabel values are macro averages of one-vs-rest binary values
+// over classes having both positive and negative targets.
+type ROCAUC struct {
+	store scoreStore
+}
+
+// NewROCAUC creates a ROC-AUC metric.
+func NewROCAUC() *ROCAUC {
+	return &ROCAUC{}
+}
+
+// Update accumulates a batch. Supported shapes are binary (preds [N], targets [N] in {0, 1}),
+// multiclass (preds [N, C], target labels [N]) and multilabel (preds [N, L], targets [N, L]).
+// Preds are scores such as probabilities or logits.
+func (m *ROCAUC) Update(preds, targets *ts.Tensor) error {
+	if err := m.store.update(preds, targets); err != nil {
+		return fmt.Errorf("ROCAUC.Update() failed: %w", err)
+	}
+	return nil
+}
+
+// Compute implements Metric interface. It returns NaN if the value is undefined.
+func (m *ROCAUC) Compute() float64 {
+	return m.store.macro(binaryROCAUC)
+}
+
+// Reset implements Metric interface.
+func (m *ROCAUC) Reset() {
+	m.store.reset()
+}
+
+// PRAUC is the area under precision-recall curve computed as average precision,
+// i.e. the mean of precisions at each threshold weighted by the increase in recall.
+//
+// Multiclass and multilabel values are macro averages of one-vs-rest binary values
+// over classes having positive targets.
+type PRAUC struct {
+	store scoreStore
+}
+
+// NewPRAUC creates a PR-AUC (average precision) metric.
+func NewPRAUC() *PRAUC {
+	return &PRAUC{}
+}
+
+// Update implements Metric interface. See ROCAUC.Update for input shapes.
+func (m *PRAUC) Update(preds, targets *ts.Tensor) error {
+	if err := m.store.update(preds, targets); err != nil {
+		return fmt.Errorf("PRAUC.Update() failed: %w", err)
+	}
+	return nil
+}
+
+// Compute implements Metric interface. It returns NaN if the value is undefined.
+func (m *PRAUC) Compute() float64 {
+	return m.store.macro(binaryAveragePrecision)
+}
+
+// Reset implements Metric interface.
+func (m *PRAUC) Reset() {
+	m.store.reset()
+}
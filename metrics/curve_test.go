@@ -0,0 +1,62 @@
+package metrics_test
+
+import (
+	"testing"
+
+	"github.com/sugarme/gotch/metrics"
+	"github.com/sugarme/gotch/ts"
+)
+
+// Reference values are from scikit-learn `roc_auc_score` and `average_precision_score`.
+func TestROCAUCAndPRAUC_Binary(t *testing.T) {
+	scores := ts.MustOfSlice([]float32{0.1, 0.4, 0.35, 0.8})
+	targets := ts.MustOfSlice([]int64{0, 0, 1, 1})
+
+	roc, pr := metrics.NewROCAUC(), metrics.NewPRAUC()
+	for _, m := range []metrics.Metric{roc, pr} {
+		if err := m.Update(scores, targets); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertClose(t, "roc-auc", 0.75, roc.Compute())
+	assertClose(t, "pr-auc", 0.8333, pr.Compute())
+
+	// Tied scores.
+	roc.Reset()
+	roc.Update(ts.MustOfSlice([]float32{0.5, 0.5, 0.5, 0.9}), targets)
+	assertClose(t, "roc-auc ties", 0.75, roc.Compute())
+}
+
+func TestROCAUC_Multiclass(t *testing.T) {
+	probs := ts.MustOfSlice([]float32{
+		0.7, 0.2, 0.1,
+		0.2, 0.5, 0.3,
+		0.1, 0.3, 0.6,
+		0.4, 0.4, 0.2,
+		0.3, 0.3, 0.4,
+		0.2, 0.6, 0.2,
+	}).MustView([]int64{6, 3}, true)
+	targets := ts.MustOfSlice([]int64{0, 1, 2, 0, 1, 2})
+
+	roc := metrics.NewROCAUC()
+	if err := roc.Update(probs, targets); err != nil {
+		t.Fatal(err)
+	}
+	// roc_auc_score(y, probs, multi_class="ovr", average="macro")
+	assertClose(t, "roc-auc ovr", 0.75, roc.Compute())
+
+	// Multilabel with the equivalent one-hot targets gives the same value.
+	onehot := ts.MustOfSlice([]float32{
+		1, 0, 0,
+		0, 1, 0,
+		0, 0, 1,
+		1, 0, 0,
+		0, 1, 0,
+		0, 0, 1,
+	}).MustView([]int64{6, 3}, true)
+	roc.Reset()
+	if err := roc.Update(probs, onehot); err != nil {
+		t.Fatal(err)
+	}
+	assertClose(t, "roc-auc multilabel", 0.75, roc.Compute())
+}
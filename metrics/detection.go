@@ -0,0 +1,198 @@
+package metrics
+
+// Object detection mean average precision.
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/sugarme/gotch/ts"
+)
+
+// Detections are boxes of an image:
+//   - Boxes: [N, 4] boxes in (x1, y1, x2, y2) format
+//   - Scores: [N] confidence scores. Only used for predictions.
+//   - Labels: [N] class labels
+type Detections struct {
+	Boxes  *ts.Tensor
+	Scores *ts.Tensor
+	Labels *ts.Tensor
+}
+
+type box [4]float64
+
+func (b box) area() float64 {
+	return math.Max(b[2]-b[0], 0) * math.Max(b[3]-b[1], 0)
+}
+
+func iou(a, b box) float64 {
+	inter := box{math.Max(a[0], b[0]), math.Max(a[1], b[1]), math.Min(a[2], b[2]), math.Min(a[3], b[3])}.area()
+	union := a.area() + b.area() - inter
+	return safeDiv(inter, union)
+}
+
+type scoredBox struct {
+	image int
+	score float64
+	box   box
+}
+
+// MeanAP is the mean over classes of average precision of detections at an IoU threshold
+// (e.g. mAP@0.5 as in Pascal VOC).
+//
+// Predictions are matched greedily in descending score order to the unmatched target box
+// of the same class and image with the highest IoU. AP is the area under the precision-recall
+// curve with precision interpolated as the maximum precision at any higher recall. Classes
+// without target boxes are ignored.
+type MeanAP struct {
+	iouThreshold float64
+	preds        map[int64][]scoredBox
+	targets      map[int64]map[int][]box // class -> image -> boxes
+	numImages    int
+}
+
+// NewMeanAP creates a mAP metric at IoU threshold.
+func NewMeanAP(iouThreshold float64) *MeanAP {
+	m := &MeanAP{iouThreshold: iouThreshold}
+	m.Reset()
+	return m
+}
+
+func detectionsOf(d Detections, withScores bool) (boxes []box, scores []float64, labels []int64, err error) {
+	size := d.Boxes.MustSize()
+	if len(size) != 2 || size[1] != 4 {
+		return nil, nil, nil, fmt.Errorf("expected boxes of shape [N, 4], got %v", size)
+	}
+	n := int(size[0])
+
+	labels = d.Labels.Int64Values()
+	if len(labels) != n {
+		return nil, nil, nil, fmt.Errorf("expected %v labels, got %v", n, len(labels))
+	}
+	if withScores {
+		if d.Scores == nil {
+			return nil, nil, nil, fmt.Errorf("missing prediction scores")
+		}
+		scores = d.Scores.Float64Values()
+		if len(scores) != n {
+			return nil, nil, nil, fmt.Errorf("expected %v scores, got %v", n, len(scores))
+		}
+	}
+
+	values := d.Boxes.Float64Values()
+	boxes = make([]box, n)
+	for i := range boxes {
+		copy(boxes[i][:], values[i*4:(i+1)*4])
+	}
+	return boxes, scores, labels, nil
+}
+
+// Update accumulates detections of a batch of images. Preds and targets are per image.
+func (m *MeanAP) Update(preds, targets []Detections) error {
+	if len(preds) != len(targets) {
+		return fmt.Errorf("MeanAP.Update() failed: number of predictions (%v) and targets (%v) mismatched", len(preds), len(targets))
+	}
+
+	for i := range preds {
+		image := m.numImages + i
+
+		boxes, _, labels, err := detectionsOf(targets[i], false)
+		if err != nil {
+			return fmt.Errorf("MeanAP.Update() failed: image %v targets: %w", i, err)
+		}
+		for j, label := range labels {
+			if m.targets[label] == nil {
+				m.targets[label] = make(map[int][]box)
+			}
+			m.targets[label][image] = append(m.targets[label][image], boxes[j])
+		}
+
+		boxes, scores, labels, err := detectionsOf(preds[i], true)
+		if err != nil {
+			return fmt.Errorf("MeanAP.Update() failed: image %v predictions: %w", i, err)
+		}
+		for j, label := range labels {
+			m.preds[label] = append(m.preds[label], scoredBox{image, scores[j], boxes[j]})
+		}
+	}
+	m.numImages += len(preds)
+
+	return nil
+}
+
+// classAP returns average precision of a class.
+func (m *MeanAP) classAP(label int64) float64 {
+	targets := m.targets[label]
+	var numTargets int
+	matched := make(map[int][]bool, len(targets))
+	for image, boxes := range targets {
+		numTargets += len(boxes)
+		matched[image] = make([]bool, len(boxes))
+	}
+
+	preds := append([]scoredBox{}, m.preds[label]...)
+	sort.SliceStable(preds, func(i, j int) bool {
+		return preds[i].score > preds[j].score
+	})
+
+	precisions := make([]float64, len(preds))
+	recalls := make([]float64, len(preds))
+	var tp float64
+	for i, p := range preds {
+		best, bestIoU := -1, m.iouThreshold
+		for j, t := range targets[p.image] {
+			if v := iou(p.box, t); !matched[p.image][j] && v >= bestIoU {
+				best, bestIoU = j, v
+			}
+		}
+		if best >= 0 {
+			matched[p.image][best] = true
+			tp++
+		}
+		precisions[i] = tp / float64(i+1)
+		recalls[i] = tp / float64(numTargets)
+	}
+
+	// Interpolate precisions as maximum precision at higher recalls.
+	for i := len(precisions) - 2; i >= 0; i-- {
+		precisions[i] = math.Max(precisions[i], precisions[i+1])
+	}
+
+	var ap, prevRecall float64
+	for i := range preds {
+		ap += (recalls[i] - prevRecall) * precisions[i]
+		prevRecall = recalls[i]
+	}
+	return ap
+}
+
+// ClassAPs returns average precision of every class having target boxes.
+func (m *MeanAP) ClassAPs() map[int64]float64 {
+	aps := make(map[int64]float64, len(m.targets))
+	for label := range m.targets {
+		aps[label] = m.classAP(label)
+	}
+	return aps
+}
+
+// Compute returns mean average precision. It returns NaN if there are no target boxes.
+func (m *MeanAP) Compute() float64 {
+	aps := m.ClassAPs()
+	if len(aps) == 0 {
+		return math.NaN()
+	}
+
+	var sum float64
+	for _, ap := range aps {
+		sum += ap
+	}
+	return sum / float64(len(aps))
+}
+
+// Reset clears accumulated detections.
+func (m *MeanAP) Reset() {
+	m.preds = make(map[int64][]scoredBox)
+	m.targets = make(map[int64]map[int][]box)
+	m.numImages = 0
+}
@@ -0,0 +1,60 @@
+package metrics_test
+
+import (
+	"testing"
+
+	"github.com/sugarme/gotch/metrics"
+	"github.com/sugarme/gotch/ts"
+)
+
+func boxes(values ...float32) *ts.Tensor {
+	return ts.MustOfSlice(values).MustView([]int64{int64(len(values) / 4), 4}, true)
+}
+
+func TestMeanAP(t *testing.T) {
+	targets := []metrics.Detections{
+		{
+			Boxes:  boxes(0, 0, 10, 10, 20, 20, 30, 30),
+			Labels: ts.MustOfSlice([]int64{0, 0}),
+		},
+		{
+			Boxes:  boxes(0, 0, 10, 10),
+			Labels: ts.MustOfSlice([]int64{1}),
+		},
+	}
+	preds := []metrics.Detections{
+		{
+			// TP, FP (no overlap), TP, FP (duplicate of the first box).
+			Boxes:  boxes(1, 1, 10, 10, 50, 50, 60, 60, 20, 20, 30, 30, 0, 0, 10, 10),
+			Scores: ts.MustOfSlice([]float32{0.9, 0.8, 0.7, 0.6}),
+			Labels: ts.MustOfSlice([]int64{0, 0, 0, 0}),
+		},
+		{
+			// Wrong class then a box with IoU 0.5.
+			Boxes:  boxes(0, 0, 10, 10, 0, 0, 10, 5),
+			Scores: ts.MustOfSlice([]float32{0.9, 0.8}),
+			Labels: ts.MustOfSlice([]int64{0, 1}),
+		},
+	}
+
+	m := metrics.NewMeanAP(0.5)
+	if err := m.Update(preds, targets); err != nil {
+		t.Fatal(err)
+	}
+
+	// Class 0 precision-recall: (1, 0.5), (0.5, 0.5), (0.33, 0.5), (0.5, 1), (0.4, 1).
+	// AP = 0.5 * 1 + 0.5 * 0.5
+	aps := m.ClassAPs()
+	assertClose(t, "class 0 AP", 0.75, aps[0])
+	assertClose(t, "class 1 AP", 1.0, aps[1])
+	assertClose(t, "mAP", 0.875, m.Compute())
+
+	// A stricter threshold rejects the IoU 0.5 and 0.81 boxes.
+	m = metrics.NewMeanAP(0.9)
+	m.Update(preds, targets)
+	assertClose(t, "class 1 AP@0.9", 0, m.ClassAPs()[1])
+
+	if err := m.Update(preds, targets[:1]); err == nil {
+		t.Errorf("want error for mismatched number of images\n")
+	}
+}
@@ -0,0 +1,63 @@
+// Package metrics provides stateful evaluation metrics accumulated over batches.
+//
+// Metrics are updated batch by batch with Update, computed over all seen samples
+// with Compute and cleared with Reset, so that an epoch metric does not depend on
+// how samples are split into batches.
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/ts"
+)
+
+// Metric is a scalar metric accumulating statistics over batches.
+type Metric interface {
+	// Update accumulates statistics of a batch of predictions and targets.
+	Update(preds, targets *ts.Tensor) error
+	// Compute returns metric value over all samples seen since the last Reset.
+	Compute() float64
+	// Reset clears accumulated statistics.
+	Reset()
+}
+
+// predLabels returns class labels of predictions. Preds can be class labels of shape [N]
+// or scores/logits of shape [N, C].
+func predLabels(preds *ts.Tensor) ([]int64, error) {
+	switch preds.Dim() {
+	case 1:
+		return preds.Int64Values(), nil
+	case 2:
+		labels := preds.MustArgmax([]int64{1}, false, false)
+		return labels.Int64Values(true), nil
+	default:
+		return nil, fmt.Errorf("expected predictions of shape [N] or [N, C], got %v", preds.MustSize())
+	}
+}
+
+// checkTargets checks that targets are class labels of shape [n].
+func checkTargets(targets *ts.Tensor, n int) error {
+	size := targets.MustSize()
+	if len(size) != 1 || size[0] != int64(n) {
+		return fmt.Errorf("expected targets of shape [%v], got %v", n, size)
+	}
+	return nil
+}
+
+// sumDim0 returns values of a tensor summed along dimension 0.
+func sumDim0(x *ts.Tensor) []float64 {
+	return x.MustSumDimIntlist([]int64{0}, false, gotch.Double, false).Float64Values(true)
+}
+
+// sumOf returns sum of all values of a tensor.
+func sumOf(x *ts.Tensor) float64 {
+	return x.MustSum(gotch.Double, false).Float64Values(true)[0]
+}
+
+func safeDiv(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return a / b
+}
@@ -0,0 +1,151 @@
+package metrics
+
+// Regression metrics.
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/ts"
+)
+
+// regressionSums accumulates sums of errors and targets over all elements.
+type regressionSums struct {
+	absErr   float64
+	sqErr    float64
+	target   float64
+	sqTarget float64
+	numel    float64
+}
+
+func (s *regressionSums) update(preds, targets *ts.Tensor) error {
+	psize, tsize := preds.MustSize(), targets.MustSize()
+	if !reflect.DeepEqual(psize, tsize) {
+		return fmt.Errorf("predictions and targets of shape %v and %v mismatched", psize, tsize)
+	}
+
+	t := targets.MustTotype(gotch.Double, false)
+	diff := preds.MustTotype(gotch.Double, false).MustSub(t, true)
+	absErr := diff.MustAbs(false)
+	sqErr := diff.MustSquare(true)
+	sqTarget := t.MustSquare(false)
+
+	s.absErr += sumOf(absErr)
+	s.sqErr += sumOf(sqErr)
+	s.target += sumOf(t)
+	s.sqTarget += sumOf(sqTarget)
+	s.numel += float64(t.Numel())
+
+	absErr.MustDrop()
+	sqErr.MustDrop()
+	sqTarget.MustDrop()
+	t.MustDrop()
+
+	return nil
+}
+
+func (s *regressionSums) reset() {
+	*s = regressionSums{}
+}
+
+// MAE is the mean absolute error.
+type MAE struct {
+	sums regressionSums
+}
+
+// NewMAE creates a mean absolute error metric.
+func NewMAE() *MAE {
+	return &MAE{}
+}
+
+// Update accumulates a batch of predictions and targets of the same shape.
+func (m *MAE) Update(preds, targets *ts.Tensor) error {
+	if err := m.sums.update(preds, targets); err != nil {
+		return fmt.Errorf("MAE.Update() failed: %w", err)
+	}
+	return nil
+}
+
+// Compute implements Metric interface. It returns NaN if no sample has been seen.
+func (m *MAE) Compute() float64 {
+	if m.sums.numel == 0 {
+		return math.NaN()
+	}
+	return m.sums.absErr / m.sums.numel
+}
+
+// Reset implements Metric interface.
+func (m *MAE) Reset() {
+	m.sums.reset()
+}
+
+// RMSE is the root mean squared error.
+type RMSE struct {
+	sums regressionSums
+}
+
+// NewRMSE creates a root mean squared error metric.
+func NewRMSE() *RMSE {
+	return &RMSE{}
+}
+
+// Update accumulates a batch of predictions and targets of the same shape.
+func (m *RMSE) Update(preds, targets *ts.Tensor) error {
+	if err := m.sums.update(preds, targets); err != nil {
+		return fmt.Errorf("RMSE.Update() failed: %w", err)
+	}
+	return nil
+}
+
+// Compute implements Metric interface. It returns NaN if no sample has been seen.
+func (m *RMSE) Compute() float64 {
+	if m.sums.numel == 0 {
+		return math.NaN()
+	}
+	return math.Sqrt(m.sums.sqErr / m.sums.numel)
+}
+
+// Reset implements Metric interface.
+func (m *RMSE) Reset() {
+	m.sums.reset()
+}
+
+// R2 is the coefficient of determination `1 - SS_res / SS_tot`.
+//
+// All target elements are treated as a single output.
+type R2 struct {
+	sums regressionSums
+}
+
+// NewR2 creates a R² metric.
+func NewR2() *R2 {
+	return &R2{}
+}
+
+// Update accumulates a batch of predictions and targets of the same shape.
+func (m *R2) Update(preds, targets *ts.Tensor) error {
+	if err := m.sums.update(preds, targets); err != nil {
+		return fmt.Errorf("R2.Update() failed: %w", err)
+	}
+	return nil
+}
+
+// Compute implements Metric interface. It returns NaN if targets have zero variance.
+func (m *R2) Compute() float64 {
+	s := m.sums
+	if s.numel == 0 {
+		return math.NaN()
+	}
+	ssTot := s.sqTarget - s.target*s.target/s.numel
+	if ssTot <= 0 {
+		return math.NaN()
+	}
+	return 1 - s.sqErr/ssTot
+}
+
+// Reset implements Metric interface.
+func (m *R2) Reset() {
+	m.sums.reset()
+}
@@ -0,0 +1,30 @@
+package metrics_test
+
+import (
+	"testing"
+
+	"github.com/sugarme/gotch/metrics"
+	"github.com/sugarme/gotch/ts"
+)
+
+// Reference values are from scikit-learn.
+func TestRegressionMetrics(t *testing.T) {
+	targets := ts.MustOfSlice([]float32{3, -0.5, 2, 7})
+	preds := ts.MustOfSlice([]float32{2.5, 0.0, 2, 8})
+
+	mae, rmse, r2 := metrics.NewMAE(), metrics.NewRMSE(), metrics.NewR2()
+	for _, m := range []metrics.Metric{mae, rmse, r2} {
+		for _, idx := range []*ts.Narrow{ts.NewNarrow(0, 1), ts.NewNarrow(1, 4)} {
+			if err := m.Update(preds.Idx(idx), targets.Idx(idx)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	assertClose(t, "mae", 0.5, mae.Compute())
+	assertClose(t, "rmse", 0.6124, rmse.Compute())
+	assertClose(t, "r2", 0.9486, r2.Compute())
+
+	if err := mae.Update(preds, ts.MustOfSlice([]float32{1, 2})); err == nil {
+		t.Errorf("want error for mismatched shapes\n")
+	}
+}
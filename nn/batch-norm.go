@@ -3,6 +3,7 @@ package nn
 // A batch-normalization layer.
 
 import (
+	"fmt"
 	"log"
 
 	"github.com/sugarme/gotch/ts"
@@ -35,6 +36,11 @@ type BatchNorm struct {
 	Ws          *ts.Tensor
 	Bs          *ts.Tensor
 	Nd          uint
+
+	// folded is set when the layer has been folded into the preceding
+	// Conv2D/Linear layer (see FuseModel). A folded layer is an identity in
+	// evaluation mode and panics in training mode.
+	folded bool
 }
 
 // NewBatchNorm creates a new BatchNorm layer
@@ -72,10 +78,21 @@ func BatchNorm3D(vs *Path, outDim int64, config *BatchNormConfig) *BatchNorm {
 	return NewBatchNorm(vs, 3, outDim, config)
 }
 
+// errFoldedTrain is raised when a batch-norm layer folded by FuseModel runs in training mode.
+// Its preceding layer holds fused weights which are not in the var store, so training the
+// fused model would not train the saved one.
+var errFoldedTrain = fmt.Errorf("BatchNorm layer is folded into its preceding layer (see FuseModel) and cannot run in training mode")
+
 // Implement ModuleT interface for BatchNorm:
 // ==========================================
 
 func (bn *BatchNorm) ForwardT(xs *ts.Tensor, train bool) (retVal *ts.Tensor) {
+	if bn.folded {
+		if train {
+			panic(errFoldedTrain)
+		}
+		return xs.MustShallowClone()
+	}
 
 	dim := xs.Dim()
 
@@ -96,6 +113,10 @@ func (bn *BatchNorm) ForwardT(xs *ts.Tensor, train bool) (retVal *ts.Tensor) {
 // This forwarding will update BatchNorm weight by default (training=true).
 // Wrap module with tensor.NoGrad() when running model inference mode.
 func (bn *BatchNorm) Forward(xs *ts.Tensor) (retVal *ts.Tensor) {
+	if bn.folded {
+		panic(errFoldedTrain)
+	}
+
 	dim := xs.Dim()
 
 	if bn.Nd == 1 && dim != 2 && dim != 3 {
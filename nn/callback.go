@@ -0,0 +1,292 @@
+package nn
+
+// Trainer callbacks.
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Callback is called by Trainer at the stages of training.
+//
+// Batch logs hold "loss" and metric values of a training batch. Epoch logs are
+// described in Trainer. An error returned by OnEpochEnd stops training and is
+// returned by Trainer.Fit.
+type Callback interface {
+	OnTrainBegin(t *Trainer)
+	OnEpochBegin(t *Trainer, epoch int)
+	OnBatchEnd(t *Trainer, batch int, logs map[string]float64)
+	OnEpochEnd(t *Trainer, epoch int, logs map[string]float64) error
+	OnTrainEnd(t *Trainer, history []map[string]float64)
+}
+
+// CallbackFuncs implements Callback with optional hook functions. Nil hooks are skipped.
+type CallbackFuncs struct {
+	TrainBegin func(t *Trainer)
+	EpochBegin func(t *Trainer, epoch int)
+	BatchEnd   func(t *Trainer, batch int, logs map[string]float64)
+	EpochEnd   func(t *Trainer, epoch int, logs map[string]float64) error
+	TrainEnd   func(t *Trainer, history []map[string]float64)
+}
+
+func (c CallbackFuncs) OnTrainBegin(t *Trainer) {
+	if c.TrainBegin != nil {
+		c.TrainBegin(t)
+	}
+}
+
+func (c CallbackFuncs) OnEpochBegin(t *Trainer, epoch int) {
+	if c.EpochBegin != nil {
+		c.EpochBegin(t, epoch)
+	}
+}
+
+func (c CallbackFuncs) OnBatchEnd(t *Trainer, batch int, logs map[string]float64) {
+	if c.BatchEnd != nil {
+		c.BatchEnd(t, batch, logs)
+	}
+}
+
+func (c CallbackFuncs) OnEpochEnd(t *Trainer, epoch int, logs map[string]float64) error {
+	if c.EpochEnd != nil {
+		return c.EpochEnd(t, epoch, logs)
+	}
+	return nil
+}
+
+func (c CallbackFuncs) OnTrainEnd(t *Trainer, history []map[string]float64) {
+	if c.TrainEnd != nil {
+		c.TrainEnd(t, history)
+	}
+}
+
+// MonitorOpts are options for callbacks monitoring an epoch log value:
+//   - Mode: "min" if lower value is better, "max" otherwise. Default="min"
+//   - MinDelta: minimum change to qualify as an improvement. Default=0
+type MonitorOpts struct {
+	Mode     string
+	MinDelta float64
+}
+
+type MonitorOpt func(*MonitorOpts)
+
+func OptModeMonitor(v string) MonitorOpt {
+	return func(o *MonitorOpts) {
+		o.Mode = v
+	}
+}
+
+func OptMinDeltaMonitor(v float64) MonitorOpt {
+	return func(o *MonitorOpts) {
+		o.MinDelta = v
+	}
+}
+
+func DefaultMonitorOpts() *MonitorOpts {
+	return &MonitorOpts{
+		Mode:     "min",
+		MinDelta: 0,
+	}
+}
+
+// monitor keeps track of the best value of an epoch log.
+type monitor struct {
+	key       string
+	opts      *MonitorOpts
+	best      float64
+	bestEpoch int
+}
+
+func newMonitor(key string, opts []MonitorOpt) (*monitor, error) {
+	o := DefaultMonitorOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Mode != "min" && o.Mode != "max" {
+		err := fmt.Errorf("invalid monitor mode %q. Mode should be 'min' or 'max'", o.Mode)
+		return nil, err
+	}
+
+	m := &monitor{key: key, opts: o}
+	m.reset()
+	return m, nil
+}
+
+func (m *monitor) reset() {
+	m.best = math.Inf(1)
+	if m.opts.Mode == "max" {
+		m.best = math.Inf(-1)
+	}
+	m.bestEpoch = -1
+}
+
+// update returns whether value of the monitored key in logs is an improvement.
+// It returns an error if logs miss the key, e.g. "val_loss" when training without
+// validation data.
+func (m *monitor) update(epoch int, logs map[string]float64) (bool, error) {
+	v, ok := logs[m.key]
+	if !ok {
+		keys := make([]string, 0, len(logs))
+		for k := range logs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		err := fmt.Errorf("monitored value %q not found in epoch logs. Available values: %v", m.key, keys)
+		return false, err
+	}
+
+	better := v < m.best-m.opts.MinDelta
+	if m.opts.Mode == "max" {
+		better = v > m.best+m.opts.MinDelta
+	}
+	if better {
+		m.best = v
+		m.bestEpoch = epoch
+	}
+	return better, nil
+}
+
+// EarlyStopping stops training when a monitored value (e.g. "val_loss") has not
+// improved for Patience epochs.
+type EarlyStopping struct {
+	CallbackFuncs
+	Patience int
+
+	monitor *monitor
+	wait    int
+}
+
+// NewEarlyStopping creates an EarlyStopping callback.
+func NewEarlyStopping(monitor string, patience int, opts ...MonitorOpt) (*EarlyStopping, error) {
+	m, err := newMonitor(monitor, opts)
+	if err != nil {
+		err = fmt.Errorf("NewEarlyStopping() failed: %w", err)
+		return nil, err
+	}
+
+	return &EarlyStopping{
+		Patience: patience,
+		monitor:  m,
+	}, nil
+}
+
+// Best returns the best monitored value and its epoch.
+func (es *EarlyStopping) Best() (float64, int) {
+	return es.monitor.best, es.monitor.bestEpoch
+}
+
+func (es *EarlyStopping) OnTrainBegin(t *Trainer) {
+	es.monitor.reset()
+	es.wait = 0
+}
+
+func (es *EarlyStopping) OnEpochEnd(t *Trainer, epoch int, logs map[string]float64) error {
+	better, err := es.monitor.update(epoch, logs)
+	if err != nil {
+		err = fmt.Errorf("EarlyStopping failed: %w", err)
+		return err
+	}
+	if better {
+		es.wait = 0
+		return nil
+	}
+
+	es.wait++
+	if es.wait >= es.Patience {
+		t.Stop()
+	}
+	return nil
+}
+
+// CheckpointBest saves the var store to a file whenever a monitored value improves.
+type CheckpointBest struct {
+	CallbackFuncs
+
+	vs       *VarStore
+	filepath string
+	monitor  *monitor
+}
+
+// NewCheckpointBest creates a CheckpointBest callback.
+func NewCheckpointBest(vs *VarStore, filepath string, monitor string, opts ...MonitorOpt) (*CheckpointBest, error) {
+	m, err := newMonitor(monitor, opts)
+	if err != nil {
+		err = fmt.Errorf("NewCheckpointBest() failed: %w", err)
+		return nil, err
+	}
+
+	return &CheckpointBest{
+		vs:       vs,
+		filepath: filepath,
+		monitor:  m,
+	}, nil
+}
+
+// Best returns the best monitored value and its epoch.
+func (c *CheckpointBest) Best() (float64, int) {
+	return c.monitor.best, c.monitor.bestEpoch
+}
+
+func (c *CheckpointBest) OnTrainBegin(t *Trainer) {
+	c.monitor.reset()
+}
+
+func (c *CheckpointBest) OnEpochEnd(t *Trainer, epoch int, logs map[string]float64) error {
+	better, err := c.monitor.update(epoch, logs)
+	if err != nil {
+		err = fmt.Errorf("CheckpointBest failed: %w", err)
+		return err
+	}
+	if !better {
+		return nil
+	}
+	if err := c.vs.Save(c.filepath); err != nil {
+		err = fmt.Errorf("CheckpointBest - saving checkpoint failed: %w", err)
+		return err
+	}
+	return nil
+}
+
+// NewLRLogger creates a callback writing learning rates of all optimizer parameter
+// groups at the end of every epoch.
+func NewLRLogger(w io.Writer) Callback {
+	return CallbackFuncs{
+		EpochEnd: func(t *Trainer, epoch int, logs map[string]float64) error {
+			fmt.Fprintf(w, "Epoch %d - learning rates: %v\n", epoch, t.Optimizer.GetLRs())
+			return nil
+		},
+	}
+}
+
+// NewProgressLogger creates a callback writing epoch logs at the end of every epoch
+// and batch logs every `every` batches. Batch logs are skipped if every <= 0.
+func NewProgressLogger(w io.Writer, every int) Callback {
+	return CallbackFuncs{
+		BatchEnd: func(t *Trainer, batch int, logs map[string]float64) {
+			if every > 0 && (batch+1)%every == 0 {
+				fmt.Fprintf(w, "Epoch %d - batch %d: %s\n", t.Epoch(), batch+1, formatLogs(logs))
+			}
+		},
+		EpochEnd: func(t *Trainer, epoch int, logs map[string]float64) error {
+			fmt.Fprintf(w, "Epoch %d/%d: %s\n", epoch+1, t.Opts.Epochs, formatLogs(logs))
+			return nil
+		},
+	}
+}
+
+func formatLogs(logs map[string]float64) string {
+	var keys []string
+	for k := range logs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var items []string
+	for _, k := range keys {
+		items = append(items, fmt.Sprintf("%s=%.4f", k, logs[k]))
+	}
+	return strings.Join(items, " ")
+}
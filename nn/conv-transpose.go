@@ -11,6 +11,7 @@ import (
 type ConvTranspose1DConfig struct {
 	Stride        []int64
 	Padding       []int64
+	PaddingMode   string // only "zeros" is supported.
 	OutputPadding []int64
 	Dilation      []int64
 	Groups        int64
@@ -22,6 +23,7 @@ type ConvTranspose1DConfig struct {
 type ConvTranspose2DConfig struct {
 	Stride        []int64
 	Padding       []int64
+	PaddingMode   string // only "zeros" is supported.
 	OutputPadding []int64
 	Dilation      []int64
 	Groups        int64
@@ -33,6 +35,7 @@ type ConvTranspose2DConfig struct {
 type ConvTranspose3DConfig struct {
 	Stride        []int64
 	Padding       []int64
+	PaddingMode   string // only "zeros" is supported.
 	OutputPadding []int64
 	Dilation      []int64
 	Groups        int64
@@ -46,6 +49,7 @@ func DefaultConvTranspose1DConfig() *ConvTranspose1DConfig {
 	return &ConvTranspose1DConfig{
 		Stride:        []int64{1},
 		Padding:       []int64{0},
+		PaddingMode:   "zeros",
 		OutputPadding: []int64{0},
 		Dilation:      []int64{1},
 		Groups:        1,
@@ -55,6 +59,46 @@ func DefaultConvTranspose1DConfig() *ConvTranspose1DConfig {
 	}
 }
 
+// DefaultConvTranspose2DConfig creates a default 2D ConvTransposeConfig
+func DefaultConvTranspose2DConfig() *ConvTranspose2DConfig {
+	return &ConvTranspose2DConfig{
+		Stride:        []int64{1, 1},
+		Padding:       []int64{0, 0},
+		PaddingMode:   "zeros",
+		OutputPadding: []int64{0, 0},
+		Dilation:      []int64{1, 1},
+		Groups:        1,
+		Bias:          true,
+		WsInit:        NewKaimingUniformInit(),
+		BsInit:        NewConstInit(float64(0.0)),
+	}
+}
+
+// DefaultConvTranspose3DConfig creates a default 3D ConvTransposeConfig
+func DefaultConvTranspose3DConfig() *ConvTranspose3DConfig {
+	return &ConvTranspose3DConfig{
+		Stride:        []int64{1, 1, 1},
+		Padding:       []int64{0, 0, 0},
+		PaddingMode:   "zeros",
+		OutputPadding: []int64{0, 0, 0},
+		Dilation:      []int64{1, 1, 1},
+		Groups:        1,
+		Bias:          true,
+		WsInit:        NewKaimingUniformInit(),
+		BsInit:        NewConstInit(float64(0.0)),
+	}
+}
+
+// checkConvTransposePaddingMode validates padding mode of transposed convolution.
+//
+// NOTE. As in Pytorch, only "zeros" padding mode is supported as padding of
+// transposed convolution removes (rather than adds) borders of the output.
+func checkConvTransposePaddingMode(mode string) {
+	if mode != "" && mode != "zeros" {
+		log.Fatalf("Only 'zeros' padding mode is supported for ConvTranspose. Got %q\n", mode)
+	}
+}
+
 type ConvTranspose1D struct {
 	Ws     *ts.Tensor
 	Bs     *ts.Tensor // optional
@@ -65,6 +109,7 @@ func NewConvTranspose1D(vs *Path, inDim, outDim int64, ksizes []int64, cfg *Conv
 	if len(ksizes) != 1 {
 		log.Fatalf("NewConvTranspose1D method call: Kernel size should be 1. Got %v\n", len(ksizes))
 	}
+	checkConvTransposePaddingMode(cfg.PaddingMode)
 
 	var (
 		ws *ts.Tensor
@@ -97,6 +142,7 @@ func NewConvTranspose2D(vs *Path, inDim, outDim int64, ksizes []int64, cfg *Conv
 	if len(ksizes) != 2 {
 		log.Fatalf("NewConvTranspose2D method call: Kernel size should be 2. Got %v\n", len(ksizes))
 	}
+	checkConvTransposePaddingMode(cfg.PaddingMode)
 
 	var (
 		ws *ts.Tensor
@@ -127,6 +173,7 @@ func NewConvTranspose3D(vs *Path, inDim, outDim int64, ksizes []int64, cfg *Conv
 	if len(ksizes) != 3 {
 		log.Fatalf("NewConvTranspose3D method call: Kernel size should be 3. Got %v\n", len(ksizes))
 	}
+	checkConvTransposePaddingMode(cfg.PaddingMode)
 
 	var (
 		ws *ts.Tensor
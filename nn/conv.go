@@ -14,14 +14,21 @@ import (
 // ============
 
 // Conv1DConfig is configuration struct for convolution 1D.
+//
+//   - KernelSize: optional per-dimension kernel size. If specified, it overrides kernel size `k` of `NewConv1D()`.
+//   - PaddingStr: optional "same" or "valid" padding. If specified, it overrides `Padding`.
+//   - PaddingMode: "zeros" (default), "reflect", "replicate" or "circular".
 type Conv1DConfig struct {
-	Stride   []int64
-	Padding  []int64
-	Dilation []int64
-	Groups   int64
-	Bias     bool
-	WsInit   Init
-	BsInit   Init
+	KernelSize  []int64
+	Stride      []int64
+	Padding     []int64
+	PaddingStr  string
+	PaddingMode string
+	Dilation    []int64
+	Groups      int64
+	Bias        bool
+	WsInit      Init
+	BsInit      Init
 }
 
 // Conv1DConfigOpt is option for Conv1DConfig.
@@ -48,6 +55,21 @@ func WithDilation1D(val int64) Conv1DConfigOpt {
 	}
 }
 
+// WithPaddingStr1D adds "same" or "valid" padding 1D option.
+func WithPaddingStr1D(val string) Conv1DConfigOpt {
+	return func(cfg *Conv1DConfig) {
+		cfg.PaddingStr = val
+	}
+}
+
+// WithPaddingMode1D adds padding mode 1D option.
+// It can be one of "zeros", "reflect", "replicate" or "circular".
+func WithPaddingMode1D(val string) Conv1DConfigOpt {
+	return func(cfg *Conv1DConfig) {
+		cfg.PaddingMode = val
+	}
+}
+
 func WithGroup1D(val int64) Conv1DConfigOpt {
 	return func(cfg *Conv1DConfig) {
 		cfg.Groups = val
@@ -79,13 +101,16 @@ func WithBsInit1D(val Init) Conv1DConfigOpt {
 func DefaultConv1DConfig() *Conv1DConfig {
 	negSlope := math.Sqrt(5)
 	return &Conv1DConfig{
-		Stride:   []int64{1},
-		Padding:  []int64{0},
-		Dilation: []int64{1},
-		Groups:   1,
-		Bias:     true,
-		WsInit:   NewKaimingUniformInit(WithKaimingNegativeSlope(negSlope)),
-		BsInit:   nil,
+		KernelSize:  nil,
+		Stride:      []int64{1},
+		Padding:     []int64{0},
+		PaddingStr:  "",
+		PaddingMode: "zeros",
+		Dilation:    []int64{1},
+		Groups:      1,
+		Bias:        true,
+		WsInit:      NewKaimingUniformInit(WithKaimingNegativeSlope(negSlope)),
+		BsInit:      nil,
 	}
 }
 
@@ -103,14 +128,21 @@ func NewConv1DConfig(opts ...Conv1DConfigOpt) *Conv1DConfig {
 // ============
 
 // Conv2DConfig is configuration for convolution 2D.
+//
+//   - KernelSize: optional per-dimension kernel size. If specified, it overrides kernel size `k` of `NewConv2D()`.
+//   - PaddingStr: optional "same" or "valid" padding. If specified, it overrides `Padding`.
+//   - PaddingMode: "zeros" (default), "reflect", "replicate" or "circular".
 type Conv2DConfig struct {
-	Stride   []int64
-	Padding  []int64
-	Dilation []int64
-	Groups   int64
-	Bias     bool
-	WsInit   Init
-	BsInit   Init
+	KernelSize  []int64
+	Stride      []int64
+	Padding     []int64
+	PaddingStr  string
+	PaddingMode string
+	Dilation    []int64
+	Groups      int64
+	Bias        bool
+	WsInit      Init
+	BsInit      Init
 }
 
 // Conv2DConfigOpt is option type for Conv2DConfig.
@@ -137,6 +169,49 @@ func WithDilation2D(val int64) Conv2DConfigOpt {
 	}
 }
 
+// WithKernelSize2D adds per-dimension kernel size 2D option.
+func WithKernelSize2D(val []int64) Conv2DConfigOpt {
+	return func(cfg *Conv2DConfig) {
+		cfg.KernelSize = val
+	}
+}
+
+// WithStrides2D adds per-dimension stride 2D option.
+func WithStrides2D(val []int64) Conv2DConfigOpt {
+	return func(cfg *Conv2DConfig) {
+		cfg.Stride = val
+	}
+}
+
+// WithPaddings2D adds per-dimension padding 2D option.
+func WithPaddings2D(val []int64) Conv2DConfigOpt {
+	return func(cfg *Conv2DConfig) {
+		cfg.Padding = val
+	}
+}
+
+// WithDilations2D adds per-dimension dilation 2D option.
+func WithDilations2D(val []int64) Conv2DConfigOpt {
+	return func(cfg *Conv2DConfig) {
+		cfg.Dilation = val
+	}
+}
+
+// WithPaddingStr2D adds "same" or "valid" padding 2D option.
+func WithPaddingStr2D(val string) Conv2DConfigOpt {
+	return func(cfg *Conv2DConfig) {
+		cfg.PaddingStr = val
+	}
+}
+
+// WithPaddingMode2D adds padding mode 2D option.
+// It can be one of "zeros", "reflect", "replicate" or "circular".
+func WithPaddingMode2D(val string) Conv2DConfigOpt {
+	return func(cfg *Conv2DConfig) {
+		cfg.PaddingMode = val
+	}
+}
+
 // WithGroup2D adds group 2D option.
 func WithGroup2D(val int64) Conv2DConfigOpt {
 	return func(cfg *Conv2DConfig) {
@@ -169,13 +244,16 @@ func WithBsInit2D(val Init) Conv2DConfigOpt {
 func DefaultConv2DConfig() *Conv2DConfig {
 	negSlope := math.Sqrt(5)
 	return &Conv2DConfig{
-		Stride:   []int64{1, 1},
-		Padding:  []int64{0, 0},
-		Dilation: []int64{1, 1},
-		Groups:   1,
-		Bias:     true,
-		WsInit:   NewKaimingUniformInit(WithKaimingNegativeSlope(negSlope)),
-		BsInit:   nil,
+		KernelSize:  nil,
+		Stride:      []int64{1, 1},
+		Padding:     []int64{0, 0},
+		PaddingStr:  "",
+		PaddingMode: "zeros",
+		Dilation:    []int64{1, 1},
+		Groups:      1,
+		Bias:        true,
+		WsInit:      NewKaimingUniformInit(WithKaimingNegativeSlope(negSlope)),
+		BsInit:      nil,
 	}
 }
 
@@ -193,14 +271,21 @@ func NewConv2DConfig(opts ...Conv2DConfigOpt) *Conv2DConfig {
 // =============
 
 // Conv3DConfig is configuration struct for convolution 3D.
+//
+//   - KernelSize: optional per-dimension kernel size. If specified, it overrides kernel size `k` of `NewConv3D()`.
+//   - PaddingStr: optional "same" or "valid" padding. If specified, it overrides `Padding`.
+//   - PaddingMode: "zeros" (default), "reflect", "replicate" or "circular".
 type Conv3DConfig struct {
-	Stride   []int64
-	Padding  []int64
-	Dilation []int64
-	Groups   int64
-	Bias     bool
-	WsInit   Init
-	BsInit   Init
+	KernelSize  []int64
+	Stride      []int64
+	Padding     []int64
+	PaddingStr  string
+	PaddingMode string
+	Dilation    []int64
+	Groups      int64
+	Bias        bool
+	WsInit      Init
+	BsInit      Init
 }
 
 // Conv3DConfigOpt is option type for Conv3DConfig.
@@ -227,6 +312,49 @@ func WithDilation3D(val int64) Conv3DConfigOpt {
 	}
 }
 
+// WithKernelSize3D adds per-dimension kernel size 3D option.
+func WithKernelSize3D(val []int64) Conv3DConfigOpt {
+	return func(cfg *Conv3DConfig) {
+		cfg.KernelSize = val
+	}
+}
+
+// WithStrides3D adds per-dimension stride 3D option.
+func WithStrides3D(val []int64) Conv3DConfigOpt {
+	return func(cfg *Conv3DConfig) {
+		cfg.Stride = val
+	}
+}
+
+// WithPaddings3D adds per-dimension padding 3D option.
+func WithPaddings3D(val []int64) Conv3DConfigOpt {
+	return func(cfg *Conv3DConfig) {
+		cfg.Padding = val
+	}
+}
+
+// WithDilations3D adds per-dimension dilation 3D option.
+func WithDilations3D(val []int64) Conv3DConfigOpt {
+	return func(cfg *Conv3DConfig) {
+		cfg.Dilation = val
+	}
+}
+
+// WithPaddingStr3D adds "same" or "valid" padding 3D option.
+func WithPaddingStr3D(val string) Conv3DConfigOpt {
+	return func(cfg *Conv3DConfig) {
+		cfg.PaddingStr = val
+	}
+}
+
+// WithPaddingMode3D adds padding mode 3D option.
+// It can be one of "zeros", "reflect", "replicate" or "circular".
+func WithPaddingMode3D(val string) Conv3DConfigOpt {
+	return func(cfg *Conv3DConfig) {
+		cfg.PaddingMode = val
+	}
+}
+
 // WithGroup3D adds group 3D option.
 func WithGroup3D(val int64) Conv3DConfigOpt {
 	return func(cfg *Conv3DConfig) {
@@ -259,13 +387,16 @@ func WithBsInit3D(val Init) Conv3DConfigOpt {
 func DefaultConv3DConfig() *Conv3DConfig {
 	negSlope := math.Sqrt(5)
 	return &Conv3DConfig{
-		Stride:   []int64{1, 1, 1},
-		Padding:  []int64{0, 0, 0},
-		Dilation: []int64{1, 1, 1},
-		Groups:   1,
-		Bias:     true,
-		WsInit:   NewKaimingUniformInit(WithKaimingNegativeSlope(negSlope)),
-		BsInit:   nil,
+		KernelSize:  nil,
+		Stride:      []int64{1, 1, 1},
+		Padding:     []int64{0, 0, 0},
+		PaddingStr:  "",
+		PaddingMode: "zeros",
+		Dilation:    []int64{1, 1, 1},
+		Groups:      1,
+		Bias:        true,
+		WsInit:      NewKaimingUniformInit(WithKaimingNegativeSlope(negSlope)),
+		BsInit:      nil,
 	}
 }
 
@@ -292,8 +423,16 @@ func NewConv1D(vs *Path, inDim, outDim, k int64, cfg *Conv1DConfig) *Conv1D {
 		ws *ts.Tensor
 		bs *ts.Tensor = ts.NewTensor()
 	)
+	ksizes := cfg.KernelSize
+	if len(ksizes) == 0 {
+		ksizes = []int64{k}
+	}
+	if err := checkConvConfig(1, ksizes, cfg.Stride, cfg.Padding, cfg.Dilation, cfg.PaddingStr, cfg.PaddingMode); err != nil {
+		err = fmt.Errorf("NewConv1D() failed: %w", err)
+		panic(err)
+	}
 	weightSize := []int64{outDim, int64(inDim / cfg.Groups)}
-	weightSize = append(weightSize, k)
+	weightSize = append(weightSize, ksizes...)
 	ws = vs.MustNewVar("weight", weightSize, cfg.WsInit)
 	if cfg.Bias {
 		switch {
@@ -334,8 +473,16 @@ func NewConv2D(vs *Path, inDim, outDim int64, k int64, cfg *Conv2DConfig) *Conv2
 		ws *ts.Tensor
 		bs *ts.Tensor = ts.NewTensor()
 	)
+	ksizes := cfg.KernelSize
+	if len(ksizes) == 0 {
+		ksizes = []int64{k, k}
+	}
+	if err := checkConvConfig(2, ksizes, cfg.Stride, cfg.Padding, cfg.Dilation, cfg.PaddingStr, cfg.PaddingMode); err != nil {
+		err = fmt.Errorf("NewConv2D() failed: %w", err)
+		panic(err)
+	}
 	weightSize := []int64{outDim, int64(inDim / cfg.Groups)}
-	weightSize = append(weightSize, k, k)
+	weightSize = append(weightSize, ksizes...)
 	ws = vs.MustNewVar("weight", weightSize, cfg.WsInit)
 
 	if cfg.Bias {
@@ -377,8 +524,16 @@ func NewConv3D(vs *Path, inDim, outDim, k int64, cfg *Conv3DConfig) *Conv3D {
 		ws *ts.Tensor
 		bs *ts.Tensor = ts.NewTensor()
 	)
+	ksizes := cfg.KernelSize
+	if len(ksizes) == 0 {
+		ksizes = []int64{k, k, k}
+	}
+	if err := checkConvConfig(3, ksizes, cfg.Stride, cfg.Padding, cfg.Dilation, cfg.PaddingStr, cfg.PaddingMode); err != nil {
+		err = fmt.Errorf("NewConv3D() failed: %w", err)
+		panic(err)
+	}
 	weightSize := []int64{outDim, int64(inDim / cfg.Groups)}
-	weightSize = append(weightSize, k, k, k)
+	weightSize = append(weightSize, ksizes...)
 	ws = vs.MustNewVar("weight", weightSize, cfg.WsInit)
 
 	if cfg.Bias {
@@ -511,18 +666,112 @@ func NewConv(vs *Path, inDim, outDim int64, ksizes []int64, config interface{})
 	}
 }
 
+// Padding:
+// ========
+
+// checkConvConfig validates per-dimension options and padding options of a nd convolution.
+func checkConvConfig(nd int, ksizes, stride, padding, dilation []int64, paddingStr, paddingMode string) error {
+	for name, v := range map[string][]int64{"kernel size": ksizes, "stride": stride, "dilation": dilation} {
+		if len(v) != nd {
+			return fmt.Errorf("expected %s of %d elements, got %v", name, nd, v)
+		}
+	}
+
+	switch paddingStr {
+	case "":
+		if len(padding) != nd {
+			return fmt.Errorf("expected padding of %d elements, got %v", nd, padding)
+		}
+	case "valid":
+	case "same":
+		for _, s := range stride {
+			if s != 1 {
+				return fmt.Errorf("padding 'same' is not supported for strided convolutions, got stride %v", stride)
+			}
+		}
+	default:
+		return fmt.Errorf("invalid padding string %q. It must be either 'same' or 'valid'", paddingStr)
+	}
+
+	switch paddingMode {
+	case "", "zeros", "reflect", "replicate", "circular":
+	default:
+		return fmt.Errorf("invalid padding mode %q. It must be one of 'zeros', 'reflect', 'replicate' or 'circular'", paddingMode)
+	}
+
+	return nil
+}
+
+// convPadding returns padding for each side of each spatial dimension in the format of `ts.Pad()`,
+// i.e., starting from the last dimension: {left_last, right_last, ..., left_first, right_first}.
+// It also returns whether padding is symmetric (same padding both sides for all dimensions).
+func convPadding(ksizes, padding, dilation []int64, paddingStr string) ([]int64, bool) {
+	nd := len(ksizes)
+	pad := make([]int64, 2*nd)
+	symmetric := true
+	for i := 0; i < nd; i++ {
+		var left, right int64
+		switch paddingStr {
+		case "valid":
+			left, right = 0, 0
+		case "same":
+			total := dilation[i] * (ksizes[i] - 1)
+			left = total / 2
+			right = total - left
+		default:
+			left, right = padding[i], padding[i]
+		}
+		pad[2*(nd-1-i)] = left
+		pad[2*(nd-1-i)+1] = right
+		if left != right {
+			symmetric = false
+		}
+	}
+
+	return pad, symmetric
+}
+
+type convFn func(input, weight, bias *ts.Tensor, stride, padding, dilation []int64, groups int64) *ts.Tensor
+
+// convForward applies padding following padding string and padding mode then convolution.
+func convForward(xs, ws, bs *ts.Tensor, conv convFn, stride, padding, dilation []int64, groups int64, paddingStr, paddingMode string) *ts.Tensor {
+	if paddingStr == "" && (paddingMode == "" || paddingMode == "zeros") {
+		return conv(xs, ws, bs, stride, padding, dilation, groups)
+	}
+
+	ksizes := ws.MustSize()[2:]
+	pad, symmetric := convPadding(ksizes, padding, dilation, paddingStr)
+
+	if paddingMode == "" || paddingMode == "zeros" {
+		if symmetric {
+			convPad := make([]int64, len(ksizes))
+			for i := range convPad {
+				convPad[i] = pad[2*(len(ksizes)-1-i)]
+			}
+			return conv(xs, ws, bs, stride, convPad, dilation, groups)
+		}
+		paddingMode = "constant"
+	}
+
+	padded := xs.MustPad(pad, paddingMode, nil, false)
+	retVal := conv(padded, ws, bs, stride, make([]int64, len(ksizes)), dilation, groups)
+	padded.MustDrop()
+
+	return retVal
+}
+
 // Implement Module for Conv1D, Conv2D, Conv3D:
 // ============================================
 
 func (c *Conv1D) Forward(xs *ts.Tensor) *ts.Tensor {
-	return ts.MustConv1d(xs, c.Ws, c.Bs, c.Config.Stride, c.Config.Padding, c.Config.Dilation, c.Config.Groups)
+	return convForward(xs, c.Ws, c.Bs, ts.MustConv1d, c.Config.Stride, c.Config.Padding, c.Config.Dilation, c.Config.Groups, c.Config.PaddingStr, c.Config.PaddingMode)
 }
 
 func (c *Conv2D) Forward(xs *ts.Tensor) *ts.Tensor {
-	return ts.MustConv2d(xs, c.Ws, c.Bs, c.Config.Stride, c.Config.Padding, c.Config.Dilation, c.Config.Groups)
+	return convForward(xs, c.Ws, c.Bs, ts.MustConv2d, c.Config.Stride, c.Config.Padding, c.Config.Dilation, c.Config.Groups, c.Config.PaddingStr, c.Config.PaddingMode)
 }
 func (c *Conv3D) Forward(xs *ts.Tensor) *ts.Tensor {
-	return ts.MustConv3d(xs, c.Ws, c.Bs, c.Config.Stride, c.Config.Padding, c.Config.Dilation, c.Config.Groups)
+	return convForward(xs, c.Ws, c.Bs, ts.MustConv3d, c.Config.Stride, c.Config.Padding, c.Config.Dilation, c.Config.Groups, c.Config.PaddingStr, c.Config.PaddingMode)
 }
 
 // Implement ModuleT for Conv1D, Conv2D, Conv3D:
@@ -531,12 +780,12 @@ func (c *Conv3D) Forward(xs *ts.Tensor) *ts.Tensor {
 // NOTE: `train` param won't be used, will be?
 
 func (c *Conv1D) ForwardT(xs *ts.Tensor, train bool) *ts.Tensor {
-	return ts.MustConv1d(xs, c.Ws, c.Bs, c.Config.Stride, c.Config.Padding, c.Config.Dilation, c.Config.Groups)
+	return convForward(xs, c.Ws, c.Bs, ts.MustConv1d, c.Config.Stride, c.Config.Padding, c.Config.Dilation, c.Config.Groups, c.Config.PaddingStr, c.Config.PaddingMode)
 }
 
 func (c *Conv2D) ForwardT(xs *ts.Tensor, train bool) *ts.Tensor {
-	return ts.MustConv2d(xs, c.Ws, c.Bs, c.Config.Stride, c.Config.Padding, c.Config.Dilation, c.Config.Groups)
+	return convForward(xs, c.Ws, c.Bs, ts.MustConv2d, c.Config.Stride, c.Config.Padding, c.Config.Dilation, c.Config.Groups, c.Config.PaddingStr, c.Config.PaddingMode)
 }
 func (c *Conv3D) ForwardT(xs *ts.Tensor, train bool) *ts.Tensor {
-	return ts.MustConv3d(xs, c.Ws, c.Bs, c.Config.Stride, c.Config.Padding, c.Config.Dilation, c.Config.Groups)
+	return convForward(xs, c.Ws, c.Bs, ts.MustConv3d, c.Config.Stride, c.Config.Padding, c.Config.Dilation, c.Config.Groups, c.Config.PaddingStr, c.Config.PaddingMode)
 }
@@ -0,0 +1,65 @@
+package nn_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	"github.com/sugarme/gotch/ts"
+)
+
+func TestConv2D_Padding(t *testing.T) {
+	tests := []struct {
+		name string
+		k    int64
+		opts []nn.Conv2DConfigOpt
+		want []int64
+	}{
+		{"default", 3, nil, []int64{2, 4, 6, 8}},
+		{"same", 3, []nn.Conv2DConfigOpt{nn.WithPaddingStr2D("same")}, []int64{2, 4, 8, 10}},
+		{"same even kernel", 4, []nn.Conv2DConfigOpt{nn.WithPaddingStr2D("same"), nn.WithPaddingMode2D("zeros")}, []int64{2, 4, 8, 10}},
+		{"valid", 3, []nn.Conv2DConfigOpt{nn.WithPaddingStr2D("valid")}, []int64{2, 4, 6, 8}},
+		{"reflect", 3, []nn.Conv2DConfigOpt{nn.WithPadding2D(1), nn.WithPaddingMode2D("reflect")}, []int64{2, 4, 8, 10}},
+		{"replicate", 3, []nn.Conv2DConfigOpt{nn.WithPadding2D(2), nn.WithPaddingMode2D("replicate")}, []int64{2, 4, 10, 12}},
+		{"circular same", 3, []nn.Conv2DConfigOpt{nn.WithPaddingStr2D("same"), nn.WithPaddingMode2D("circular")}, []int64{2, 4, 8, 10}},
+		{
+			"asymmetric kernel", 0,
+			[]nn.Conv2DConfigOpt{nn.WithKernelSize2D([]int64{1, 7}), nn.WithPaddings2D([]int64{0, 3}), nn.WithStrides2D([]int64{2, 1})},
+			[]int64{2, 4, 4, 10},
+		},
+	}
+
+	for _, tt := range tests {
+		vs := nn.NewVarStore(gotch.CPU)
+		conv := nn.NewConv2D(vs.Root(), 3, 4, tt.k, nn.NewConv2DConfig(tt.opts...))
+		x := ts.MustRandn([]int64{2, 3, 8, 10}, gotch.Float, gotch.CPU)
+		out := conv.Forward(x)
+		got := out.MustSize()
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("%s - want output shape %v, got %v\n", tt.name, tt.want, got)
+		}
+		x.MustDrop()
+		out.MustDrop()
+	}
+}
+
+func TestConv2D_ReflectPaddingValues(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.NewConv2DConfig(
+		nn.WithKernelSize2D([]int64{1, 3}),
+		nn.WithPaddings2D([]int64{0, 1}),
+		nn.WithPaddingMode2D("reflect"),
+		nn.WithBias2D(false),
+		nn.WithWsInit2D(nn.NewConstInit(1.0)),
+	)
+	conv := nn.NewConv2D(vs.Root(), 1, 1, 0, cfg)
+
+	// input row [1 2 3] padded by reflection is [2 1 2 3 2]
+	x := ts.MustOfSlice([]float32{1, 2, 3}).MustView([]int64{1, 1, 1, 3}, true)
+	got := conv.Forward(x).Float64Values(true)
+	want := []float64{5, 6, 7}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v\n", want, got)
+	}
+}
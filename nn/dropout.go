@@ -0,0 +1,172 @@
+package nn
+
+// Dropout layers.
+
+import (
+	"log"
+
+	"github.com/sugarme/gotch/ts"
+)
+
+func checkDropoutProb(name string, p float64) {
+	if p < 0 || p > 1 {
+		log.Fatalf("%s: dropout probability has to be between 0 and 1, but got %v\n", name, p)
+	}
+}
+
+// Dropout:
+// ========
+
+// Dropout represents a neural network dropout layer.
+type Dropout struct {
+	dropoutProb float64
+}
+
+// NewDropout creates a new Dropout layer
+func NewDropout(p float64) *Dropout {
+	return &Dropout{
+		dropoutProb: p,
+	}
+}
+
+// ForwardT implements ModuleT for Dropout layer.
+func (d *Dropout) ForwardT(input *ts.Tensor, train bool) (retVal *ts.Tensor) {
+	return ts.MustDropout(input, d.dropoutProb, train)
+}
+
+// FeatureDropout:
+// ===============
+
+// FeatureDropout randomly zeroes out entire channels (feature maps) of input.
+//
+// Input is assumed to be of shape (N, C, *). Each channel is zeroed out
+// independently with probability p and the outputs are scaled by 1/(1-p)
+// during training. It is a no-op in eval mode.
+//
+// Use `NewDropout1D`, `NewDropout2D` or `NewDropout3D` to create one.
+type FeatureDropout struct {
+	dropoutProb float64
+	nd          uint
+}
+
+func newFeatureDropout(nd uint, p float64) *FeatureDropout {
+	checkDropoutProb("FeatureDropout", p)
+	return &FeatureDropout{
+		dropoutProb: p,
+		nd:          nd,
+	}
+}
+
+// NewDropout1D creates a channel-wise dropout layer for input of shape (N, C, L).
+func NewDropout1D(p float64) *FeatureDropout {
+	return newFeatureDropout(1, p)
+}
+
+// NewDropout2D creates a channel-wise dropout layer for input of shape (N, C, H, W).
+func NewDropout2D(p float64) *FeatureDropout {
+	return newFeatureDropout(2, p)
+}
+
+// NewDropout3D creates a channel-wise dropout layer for input of shape (N, C, D, H, W).
+func NewDropout3D(p float64) *FeatureDropout {
+	return newFeatureDropout(3, p)
+}
+
+// ForwardT implements ModuleT for FeatureDropout layer.
+func (d *FeatureDropout) ForwardT(input *ts.Tensor, train bool) *ts.Tensor {
+	if dim := input.Dim(); int(dim) != int(d.nd)+2 {
+		log.Fatalf("Dropout%dD: expected an input tensor with %v dims, got %v\n", d.nd, d.nd+2, input.MustSize())
+	}
+
+	if !train || d.dropoutProb == 0 {
+		return input.MustShallowClone()
+	}
+
+	return ts.MustFeatureDropout(input, d.dropoutProb, train)
+}
+
+// AlphaDropout:
+// =============
+
+// AlphaDropout applies dropout that maintains self-normalizing property
+// (zero mean and unit variance) of inputs. It should be used with SELU activation.
+//
+// It is a no-op in eval mode.
+// Paper: https://arxiv.org/abs/1706.02515
+type AlphaDropout struct {
+	dropoutProb float64
+}
+
+// NewAlphaDropout creates a new AlphaDropout layer.
+func NewAlphaDropout(p float64) *AlphaDropout {
+	checkDropoutProb("AlphaDropout", p)
+	return &AlphaDropout{
+		dropoutProb: p,
+	}
+}
+
+// ForwardT implements ModuleT for AlphaDropout layer.
+func (d *AlphaDropout) ForwardT(input *ts.Tensor, train bool) *ts.Tensor {
+	if !train || d.dropoutProb == 0 {
+		return input.MustShallowClone()
+	}
+
+	return ts.MustAlphaDropout(input, d.dropoutProb, train)
+}
+
+// DropPath:
+// =========
+
+// DropPath (stochastic depth) randomly drops entire samples of the main path
+// of residual blocks. Each sample in a batch is dropped independently with
+// probability p.
+//
+// It is a no-op in eval mode.
+// Paper: https://arxiv.org/abs/1603.09382
+type DropPath struct {
+	dropProb    float64
+	scaleByKeep bool
+}
+
+// NewDropPath creates a new DropPath layer.
+//
+// If scaleByKeepOpt is true (default), kept samples are scaled by 1/(1-p).
+func NewDropPath(p float64, scaleByKeepOpt ...bool) *DropPath {
+	checkDropoutProb("DropPath", p)
+	scaleByKeep := true
+	if len(scaleByKeepOpt) > 0 {
+		scaleByKeep = scaleByKeepOpt[0]
+	}
+
+	return &DropPath{
+		dropProb:    p,
+		scaleByKeep: scaleByKeep,
+	}
+}
+
+// ForwardT implements ModuleT for DropPath layer.
+func (d *DropPath) ForwardT(input *ts.Tensor, train bool) *ts.Tensor {
+	if !train || d.dropProb == 0 {
+		return input.MustShallowClone()
+	}
+
+	keepProb := 1.0 - d.dropProb
+
+	// mask of shape (N, 1, 1, ...) to work with tensors of any dims.
+	shape := make([]int64, input.Dim())
+	for i := range shape {
+		shape[i] = 1
+	}
+	shape[0] = input.MustSize()[0]
+
+	rand := ts.MustRand(shape, input.DType(), input.MustDevice())
+	mask := rand.MustLt(ts.FloatScalar(keepProb), true).MustTotype(input.DType(), true)
+	if d.scaleByKeep && keepProb > 0 {
+		mask.MustDivScalar_(ts.FloatScalar(keepProb))
+	}
+
+	retVal := input.MustMul(mask, false)
+	mask.MustDrop()
+
+	return retVal
+}
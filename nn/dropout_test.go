@@ -0,0 +1,62 @@
+package nn_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	"github.com/sugarme/gotch/ts"
+)
+
+func TestDropout_Eval(t *testing.T) {
+	x := ts.MustRandn([]int64{4, 3, 5, 5}, gotch.Float, gotch.CPU)
+	modules := map[string]ts.ModuleT{
+		"Dropout2D":    nn.NewDropout2D(0.5),
+		"AlphaDropout": nn.NewAlphaDropout(0.5),
+		"DropPath":     nn.NewDropPath(0.5),
+	}
+
+	for name, m := range modules {
+		out := m.ForwardT(x, false)
+		if !out.MustEqual(x, false) {
+			t.Errorf("%s - want no-op in eval mode\n", name)
+		}
+		out.MustDrop()
+	}
+}
+
+func TestDropout2D(t *testing.T) {
+	x := ts.MustOnes([]int64{8, 16, 4, 4}, gotch.Float, gotch.CPU)
+	out := nn.NewDropout2D(0.5).ForwardT(x, true)
+
+	// Each channel is either all zeros or all scaled by 1/(1-p).
+	channelMin := out.MustAmin([]int64{2, 3}, false, false).Float64Values(true)
+	channelMax := out.MustAmax([]int64{2, 3}, false, true).Float64Values(true)
+	for i := range channelMin {
+		if channelMin[i] != channelMax[i] || (channelMin[i] != 0 && channelMin[i] != 2) {
+			t.Fatalf("want channel values all 0 or all 2, got min %v, max %v\n", channelMin[i], channelMax[i])
+		}
+	}
+}
+
+func TestDropPath(t *testing.T) {
+	x := ts.MustOnes([]int64{32, 3, 4}, gotch.Float, gotch.CPU)
+	out := nn.NewDropPath(0.25).ForwardT(x, true)
+
+	// Each sample is either all zeros or all scaled by 1/(1-p).
+	sampleMin := out.MustAmin([]int64{1, 2}, false, false).Float64Values(true)
+	sampleMax := out.MustAmax([]int64{1, 2}, false, true).Float64Values(true)
+	for i := range sampleMin {
+		if sampleMin[i] != sampleMax[i] || (sampleMin[i] != 0 && math.Abs(sampleMin[i]-1/0.75) > 1e-6) {
+			t.Fatalf("want sample values all 0 or all 1/0.75, got min %v, max %v\n", sampleMin[i], sampleMax[i])
+		}
+	}
+
+	out = nn.NewDropPath(1.0).ForwardT(x, true)
+	for _, v := range out.Float64Values(true) {
+		if v != 0 {
+			t.Fatalf("want all samples dropped with p=1, got %v\n", v)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package nn
+
+// Pretrained word vectors (GloVe, word2vec) loaders.
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/ts"
+)
+
+// WordVectors holds pretrained word vectors.
+type WordVectors struct {
+	Dim     int64
+	Vectors map[string][]float32
+}
+
+// LoadWordVectors loads pretrained word vectors from file.
+//
+//   - binaryFormat=false: text format, one word per line followed by its values separated by spaces.
+//     It supports GloVe format (no header) and word2vec text format (header line "<numWords> <dim>").
+//   - binaryFormat=true: word2vec binary format (header line "<numWords> <dim>" followed by
+//     records of a space-terminated word and `dim` little-endian float32 values).
+//
+// If a word appears more than once, the first vector is kept.
+func LoadWordVectors(file string, binaryFormat bool) (*WordVectors, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		err = fmt.Errorf("LoadWordVectors() failed: %w", err)
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+
+	var wv *WordVectors
+	if binaryFormat {
+		wv, err = readWord2VecBinary(r)
+	} else {
+		wv, err = readWordVectorsText(r)
+	}
+	if err != nil {
+		err = fmt.Errorf("LoadWordVectors() failed: %w", err)
+		return nil, err
+	}
+
+	return wv, nil
+}
+
+// parseHeader parses word2vec header line "<numWords> <dim>".
+func parseHeader(line string) (numWords, dim int64, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return 0, 0, false
+	}
+	n, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	d, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return n, d, true
+}
+
+func readWordVectorsText(r *bufio.Reader) (*WordVectors, error) {
+	wv := &WordVectors{
+		Dim:     -1,
+		Vectors: make(map[string][]float32),
+	}
+
+	lineNum := 0
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		lineNum++
+
+		line = strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(line) != "" {
+			if lineNum == 1 {
+				if _, dim, ok := parseHeader(line); ok {
+					wv.Dim = dim
+					line = ""
+				}
+			}
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			if wv.Dim < 0 {
+				wv.Dim = int64(len(fields) - 1)
+			}
+			if int64(len(fields)-1) != wv.Dim {
+				err := fmt.Errorf("line %d: expected %d values, got %d", lineNum, wv.Dim, len(fields)-1)
+				return nil, err
+			}
+
+			word := fields[0]
+			if _, ok := wv.Vectors[word]; !ok {
+				vec := make([]float32, wv.Dim)
+				for i, s := range fields[1:] {
+					v, err := strconv.ParseFloat(s, 32)
+					if err != nil {
+						err = fmt.Errorf("line %d: %w", lineNum, err)
+						return nil, err
+					}
+					vec[i] = float32(v)
+				}
+				wv.Vectors[word] = vec
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if wv.Dim <= 0 {
+		err := fmt.Errorf("no word vectors found")
+		return nil, err
+	}
+
+	return wv, nil
+}
+
+func readWord2VecBinary(r *bufio.Reader) (*WordVectors, error) {
+	header, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	numWords, dim, ok := parseHeader(header)
+	if !ok || dim <= 0 {
+		err := fmt.Errorf("invalid word2vec binary header %q", strings.TrimSpace(header))
+		return nil, err
+	}
+
+	wv := &WordVectors{
+		Dim:     dim,
+		Vectors: make(map[string][]float32, numWords),
+	}
+
+	buf := make([]byte, 4*dim)
+	for i := int64(0); i < numWords; i++ {
+		word, err := r.ReadString(' ')
+		if err != nil {
+			err = fmt.Errorf("reading word %d: %w", i, err)
+			return nil, err
+		}
+		// NOTE. some writers put a newline after each vector.
+		word = strings.TrimLeft(strings.TrimSuffix(word, " "), "\n")
+
+		if _, err := io.ReadFull(r, buf); err != nil {
+			err = fmt.Errorf("reading vector of word %q: %w", word, err)
+			return nil, err
+		}
+
+		if _, ok := wv.Vectors[word]; ok {
+			continue
+		}
+		vec := make([]float32, dim)
+		for j := range vec {
+			vec[j] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*j:]))
+		}
+		wv.Vectors[word] = vec
+	}
+
+	return wv, nil
+}
+
+// Weight creates a 2D weight tensor of shape [numEmbeddings, Dim] from a vocabulary
+// mapping word to row index, where numEmbeddings = max(index) + 1.
+//
+// Rows of words not found in the pretrained vectors (and rows not in vocabulary) are
+// initiated with `init`. It returns the weight tensor (Float dtype, on CPU) and a list
+// of missing words.
+func (wv *WordVectors) Weight(vocab map[string]int64, init Init) (*ts.Tensor, []string, error) {
+	var numEmbeddings int64 = 0
+	for word, idx := range vocab {
+		if idx < 0 {
+			err := fmt.Errorf("WordVectors.Weight() failed: negative index %v for word %q", idx, word)
+			return nil, nil, err
+		}
+		if idx+1 > numEmbeddings {
+			numEmbeddings = idx + 1
+		}
+	}
+	if numEmbeddings == 0 {
+		err := fmt.Errorf("WordVectors.Weight() failed: empty vocabulary")
+		return nil, nil, err
+	}
+
+	initTs := init.InitTensor([]int64{numEmbeddings, wv.Dim}, gotch.CPU, gotch.Float)
+	data := make([]float32, numEmbeddings*wv.Dim)
+	initTs.MustCopyData(data, uint(len(data)))
+	initTs.MustDrop()
+
+	var missing []string
+	for word, idx := range vocab {
+		vec, ok := wv.Vectors[word]
+		if !ok {
+			missing = append(missing, word)
+			continue
+		}
+		copy(data[idx*wv.Dim:(idx+1)*wv.Dim], vec)
+	}
+	sort.Strings(missing)
+
+	weight, err := ts.NewTensorFromData(data, []int64{numEmbeddings, wv.Dim})
+	if err != nil {
+		err = fmt.Errorf("WordVectors.Weight() failed: %w", err)
+		return nil, nil, err
+	}
+
+	return weight, missing, nil
+}
+
+// NewEmbeddingFromWordVectors creates a new Embedding from pretrained word vectors file
+// (see `LoadWordVectors()`) and a vocabulary mapping word to row index.
+//
+// Rows of words not found in the file are initiated with `config.WsInit`.
+// It returns the embedding and a list of vocabulary words missing from the file.
+func NewEmbeddingFromWordVectors(vs *Path, file string, binaryFormat bool, vocab map[string]int64, config *EmbeddingConfig) (*Embedding, []string, error) {
+	wv, err := LoadWordVectors(file, binaryFormat)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	weight, missing, err := wv.Weight(vocab, config.WsInit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	emb := NewEmbeddingFromPretrained(vs, weight, config)
+	weight.MustDrop()
+
+	return emb, missing, nil
+}
@@ -0,0 +1,134 @@
+package nn
+
+// Fold and Unfold layers.
+
+import (
+	"log"
+
+	"github.com/sugarme/gotch/ts"
+)
+
+// FoldOpts is options for Fold and Unfold layers.
+type FoldOpts struct {
+	Dilation []int64
+	Padding  []int64
+	Stride   []int64
+}
+
+type FoldOpt func(*FoldOpts)
+
+func OptDilationFold(v []int64) FoldOpt {
+	return func(o *FoldOpts) {
+		o.Dilation = v
+	}
+}
+
+func OptPaddingFold(v []int64) FoldOpt {
+	return func(o *FoldOpts) {
+		o.Padding = v
+	}
+}
+
+func OptStrideFold(v []int64) FoldOpt {
+	return func(o *FoldOpts) {
+		o.Stride = v
+	}
+}
+
+func DefaultFoldOpts() *FoldOpts {
+	return &FoldOpts{
+		Dilation: []int64{1, 1},
+		Padding:  []int64{0, 0},
+		Stride:   []int64{1, 1},
+	}
+}
+
+// Unfold:
+// =======
+
+// Unfold extracts sliding local blocks from a batched input tensor.
+//
+// Input of shape (N, C, H, W) is unfolded to a tensor of shape (N, C * prod(KernelSize), L)
+// where L is the total number of blocks.
+type Unfold struct {
+	KernelSize []int64
+	Dilation   []int64
+	Padding    []int64
+	Stride     []int64
+}
+
+// NewUnfold creates a new Unfold layer.
+func NewUnfold(kernelSize []int64, opts ...FoldOpt) *Unfold {
+	o := DefaultFoldOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &Unfold{
+		KernelSize: kernelSize,
+		Dilation:   o.Dilation,
+		Padding:    o.Padding,
+		Stride:     o.Stride,
+	}
+}
+
+// Forward implements Module interface for Unfold.
+func (m *Unfold) Forward(x *ts.Tensor) *ts.Tensor {
+	if x.Dim() != 3 && x.Dim() != 4 {
+		log.Fatalf("Unfold: expected 3D or 4D input tensor, got %v\n", x.MustSize())
+	}
+	return x.MustIm2col(m.KernelSize, m.Dilation, m.Padding, m.Stride, false)
+}
+
+// ForwardT implements ModuleT interface for Unfold.
+//
+// NOTE: train param will not be used.
+func (m *Unfold) ForwardT(x *ts.Tensor, train bool) *ts.Tensor {
+	return m.Forward(x)
+}
+
+// Fold:
+// =====
+
+// Fold combines an array of sliding local blocks into a large containing tensor.
+//
+// It is the reverse of Unfold. Input of shape (N, C * prod(KernelSize), L) is folded to
+// a tensor of shape (N, C, OutputSize[0], OutputSize[1]). Overlapping values are summed.
+type Fold struct {
+	OutputSize []int64
+	KernelSize []int64
+	Dilation   []int64
+	Padding    []int64
+	Stride     []int64
+}
+
+// NewFold creates a new Fold layer.
+func NewFold(outputSize, kernelSize []int64, opts ...FoldOpt) *Fold {
+	o := DefaultFoldOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &Fold{
+		OutputSize: outputSize,
+		KernelSize: kernelSize,
+		Dilation:   o.Dilation,
+		Padding:    o.Padding,
+		Stride:     o.Stride,
+	}
+}
+
+// Forward implements Module interface for Fold.
+func (m *Fold) Forward(x *ts.Tensor) *ts.Tensor {
+	if x.Dim() != 2 && x.Dim() != 3 {
+		log.Fatalf("Fold: expected 2D or 3D input tensor, got %v\n", x.MustSize())
+	}
+	return x.MustCol2im(m.OutputSize, m.KernelSize, m.Dilation, m.Padding, m.Stride, false)
+}
+
+// ForwardT implements ModuleT interface for Fold.
+//
+// NOTE: train param will not be used.
+func (m *Fold) ForwardT(x *ts.Tensor, train bool) *ts.Tensor {
+	return m.Forward(x)
+}
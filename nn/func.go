@@ -7,11 +7,16 @@ import (
 )
 
 type Func struct {
-	f func(*ts.Tensor) *ts.Tensor
+	f       func(*ts.Tensor) *ts.Tensor
+	modules []interface{}
 }
 
-func NewFunc(fn func(*ts.Tensor) *ts.Tensor) (retVal Func) {
-	return Func{f: fn}
+// NewFunc creates a layer from a closure.
+//
+// Optional modules are sub-modules used by the closure, listed in the order they are applied.
+// They are not used in forward pass but let model passes such as FuseModel reach them.
+func NewFunc(fn func(*ts.Tensor) *ts.Tensor, modules ...interface{}) (retVal Func) {
+	return Func{f: fn, modules: modules}
 }
 
 // Implement Module interface for Func:
@@ -27,12 +32,21 @@ func (fn Func) ForwardT(xs *ts.Tensor, train bool) (retVal *ts.Tensor) {
 	return fn.f(xs)
 }
 
+// Modules returns sub-modules registered with NewFunc.
+func (fn Func) Modules() []interface{} {
+	return fn.modules
+}
+
 type FuncT struct {
-	f func(*ts.Tensor, bool) *ts.Tensor
+	f       func(*ts.Tensor, bool) *ts.Tensor
+	modules []interface{}
 }
 
-func NewFuncT(fn func(*ts.Tensor, bool) *ts.Tensor) (retVal FuncT) {
-	return FuncT{f: fn}
+// NewFuncT creates a layer with support for a training mode from a closure.
+//
+// Optional modules are sub-modules used by the closure, listed in the order they are applied.
+func NewFuncT(fn func(*ts.Tensor, bool) *ts.Tensor, modules ...interface{}) (retVal FuncT) {
+	return FuncT{f: fn, modules: modules}
 }
 
 // Implement Module interface for Func:
@@ -40,3 +54,8 @@ func NewFuncT(fn func(*ts.Tensor, bool) *ts.Tensor) (retVal FuncT) {
 func (fn FuncT) ForwardT(xs *ts.Tensor, train bool) (retVal *ts.Tensor) {
 	return fn.f(xs, train)
 }
+
+// Modules returns sub-modules registered with NewFuncT.
+func (fn FuncT) Modules() []interface{} {
+	return fn.modules
+}
@@ -0,0 +1,171 @@
+package nn
+
+// Folding batch-normalization layers into preceding convolution/linear layers for inference.
+
+import (
+	"fmt"
+
+	"github.com/sugarme/gotch/ts"
+)
+
+// ModuleLister is implemented by composite modules (Sequential, SequentialT, Func, FuncT and
+// models built upon them) to expose their sub-modules in the order they are applied.
+type ModuleLister interface {
+	Modules() []interface{}
+}
+
+// Unwrapper is implemented by wrapper modules whose output is the unchanged output of a
+// wrapped Conv2D or Linear layer, e.g. a convolution applying its own input padding.
+// FuseModel folds a batch-norm layer that follows such a wrapper into the wrapped layer.
+type Unwrapper interface {
+	Unwrap() interface{}
+}
+
+// bnScale returns `weight / sqrt(running_var + eps)` of a batch-norm layer.
+func bnScale(bn *BatchNorm) *ts.Tensor {
+	std := bn.RunningVar.MustAddScalar(ts.FloatScalar(bn.config.Eps), false).MustRsqrt(true)
+	return bn.Ws.MustMul(std, false).MustDetach(true)
+}
+
+// fusedBias returns `(bias - running_mean) * scale + bn_bias`. Nil or undefined bias
+// (e.g. Conv2D created with `Bias=false`) is treated as zeros.
+func fusedBias(bias *ts.Tensor, bn *BatchNorm, scale *ts.Tensor) *ts.Tensor {
+	shifted := bn.RunningMean.MustNeg(false)
+	if bias != nil && bias.MustDefined() {
+		shifted = shifted.MustAdd(bias, true)
+	}
+	return shifted.MustMul(scale, true).MustAdd(bn.Bs, true).MustDetach(true)
+}
+
+// FuseConvBN returns a new Conv2D layer that is equivalent to the given Conv2D layer
+// followed by a BatchNorm2D layer in evaluation mode, i.e. using the running statistics.
+//
+// Fused weights are not registered in the var store. The input layers are left unchanged.
+func FuseConvBN(conv *Conv2D, bn *BatchNorm) *Conv2D {
+	outDim := conv.Ws.MustSize()[0]
+	if bn.RunningMean.MustSize()[0] != outDim {
+		err := fmt.Errorf("FuseConvBN() failed: conv output channels (%v) and batch-norm features (%v) mismatched", outDim, bn.RunningMean.MustSize()[0])
+		panic(err)
+	}
+
+	var ws, bs *ts.Tensor
+	ts.NoGrad(func() {
+		scale := bnScale(bn)
+		ws = conv.Ws.MustMul(scale.MustView([]int64{outDim, 1, 1, 1}, false), false).MustDetach(true)
+		bs = fusedBias(conv.Bs, bn, scale)
+		scale.MustDrop()
+	})
+
+	config := *conv.Config
+	config.Bias = true
+
+	return &Conv2D{Ws: ws, Bs: bs, Config: &config}
+}
+
+// FuseLinearBN returns a new Linear layer that is equivalent to the given Linear layer
+// followed by a BatchNorm1D layer in evaluation mode, i.e. using the running statistics.
+//
+// Fused weights are not registered in the var store. The input layers are left unchanged.
+func FuseLinearBN(l *Linear, bn *BatchNorm) *Linear {
+	// NOTE. Linear weight is stored transposed as [in, out].
+	outDim := l.Ws.MustSize()[1]
+	if bn.RunningMean.MustSize()[0] != outDim {
+		err := fmt.Errorf("FuseLinearBN() failed: linear output features (%v) and batch-norm features (%v) mismatched", outDim, bn.RunningMean.MustSize()[0])
+		panic(err)
+	}
+
+	var ws, bs *ts.Tensor
+	ts.NoGrad(func() {
+		scale := bnScale(bn)
+		ws = l.Ws.MustMul(scale, false).MustDetach(true)
+		bs = fusedBias(l.Bs, bn, scale)
+		scale.MustDrop()
+	})
+
+	return &Linear{Ws: ws, Bs: bs}
+}
+
+// FuseModel folds every batch-norm layer that directly follows a Conv2D or Linear layer
+// into that layer, in place. It returns number of folded pairs.
+//
+// Model can be any module implementing ModuleLister (Sequential, SequentialT, Func/FuncT created
+// with sub-modules and vision models). Sub-modules are visited recursively; a pair is folded
+// when a Conv2D or Linear layer, or an Unwrapper of one, is directly followed by a batch-norm
+// layer in a module list. Other composite modules are never treated as the layer they wrap,
+// as their forward pass may compute more than that layer. Folded batch-norm layers are
+// removed from Sequential/SequentialT and become identity elsewhere.
+//
+// NOTE. The fused model is meant for inference only: folded batch-norm layers panic in
+// training mode. Var store variables are left unchanged.
+func FuseModel(model interface{}) (int, error) {
+	if _, ok := model.(ModuleLister); !ok {
+		err := fmt.Errorf("FuseModel() failed: unsupported model type %T. Model should implement 'nn.ModuleLister'", model)
+		return 0, err
+	}
+
+	return fuseModules(model), nil
+}
+
+func fuseModules(module interface{}) int {
+	lister, ok := module.(ModuleLister)
+	if !ok {
+		return 0
+	}
+
+	n := 0
+	modules := lister.Modules()
+	for _, m := range modules {
+		n += fuseModules(m)
+	}
+
+	for i := 0; i+1 < len(modules); i++ {
+		bn, ok := modules[i+1].(*BatchNorm)
+		if !ok || bn.folded {
+			continue
+		}
+
+		switch l := unwrapModule(modules[i]).(type) {
+		case *Conv2D:
+			*l = *FuseConvBN(l, bn)
+		case *Linear:
+			*l = *FuseLinearBN(l, bn)
+		default:
+			continue
+		}
+		bn.folded = true
+		n++
+	}
+
+	// Drop folded batch-norm layers from sequential layers.
+	switch s := module.(type) {
+	case *Sequential:
+		layers := s.layers[:0]
+		for _, l := range s.layers {
+			if bn, ok := l.(*BatchNorm); !ok || !bn.folded {
+				layers = append(layers, l)
+			}
+		}
+		s.layers = layers
+	case *SequentialT:
+		layers := s.layers[:0]
+		for _, l := range s.layers {
+			if bn, ok := l.(*BatchNorm); !ok || !bn.folded {
+				layers = append(layers, l)
+			}
+		}
+		s.layers = layers
+	}
+
+	return n
+}
+
+// unwrapModule returns the layer wrapped by Unwrapper modules.
+func unwrapModule(module interface{}) interface{} {
+	for {
+		u, ok := module.(Unwrapper)
+		if !ok {
+			return module
+		}
+		module = u.Unwrap()
+	}
+}
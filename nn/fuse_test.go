@@ -0,0 +1,170 @@
+package nn_test
+
+import (
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	"github.com/sugarme/gotch/ts"
+)
+
+// warmUp runs model in training mode to update batch-norm running statistics.
+func warmUp(model ts.ModuleT, shape []int64) {
+	ts.NoGrad(func() {
+		for i := 0; i < 5; i++ {
+			x := ts.MustRandn(shape, gotch.Float, gotch.CPU).MustMulScalar(ts.FloatScalar(3), true)
+			model.ForwardT(x, true).MustDrop()
+			x.MustDrop()
+		}
+	})
+}
+
+func TestFuseConvBN(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	config := nn.DefaultConv2DConfig()
+	config.Bias = false
+	conv := nn.NewConv2D(vs.Root().Sub("conv"), 3, 4, 3, config)
+	bn := nn.BatchNorm2D(vs.Root().Sub("bn"), 4, nn.DefaultBatchNormConfig())
+
+	seq := nn.SeqT()
+	seq.Add(conv)
+	seq.Add(bn)
+	warmUp(seq, []int64{8, 3, 6, 6})
+
+	x := ts.MustRandn([]int64{2, 3, 6, 6}, gotch.Float, gotch.CPU)
+	want := bn.ForwardT(conv.ForwardT(x, false), false)
+	got := nn.FuseConvBN(conv, bn).ForwardT(x, false)
+	if diff := maxAbsDiff(want, got); diff > 1e-4 {
+		t.Errorf("want fused output equal to conv+bn output, got max diff %v\n", diff)
+	}
+}
+
+func TestFuseLinearBN(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	linear := nn.NewLinear(vs.Root().Sub("fc"), 5, 3, nn.DefaultLinearConfig())
+	bn := nn.BatchNorm1D(vs.Root().Sub("bn"), 3, nn.DefaultBatchNormConfig())
+
+	seq := nn.SeqT()
+	seq.Add(linear)
+	seq.Add(bn)
+	warmUp(seq, []int64{16, 5})
+
+	x := ts.MustRandn([]int64{4, 5}, gotch.Float, gotch.CPU)
+	want := bn.ForwardT(linear.ForwardT(x, false), false)
+	got := nn.FuseLinearBN(linear, bn).ForwardT(x, false)
+	if diff := maxAbsDiff(want, got); diff > 1e-4 {
+		t.Errorf("want fused output equal to linear+bn output, got max diff %v\n", diff)
+	}
+}
+
+func TestFuseModel(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	root := vs.Root()
+	relu := nn.NewFunc(func(x *ts.Tensor) *ts.Tensor { return x.MustRelu(false) })
+
+	block := nn.SeqT()
+	block.Add(nn.NewConv2D(root.Sub("conv1"), 3, 4, 3, nn.DefaultConv2DConfig()))
+	block.Add(nn.BatchNorm2D(root.Sub("bn1"), 4, nn.DefaultBatchNormConfig()))
+	block.AddFn(relu)
+
+	// A closure layer exposing its sub-modules.
+	conv2 := nn.NewConv2D(root.Sub("conv2"), 4, 4, 1, nn.DefaultConv2DConfig())
+	bn2 := nn.BatchNorm2D(root.Sub("bn2"), 4, nn.DefaultBatchNormConfig())
+	residual := nn.NewFuncT(func(x *ts.Tensor, train bool) *ts.Tensor {
+		y := conv2.ForwardT(x, train)
+		z := bn2.ForwardT(y, train)
+		y.MustDrop()
+		return z.MustAdd(x, true)
+	}, conv2, bn2)
+
+	model := nn.SeqT()
+	model.Add(block)
+	model.Add(residual)
+	model.Add(nn.NewFlatten())
+	model.Add(nn.NewLinear(root.Sub("fc"), 4*4*4, 8, nn.DefaultLinearConfig()))
+	model.Add(nn.BatchNorm1D(root.Sub("bn3"), 8, nn.DefaultBatchNormConfig()))
+	warmUp(model, []int64{8, 3, 6, 6})
+
+	x := ts.MustRandn([]int64{2, 3, 6, 6}, gotch.Float, gotch.CPU)
+	want := model.ForwardT(x, false)
+
+	n, err := nn.FuseModel(model)
+	if err != nil || n != 3 {
+		t.Fatalf("FuseModel() - want 3 fused pairs, got %v (err: %v)\n", n, err)
+	}
+	if block.Len() != 2 || model.Len() != 4 {
+		t.Errorf("want folded batch-norm layers removed, got %v and %v layers\n", block.Len(), model.Len())
+	}
+
+	got := model.ForwardT(x, false)
+	if diff := maxAbsDiff(want, got); diff > 1e-4 {
+		t.Errorf("want fused model output equal to original output, got max diff %v\n", diff)
+	}
+
+	if _, err := nn.FuseModel(relu); err != nil {
+		t.Errorf("want no error for Func model, got %v\n", err)
+	}
+	if _, err := nn.FuseModel(conv2); err == nil {
+		t.Errorf("want error for unsupported model type\n")
+	}
+}
+
+func TestFuseModel_Wrappers(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	root := vs.Root()
+
+	// A closure over a single convolution computing more than the convolution.
+	conv1 := nn.NewConv2D(root.Sub("conv1"), 4, 4, 1, nn.DefaultConv2DConfig())
+	residual := nn.NewFuncT(func(x *ts.Tensor, train bool) *ts.Tensor {
+		return conv1.ForwardT(x, train).MustAdd(x, true)
+	}, conv1)
+
+	// A pass-through wrapper opting in with Unwrap.
+	conv2 := nn.NewConv2D(root.Sub("conv2"), 4, 4, 1, nn.DefaultConv2DConfig())
+
+	model := nn.SeqT()
+	model.Add(residual)
+	model.Add(nn.BatchNorm2D(root.Sub("bn1"), 4, nn.DefaultBatchNormConfig()))
+	model.Add(&unwrapConv{conv2})
+	model.Add(nn.BatchNorm2D(root.Sub("bn2"), 4, nn.DefaultBatchNormConfig()))
+	warmUp(model, []int64{8, 4, 5, 5})
+
+	x := ts.MustRandn([]int64{2, 4, 5, 5}, gotch.Float, gotch.CPU)
+	want := model.ForwardT(x, false)
+
+	n, err := nn.FuseModel(model)
+	if err != nil || n != 1 {
+		t.Fatalf("FuseModel() - want 1 fused pair, got %v (err: %v)\n", n, err)
+	}
+	got := model.ForwardT(x, false)
+	if diff := maxAbsDiff(want, got); diff > 1e-4 {
+		t.Errorf("want fused model output equal to original output, got max diff %v\n", diff)
+	}
+
+	// Folded batch-norm layers refuse to train.
+	bn := nn.BatchNorm2D(root.Sub("bn3"), 4, nn.DefaultBatchNormConfig())
+	seq := nn.SeqT()
+	seq.Add(nn.NewConv2D(root.Sub("conv3"), 4, 4, 1, nn.DefaultConv2DConfig()))
+	seq.Add(bn)
+	if _, err := nn.FuseModel(seq); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("want panic running folded batch-norm in training mode\n")
+		}
+	}()
+	bn.ForwardT(x, true)
+}
+
+type unwrapConv struct {
+	conv *nn.Conv2D
+}
+
+func (c *unwrapConv) ForwardT(x *ts.Tensor, train bool) *ts.Tensor {
+	return c.conv.ForwardT(x, train)
+}
+
+func (c *unwrapConv) Unwrap() interface{} {
+	return c.conv
+}
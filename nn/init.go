@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"path"
+	"sort"
 	"strings"
 
 	"github.com/sugarme/gotch"
@@ -278,19 +280,13 @@ func (k kaimingUniformInit) Set(tensor *ts.Tensor) {
 		log.Fatalf("uniformInit - Set method call error: %v\n", err)
 	}
 
-	fanIn, _, err := CalculateFans(dims)
-	if err != nil {
-		panic(err)
-	}
-
-	gain, err := calculateGain(k.NonLinearity, k.NegativeSlope) // default non-linearity="leaky_relu", negative_slope=0.01
+	// default non-linearity="leaky_relu", negative_slope=0.01, mode="fanIn"
+	std, err := kaimingStd(dims, k.Mode, k.NonLinearity, k.NegativeSlope)
 	if err != nil {
 		err = fmt.Errorf("kaimingUniformInit.Set() failed: %v\n", err)
 		panic(err)
 	}
 
-	std := gain / math.Sqrt(float64(fanIn)) // default using fanIn
-
 	// Calculate uniform bounds from standard deviation
 	bound := math.Sqrt(3.0) * std
 
@@ -299,20 +295,504 @@ func (k kaimingUniformInit) Set(tensor *ts.Tensor) {
 
 // glorotInit :
 // ====================
-type glorotNInit struct{}
 
-func NewGlorotNInit() glorotNInit {
-	return glorotNInit{}
+// glorotNInit initializes tensor with values drawn from a normal distribution
+// N(0, std^2) where std = gain * sqrt(2/(fanIn + fanOut)).
+//
+// Also known as Xavier normal initialization.
+type glorotNInit struct {
+	gain float64
 }
 
-func (gl glorotNInit) InitTensor(dims []int64, device gotch.Device) (retVal *ts.Tensor) {
-	// TODO: implement
+var _ Init = new(glorotNInit)
 
-	return
+// NewGlorotNInit creates Glorot (Xavier) normal initialization. Default gain = 1.0.
+func NewGlorotNInit(gainOpt ...float64) glorotNInit {
+	gain := 1.0
+	if len(gainOpt) > 0 {
+		gain = gainOpt[0]
+	}
+	return glorotNInit{gain}
+}
+
+// NewXavierNormalInit is an alias of NewGlorotNInit.
+func NewXavierNormalInit(gainOpt ...float64) glorotNInit {
+	return NewGlorotNInit(gainOpt...)
+}
+
+func (gl glorotNInit) std(dims []int64) float64 {
+	fanIn, fanOut, err := CalculateFans(dims)
+	if err != nil {
+		err = fmt.Errorf("glorotNInit failed: %w", err)
+		panic(err)
+	}
+
+	return gl.gain * math.Sqrt(2.0/float64(fanIn+fanOut))
+}
+
+func (gl glorotNInit) InitTensor(dims []int64, device gotch.Device, dtypeOpt ...gotch.DType) (retVal *ts.Tensor) {
+	dtype := gotch.DefaultDType
+	if len(dtypeOpt) > 0 {
+		dtype = dtypeOpt[0]
+	}
+
+	std := gl.std(dims)
+	ts.NoGrad(func() {
+		initTs := ts.MustRandn(dims, dtype, device)
+		retVal = initTs.MustMulScalar(ts.FloatScalar(std), true)
+	})
+
+	return retVal
 }
 
 func (gl glorotNInit) Set(tensor *ts.Tensor) {
-	// TODO: implement
+	std := gl.std(tensor.MustSize())
+	ts.NoGrad(func() {
+		tensor.MustNormal_(0.0, std)
+	})
+}
+
+// xavierUniformInit :
+// ===================
+
+// xavierUniformInit is `Init` version of `XavierUniform_`.
+type xavierUniformInit struct {
+	gain float64
+}
+
+var _ Init = new(xavierUniformInit)
+
+// NewXavierUniformInit creates Xavier (Glorot) uniform initialization. Default gain = 1.0.
+func NewXavierUniformInit(gainOpt ...float64) xavierUniformInit {
+	gain := 1.0
+	if len(gainOpt) > 0 {
+		gain = gainOpt[0]
+	}
+	return xavierUniformInit{gain}
+}
+
+func (x xavierUniformInit) InitTensor(dims []int64, device gotch.Device, dtypeOpt ...gotch.DType) (retVal *ts.Tensor) {
+	dtype := gotch.DefaultDType
+	if len(dtypeOpt) > 0 {
+		dtype = dtypeOpt[0]
+	}
+
+	ts.NoGrad(func() {
+		retVal = ts.MustZeros(dims, dtype, device)
+		x.Set(retVal)
+	})
+
+	return retVal
+}
+
+func (x xavierUniformInit) Set(tensor *ts.Tensor) {
+	ts.NoGrad(func() {
+		XavierUniform_(tensor, x.gain)
+	})
+}
+
+// kaimingNormalInit :
+// ===================
+
+// kaimingNormalInit initializes tensor with values drawn from a normal distribution
+// N(0, std^2) where std = gain / sqrt(fan) and fan is either fanIn or fanOut.
+//
+// Paper: https://arxiv.org/abs/1502.01852
+type kaimingNormalInit struct {
+	NegativeSlope float64
+	Mode          string
+	NonLinearity  string
+}
+
+var _ Init = new(kaimingNormalInit)
+
+// NewKaimingNormalInit creates Kaiming (He) normal initialization.
+func NewKaimingNormalInit(opts ...KaimingOption) *kaimingNormalInit {
+	o := NewKaimingOptions(opts...)
+
+	return &kaimingNormalInit{
+		NegativeSlope: o.NegativeSlope,
+		Mode:          o.Mode,
+		NonLinearity:  o.NonLinearity,
+	}
+}
+
+func (k *kaimingNormalInit) InitTensor(dims []int64, device gotch.Device, dtypeOpt ...gotch.DType) (retVal *ts.Tensor) {
+	dtype := gotch.DefaultDType
+	if len(dtypeOpt) > 0 {
+		dtype = dtypeOpt[0]
+	}
+
+	std, err := kaimingStd(dims, k.Mode, k.NonLinearity, k.NegativeSlope)
+	if err != nil {
+		err = fmt.Errorf("kaimingNormalInit.InitTensor() failed: %w", err)
+		panic(err)
+	}
+
+	ts.NoGrad(func() {
+		initTs := ts.MustRandn(dims, dtype, device)
+		retVal = initTs.MustMulScalar(ts.FloatScalar(std), true)
+	})
+
+	return retVal
+}
+
+func (k *kaimingNormalInit) Set(tensor *ts.Tensor) {
+	std, err := kaimingStd(tensor.MustSize(), k.Mode, k.NonLinearity, k.NegativeSlope)
+	if err != nil {
+		err = fmt.Errorf("kaimingNormalInit.Set() failed: %w", err)
+		panic(err)
+	}
+
+	ts.NoGrad(func() {
+		tensor.MustNormal_(0.0, std)
+	})
+}
+
+// kaimingStd calculates standard deviation for Kaiming initialization.
+func kaimingStd(dims []int64, mode, nonLinearity string, negativeSlope float64) (float64, error) {
+	fanIn, fanOut, err := CalculateFans(dims)
+	if err != nil {
+		return -1, err
+	}
+	fan := fanIn
+	if mode == "fanOut" {
+		fan = fanOut
+	}
+
+	gain, err := calculateGain(nonLinearity, negativeSlope)
+	if err != nil {
+		return -1, err
+	}
+
+	return gain / math.Sqrt(float64(fan)), nil
+}
+
+// truncNormalInit :
+// =================
+
+// truncNormalInit initializes tensor with values drawn from a normal distribution
+// N(mean, std^2) truncated to range [a, b].
+type truncNormalInit struct {
+	mean float64
+	std  float64
+	a    float64
+	b    float64
+}
+
+var _ Init = new(truncNormalInit)
+
+// NewTruncNormalInit creates truncated normal initialization.
+//
+// Values outside [a, b] are redrawn (by inverse CDF sampling) so that
+// all values are within the bounds.
+func NewTruncNormalInit(mean, std, a, b float64) truncNormalInit {
+	if a >= b {
+		err := fmt.Errorf("NewTruncNormalInit() failed: lower bound a (%v) must be smaller than upper bound b (%v)", a, b)
+		panic(err)
+	}
+	return truncNormalInit{mean, std, a, b}
+}
+
+func (t truncNormalInit) InitTensor(dims []int64, device gotch.Device, dtypeOpt ...gotch.DType) (retVal *ts.Tensor) {
+	dtype := gotch.DefaultDType
+	if len(dtypeOpt) > 0 {
+		dtype = dtypeOpt[0]
+	}
+
+	ts.NoGrad(func() {
+		retVal = ts.MustZeros(dims, dtype, device)
+		t.Set(retVal)
+	})
+
+	return retVal
+}
+
+// Set fills tensor using inverse CDF method.
+// Ref. https://github.com/pytorch/pytorch/blob/df50f91571891ec3f87977a2bdd4a2b609d70afc/torch/nn/init.py#L22
+func (t truncNormalInit) Set(tensor *ts.Tensor) {
+	normCdf := func(x float64) float64 {
+		return (1.0 + math.Erf(x/math.Sqrt2)) / 2.0
+	}
+
+	l := normCdf((t.a - t.mean) / t.std)
+	u := normCdf((t.b - t.mean) / t.std)
+
+	ts.NoGrad(func() {
+		tensor.MustUniform_(2*l-1, 2*u-1)
+		tensor.MustErfinv_()
+		tensor.MustMulScalar_(ts.FloatScalar(t.std * math.Sqrt2))
+		tensor.MustAddScalar_(ts.FloatScalar(t.mean))
+		tensor.MustClamp_(ts.FloatScalar(t.a), ts.FloatScalar(t.b))
+	})
+}
+
+// orthogonalInit :
+// ================
+
+// orthogonalInit initializes tensor with a (semi) orthogonal matrix.
+//
+// Tensor must have at least 2 dimensions. Trailing dimensions are flattened.
+// Paper: https://arxiv.org/abs/1312.6120
+type orthogonalInit struct {
+	gain float64
+}
+
+var _ Init = new(orthogonalInit)
+
+// NewOrthogonalInit creates orthogonal initialization. Default gain = 1.0.
+func NewOrthogonalInit(gainOpt ...float64) orthogonalInit {
+	gain := 1.0
+	if len(gainOpt) > 0 {
+		gain = gainOpt[0]
+	}
+	return orthogonalInit{gain}
+}
+
+func (o orthogonalInit) InitTensor(dims []int64, device gotch.Device, dtypeOpt ...gotch.DType) (retVal *ts.Tensor) {
+	dtype := gotch.DefaultDType
+	if len(dtypeOpt) > 0 {
+		dtype = dtypeOpt[0]
+	}
+
+	ts.NoGrad(func() {
+		retVal = ts.MustZeros(dims, dtype, device)
+		o.Set(retVal)
+	})
+
+	return retVal
+}
+
+func (o orthogonalInit) Set(tensor *ts.Tensor) {
+	dims := tensor.MustSize()
+	if len(dims) < 2 {
+		err := fmt.Errorf("orthogonalInit.Set() failed: only tensors with 2 or more dimensions are supported. Got %v", dims)
+		panic(err)
+	}
+
+	rows := dims[0]
+	cols := product(dims) / rows
+
+	ts.NoGrad(func() {
+		// NOTE. QR decomposition is done in Double on CPU for numerical stability.
+		flattened := ts.MustRandn([]int64{rows, cols}, gotch.Double, gotch.CPU)
+		if rows < cols {
+			flattened.MustT_()
+		}
+
+		q, r := ts.MustLinalgQr(flattened, "reduced")
+		flattened.MustDrop()
+
+		// Make Q uniform
+		d := r.MustDiag(0, true)
+		ph := d.MustSign(true)
+		q.MustMul_(ph)
+		ph.MustDrop()
+
+		if rows < cols {
+			q.MustT_()
+		}
+
+		src := q.MustReshape(dims, true)
+		src.MustMulScalar_(ts.FloatScalar(o.gain))
+		tensor.Copy_(src)
+		src.MustDrop()
+	})
+}
+
+// diracInit :
+// ===========
+
+// diracInit initializes {3, 4, 5}-dimensional tensor with Dirac delta function.
+//
+// It preserves identity of the inputs in convolutional layers, where as many
+// input channels are preserved as possible. In case of groups > 1, each group
+// of channels preserves identity.
+type diracInit struct {
+	groups int64
+}
+
+var _ Init = new(diracInit)
+
+// NewDiracInit creates Dirac initialization. Default groups = 1.
+func NewDiracInit(groupsOpt ...int64) diracInit {
+	groups := int64(1)
+	if len(groupsOpt) > 0 {
+		groups = groupsOpt[0]
+	}
+	return diracInit{groups}
+}
+
+func (di diracInit) InitTensor(dims []int64, device gotch.Device, dtypeOpt ...gotch.DType) (retVal *ts.Tensor) {
+	dtype := gotch.DefaultDType
+	if len(dtypeOpt) > 0 {
+		dtype = dtypeOpt[0]
+	}
+
+	ts.NoGrad(func() {
+		retVal = ts.MustZeros(dims, dtype, device)
+		di.Set(retVal)
+	})
+
+	return retVal
+}
+
+func (di diracInit) Set(tensor *ts.Tensor) {
+	dims := tensor.MustSize()
+	ndims := len(dims)
+	if ndims < 3 || ndims > 5 {
+		err := fmt.Errorf("diracInit.Set() failed: only tensors with 3, 4, or 5 dimensions are supported. Got %v", dims)
+		panic(err)
+	}
+	if di.groups <= 0 || dims[0]%di.groups != 0 {
+		err := fmt.Errorf("diracInit.Set() failed: dim 0 (%v) must be divisible by groups (%v)", dims[0], di.groups)
+		panic(err)
+	}
+
+	outChansPerGroup := dims[0] / di.groups
+	minDim := outChansPerGroup
+	if dims[1] < minDim {
+		minDim = dims[1]
+	}
+
+	// strides of a contiguous tensor with shape `dims`
+	strides := make([]int64, ndims)
+	strides[ndims-1] = 1
+	for i := ndims - 2; i >= 0; i-- {
+		strides[i] = strides[i+1] * dims[i+1]
+	}
+
+	data := make([]float64, product(dims))
+	for g := int64(0); g < di.groups; g++ {
+		for d := int64(0); d < minDim; d++ {
+			idx := (g*outChansPerGroup+d)*strides[0] + d*strides[1]
+			for i := 2; i < ndims; i++ {
+				idx += (dims[i] / 2) * strides[i]
+			}
+			data[idx] = 1
+		}
+	}
+
+	src, err := ts.NewTensorFromData(data, dims)
+	if err != nil {
+		err = fmt.Errorf("diracInit.Set() failed: %w", err)
+		panic(err)
+	}
+	ts.NoGrad(func() {
+		tensor.Copy_(src)
+	})
+	src.MustDrop()
+}
+
+// eyeInit :
+// =========
+
+// eyeInit initializes 2-dimensional tensor with the identity matrix.
+//
+// It preserves the identity of the inputs in Linear layers, where as many
+// inputs are preserved as possible.
+type eyeInit struct{}
+
+var _ Init = new(eyeInit)
+
+// NewEyeInit creates identity matrix initialization.
+func NewEyeInit() eyeInit {
+	return eyeInit{}
+}
+
+func (e eyeInit) InitTensor(dims []int64, device gotch.Device, dtypeOpt ...gotch.DType) (retVal *ts.Tensor) {
+	dtype := gotch.DefaultDType
+	if len(dtypeOpt) > 0 {
+		dtype = dtypeOpt[0]
+	}
+
+	if len(dims) != 2 {
+		err := fmt.Errorf("eyeInit.InitTensor() failed: only 2-dimensional tensors are supported. Got %v", dims)
+		panic(err)
+	}
+
+	return ts.MustEyeM(dims[0], dims[1], dtype, device)
+}
+
+func (e eyeInit) Set(tensor *ts.Tensor) {
+	dims := tensor.MustSize()
+	if len(dims) != 2 {
+		err := fmt.Errorf("eyeInit.Set() failed: only 2-dimensional tensors are supported. Got %v", dims)
+		panic(err)
+	}
+
+	ts.NoGrad(func() {
+		src := ts.MustEyeM(dims[0], dims[1], tensor.DType(), tensor.MustDevice())
+		tensor.Copy_(src)
+		src.MustDrop()
+	})
+}
+
+// sparseInit :
+// ============
+
+// sparseInit initializes 2-dimensional tensor as a sparse matrix where
+// non-zero elements are drawn from normal distribution N(0, std^2).
+//
+// Paper: https://www.cs.toronto.edu/~jmartens/docs/Deep_HessianFree.pdf
+type sparseInit struct {
+	sparsity float64
+	std      float64
+}
+
+var _ Init = new(sparseInit)
+
+// NewSparseInit creates sparse initialization.
+//
+// - sparsity: fraction of elements in each column to be set to zero.
+// - std: standard deviation of normal distribution used to generate the non-zero values. Default = 0.01.
+func NewSparseInit(sparsity float64, stdOpt ...float64) sparseInit {
+	if sparsity < 0 || sparsity > 1 {
+		err := fmt.Errorf("NewSparseInit() failed: sparsity must be in range [0, 1]. Got %v", sparsity)
+		panic(err)
+	}
+	std := 0.01
+	if len(stdOpt) > 0 {
+		std = stdOpt[0]
+	}
+	return sparseInit{sparsity, std}
+}
+
+func (s sparseInit) InitTensor(dims []int64, device gotch.Device, dtypeOpt ...gotch.DType) (retVal *ts.Tensor) {
+	dtype := gotch.DefaultDType
+	if len(dtypeOpt) > 0 {
+		dtype = dtypeOpt[0]
+	}
+
+	ts.NoGrad(func() {
+		retVal = ts.MustZeros(dims, dtype, device)
+		s.Set(retVal)
+	})
+
+	return retVal
+}
+
+func (s sparseInit) Set(tensor *ts.Tensor) {
+	dims := tensor.MustSize()
+	if len(dims) != 2 {
+		err := fmt.Errorf("sparseInit.Set() failed: only 2-dimensional tensors are supported. Got %v", dims)
+		panic(err)
+	}
+
+	rows := dims[0]
+	numZeros := int(math.Ceil(s.sparsity * float64(rows)))
+
+	// mask with `numZeros` randomly selected zero rows in each column: ranks of uniform
+	// noise along rows are a random permutation per column drawn from the torch RNG.
+	ts.NoGrad(func() {
+		device := tensor.MustDevice()
+		noise := ts.MustRand(dims, gotch.Float, device)
+		ranks := noise.MustArgsort(0, false, true).MustArgsort(0, false, true)
+		mask := ranks.MustGe(ts.IntScalar(int64(numZeros)), true).MustTotype(tensor.DType(), true)
+		tensor.MustNormal_(0.0, s.std)
+		tensor.MustMul_(mask)
+		mask.MustDrop()
+	})
 }
 
 // KaimingUniform:
@@ -420,3 +900,98 @@ func XavierUniform_(x *ts.Tensor, gainOpt ...float64) {
 
 	src.MustDrop()
 }
+
+// ApplyInit:
+// ==========
+
+// InitRule specifies an initialization to apply to VarStore variables
+// selected by name pattern and kind.
+//
+// - Pattern: glob pattern (see `path.Match`) matched against full variable name
+// i.e., "layer1.conv1.weight". Empty pattern matches all variables.
+// - Kind: last element of variable name, i.e., "weight" or "bias". Empty kind matches all.
+// - Init: initialization to re-initialize (in-place) matched variables.
+type InitRule struct {
+	Pattern string
+	Kind    string
+	Init    Init
+}
+
+// Match returns whether variable name is selected by this rule.
+func (r InitRule) Match(name string) (bool, error) {
+	if r.Kind != "" {
+		elems := strings.Split(name, SEP)
+		if elems[len(elems)-1] != r.Kind {
+			return false, nil
+		}
+	}
+
+	if r.Pattern == "" {
+		return true, nil
+	}
+
+	return path.Match(r.Pattern, name)
+}
+
+// ApplyInit re-initializes (in-place) variables of "parameter" type in VarStore
+// after model construction.
+//
+// Rules are tried in order and the first matching rule is applied to the variable.
+// Variables that match no rule are left unchanged. It returns names of re-initialized
+// variables (sorted) and error if any rule has a malformed pattern.
+//
+// Example:
+//
+//	rules := []nn.InitRule{
+//		{Pattern: "encoder.*", Kind: "weight", Init: nn.NewOrthogonalInit()},
+//		{Kind: "weight", Init: nn.NewKaimingNormalInit(nn.WithKaimingNonLinearity("relu"))},
+//		{Kind: "bias", Init: nn.NewConstInit(0.0)},
+//	}
+//	_, err := nn.ApplyInit(vs, rules)
+func ApplyInit(vs *VarStore, rules []InitRule) ([]string, error) {
+	for _, r := range rules {
+		if r.Init == nil {
+			err := fmt.Errorf("ApplyInit() failed: rule (pattern: %q, kind: %q) has nil Init", r.Pattern, r.Kind)
+			return nil, err
+		}
+		if _, err := path.Match(r.Pattern, ""); err != nil {
+			err = fmt.Errorf("ApplyInit() failed: invalid pattern %q: %w", r.Pattern, err)
+			return nil, err
+		}
+	}
+
+	vs.Lock()
+	defer vs.Unlock()
+
+	// Initialize in sorted order so that tensors draw from the torch RNG reproducibly.
+	var params []string
+	for name, v := range vs.vars {
+		if v.Type == "parameter" {
+			params = append(params, name)
+		}
+	}
+	sort.Strings(params)
+
+	var names []string
+	for _, name := range params {
+		v := vs.vars[name]
+		for _, r := range rules {
+			ok, err := r.Match(name)
+			if err != nil {
+				err = fmt.Errorf("ApplyInit() failed: %w", err)
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+
+			ts.NoGrad(func() {
+				r.Init.Set(v.Tensor)
+			})
+			names = append(names, name)
+			break
+		}
+	}
+
+	return names, nil
+}
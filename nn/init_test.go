@@ -2,6 +2,8 @@ package nn
 
 import (
 	"fmt"
+	"math"
+	"reflect"
 	"testing"
 	"time"
 
@@ -42,3 +44,142 @@ func TestInitTensor_Memcheck(t *testing.T) {
 	time.Sleep(time.Second * 10)
 	gotch.PrintMemStats("Final")
 }
+
+func TestInit_Shapes(t *testing.T) {
+	inits := map[string]Init{
+		"xavierNormal":  NewXavierNormalInit(),
+		"xavierUniform": NewXavierUniformInit(),
+		"kaimingNormal": NewKaimingNormalInit(WithKaimingNonLinearity("relu")),
+		"truncNormal":   NewTruncNormalInit(0.0, 1.0, -2.0, 2.0),
+		"orthogonal":    NewOrthogonalInit(),
+		"eye":           NewEyeInit(),
+		"sparse":        NewSparseInit(0.5),
+	}
+
+	dims := []int64{4, 6}
+	for name, init := range inits {
+		x := init.InitTensor(dims, gotch.CPU)
+		got := x.MustSize()
+		if !reflect.DeepEqual(got, dims) {
+			t.Errorf("%s - want shape %v, got %v\n", name, dims, got)
+		}
+
+		init.Set(x)
+		if x.DType() != gotch.DefaultDType {
+			t.Errorf("%s - want dtype %v, got %v\n", name, gotch.DefaultDType, x.DType())
+		}
+		x.MustDrop()
+	}
+}
+
+func TestTruncNormalInit(t *testing.T) {
+	x := NewTruncNormalInit(0.0, 1.0, -0.5, 0.5).InitTensor([]int64{1000}, gotch.CPU)
+	for _, v := range x.Float64Values(true) {
+		if v < -0.5 || v > 0.5 {
+			t.Fatalf("want value in range [-0.5, 0.5], got %v\n", v)
+		}
+	}
+}
+
+func TestOrthogonalInit(t *testing.T) {
+	x := NewOrthogonalInit().InitTensor([]int64{3, 5}, gotch.CPU)
+
+	// rows are orthonormal: x * xT = I
+	got := x.MustMatmul(x.MustT(false), true)
+	want := ts.MustEye(3, gotch.DefaultDType, gotch.CPU)
+	if !got.MustAllclose(want, 1e-5, 1e-5, false, true) {
+		t.Errorf("want x * xT to be identity matrix\n")
+	}
+	want.MustDrop()
+}
+
+func TestDiracInit(t *testing.T) {
+	x := NewDiracInit().InitTensor([]int64{2, 3, 3, 3}, gotch.CPU)
+	vals := x.Float64Values(true)
+
+	// one `1` at center of each of min(2, 3) channels.
+	want := []int{4, 27 + 9 + 4}
+	var got []int
+	for i, v := range vals {
+		if v != 0 {
+			got = append(got, i)
+		}
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want non-zero indices %v, got %v\n", want, got)
+	}
+}
+
+func TestSparseInit(t *testing.T) {
+	x := NewSparseInit(0.5, 1.0).InitTensor([]int64{10, 4}, gotch.CPU)
+	zeros := x.MustEq(ts.FloatScalar(0.0), true).MustSumDimIntlist([]int64{0}, false, gotch.Int64, true)
+	for _, n := range zeros.Int64Values(true) {
+		if n < 5 {
+			t.Errorf("want at least 5 zeros per column, got %v\n", n)
+		}
+	}
+}
+
+func TestSparseInit_Seed(t *testing.T) {
+	ts.ManualSeed(42)
+	x := NewSparseInit(0.5, 1.0).InitTensor([]int64{10, 4}, gotch.CPU)
+	ts.ManualSeed(42)
+	y := NewSparseInit(0.5, 1.0).InitTensor([]int64{10, 4}, gotch.CPU)
+	if !x.MustEqual(y, false) {
+		t.Errorf("want same sparse tensors with the same seed\n")
+	}
+}
+
+func TestApplyInit(t *testing.T) {
+	vs := NewVarStore(gotch.CPU)
+	path := vs.Root()
+	l1 := NewLinear(path.Sub("l1"), 4, 4, DefaultLinearConfig())
+	l2 := NewLinear(path.Sub("l2"), 4, 4, DefaultLinearConfig())
+
+	rules := []InitRule{
+		{Pattern: "l1.*", Kind: "weight", Init: NewEyeInit()},
+		{Kind: "bias", Init: NewConstInit(1.0)},
+	}
+
+	names, err := ApplyInit(vs, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantNames := []string{"l1.bias", "l1.weight", "l2.bias"}
+	if !reflect.DeepEqual(wantNames, names) {
+		t.Errorf("want re-initialized %v, got %v\n", wantNames, names)
+	}
+
+	eye := ts.MustEye(4, gotch.DefaultDType, gotch.CPU)
+	if !l1.Ws.MustAllclose(eye, 1e-5, 1e-8, false, false) {
+		t.Errorf("want l1 weight to be identity matrix\n")
+	}
+	if l2.Ws.MustAllclose(eye, 1e-5, 1e-8, false, false) {
+		t.Errorf("want l2 weight unchanged\n")
+	}
+	for _, v := range l2.Bs.Float64Values() {
+		if math.Abs(v-1.0) > 1e-6 {
+			t.Errorf("want l2 bias values 1.0, got %v\n", v)
+		}
+	}
+
+	// Same seed, same weights.
+	seeded := []InitRule{{Kind: "weight", Init: NewKaimingUniformInit()}}
+	var ws [][]float64
+	for i := 0; i < 2; i++ {
+		ts.ManualSeed(42)
+		if _, err := ApplyInit(vs, seeded); err != nil {
+			t.Fatal(err)
+		}
+		ws = append(ws, append(l1.Ws.Float64Values(), l2.Ws.Float64Values()...))
+	}
+	if !reflect.DeepEqual(ws[0], ws[1]) {
+		t.Errorf("want same weights re-initialized with the same seed\n")
+	}
+
+	_, err = ApplyInit(vs, []InitRule{{Pattern: "[", Init: NewConstInit(0.0)}})
+	if err == nil {
+		t.Errorf("want error for malformed pattern\n")
+	}
+}
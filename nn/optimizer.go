@@ -285,6 +285,9 @@ func (opt *Optimizer) Step() error {
 	}
 	opt.stepCount += 1
 
+	// Keep pruned weights at zero.
+	opt.varstore.ApplyPruneMasks()
+
 	return nil
 }
 
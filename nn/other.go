@@ -4,26 +4,6 @@ import (
 	"github.com/sugarme/gotch/ts"
 )
 
-// Dropout:
-// ========
-
-// Dropout represents a neural network dropout layer.
-type Dropout struct {
-	dropoutProb float64
-}
-
-// NewDropout creates a new Dropout layer
-func NewDropout(p float64) *Dropout {
-	return &Dropout{
-		dropoutProb: p,
-	}
-}
-
-// ForwardT implements ModuleT for Dropout layer.
-func (d *Dropout) ForwardT(input *ts.Tensor, train bool) (retVal *ts.Tensor) {
-	return ts.MustDropout(input, d.dropoutProb, train)
-}
-
 // Parameter:
 // ==========
 
@@ -149,3 +129,83 @@ func NewMaxPool2D(kernelSize []int64, opts ...MaxPool2DOpt) *MaxPool2D {
 func (m *MaxPool2D) Forward(x *ts.Tensor) *ts.Tensor {
 	return x.MustMaxPool2d(m.Kernel, m.Stride, m.Padding, m.Dilation, m.CeilMode, false)
 }
+
+// Flatten:
+// ========
+
+// Flatten flattens a contiguous range of dims of input tensor.
+type Flatten struct {
+	StartDim int64
+	EndDim   int64
+}
+
+type FlattenOpts struct {
+	StartDim int64
+	EndDim   int64
+}
+
+type FlattenOpt func(*FlattenOpts)
+
+func OptStartDimFlatten(v int64) FlattenOpt {
+	return func(o *FlattenOpts) {
+		o.StartDim = v
+	}
+}
+
+func OptEndDimFlatten(v int64) FlattenOpt {
+	return func(o *FlattenOpts) {
+		o.EndDim = v
+	}
+}
+
+// DefaultFlattenOpts flattens all dims except the first (batch) one.
+func DefaultFlattenOpts() *FlattenOpts {
+	return &FlattenOpts{
+		StartDim: 1,
+		EndDim:   -1,
+	}
+}
+
+func NewFlatten(opts ...FlattenOpt) *Flatten {
+	o := DefaultFlattenOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &Flatten{
+		StartDim: o.StartDim,
+		EndDim:   o.EndDim,
+	}
+}
+
+func (m *Flatten) Forward(x *ts.Tensor) *ts.Tensor {
+	return x.MustFlatten(m.StartDim, m.EndDim, false)
+}
+
+func (m *Flatten) ForwardT(x *ts.Tensor, train bool) *ts.Tensor {
+	return m.Forward(x)
+}
+
+// Unflatten:
+// ==========
+
+// Unflatten expands a dim of input tensor over multiple dims of given sizes.
+type Unflatten struct {
+	Dim   int64
+	Sizes []int64
+}
+
+func NewUnflatten(dim int64, sizes []int64) *Unflatten {
+	return &Unflatten{
+		Dim:   dim,
+		Sizes: sizes,
+	}
+}
+
+func (m *Unflatten) Forward(x *ts.Tensor) *ts.Tensor {
+	return x.MustUnflatten(m.Dim, m.Sizes, false)
+}
+
+func (m *Unflatten) ForwardT(x *ts.Tensor, train bool) *ts.Tensor {
+	return m.Forward(x)
+}
@@ -0,0 +1,534 @@
+package nn
+
+// Weight pruning.
+//
+// Pruning a parameter stores a binary mask as a buffer named `<param>_mask` next to it
+// (e.g. "fc1.weight" -> "fc1.weight_mask") and multiplies the parameter by the mask in place,
+// so that every subsequent forward pass sees the pruned weights. Masks are re-applied after
+// every `Optimizer.Step()` to keep pruned weights at zero during fine-tuning.
+//
+// Example:
+//
+//	err := vs.Root().Sub("fc1").Prune("weight", nn.NewL1Unstructured(0.3))
+//	err = vs.Root().Sub("conv1").Prune("weight", nn.NewLnStructured(0.5, 2, 0))
+//	err = vs.GlobalPrune([]string{"fc1.weight", "fc2.weight"}, nn.NewL1Unstructured(0.2))
+//	fmt.Println(vs.SparsityReport())
+//	err = vs.MakePermanent()
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/ts"
+)
+
+// PruneMaskSuffix is suffix of buffer name holding pruning mask of a parameter.
+const PruneMaskSuffix = "_mask"
+
+// PruneMethod computes pruning masks.
+type PruneMethod interface {
+	// ComputeMask computes a new mask for tensor x given its current mask.
+	// Entries already pruned in the current mask stay pruned.
+	ComputeMask(x, mask *ts.Tensor) (*ts.Tensor, error)
+	// Structured reports whether the method prunes whole slices (channels/filters)
+	// rather than individual entries.
+	Structured() bool
+}
+
+// checkPruneAmount checks amount is a fraction in range [0, 1].
+func checkPruneAmount(amount float64) {
+	if amount < 0 || amount > 1 {
+		err := fmt.Errorf("pruning amount should be in range [0, 1], got %v", amount)
+		panic(err)
+	}
+}
+
+// pruneMask prunes `amount` fraction of remaining (non-zero in mask) entries with the lowest scores.
+// Scores should be non-negative and have same shape as mask.
+func pruneMask(scores, mask *ts.Tensor, amount float64) *ts.Tensor {
+	numel := int64(mask.Numel())
+	remaining := int64(mask.MustNe(ts.FloatScalar(0), false).MustSum(gotch.Int64, true).Int64Values(true)[0])
+	k := int64(math.Round(amount * float64(remaining)))
+	if k == 0 {
+		return mask.MustDetachCopy(false)
+	}
+
+	// Already pruned entries get the lowest score so that they are always selected.
+	pruned := mask.MustEq(ts.FloatScalar(0), false)
+	s := scores.MustMaskedFill(pruned, ts.FloatScalar(-1), false).MustReshape([]int64{-1}, true)
+	pruned.MustDrop()
+	_, idx := s.MustTopk(numel-remaining+k, 0, false, false, true)
+
+	newMask := mask.MustOnesLike(false).MustReshape([]int64{-1}, true)
+	newMask.MustIndexFill_(0, idx, ts.FloatScalar(0))
+	idx.MustDrop()
+
+	return newMask.MustReshape(mask.MustSize(), true)
+}
+
+// L1 unstructured:
+// ================
+
+type l1Unstructured struct {
+	amount float64
+}
+
+// NewL1Unstructured creates a pruning method that prunes `amount` fraction of remaining
+// entries with the lowest absolute values.
+func NewL1Unstructured(amount float64) PruneMethod {
+	checkPruneAmount(amount)
+	return &l1Unstructured{amount}
+}
+
+// ComputeMask implements PruneMethod interface.
+func (m *l1Unstructured) ComputeMask(x, mask *ts.Tensor) (*ts.Tensor, error) {
+	scores := x.MustAbs(false)
+	newMask := pruneMask(scores, mask, m.amount)
+	scores.MustDrop()
+	return newMask, nil
+}
+
+// Structured implements PruneMethod interface.
+func (m *l1Unstructured) Structured() bool {
+	return false
+}
+
+// Random unstructured:
+// ====================
+
+type randomUnstructured struct {
+	amount float64
+}
+
+// NewRandomUnstructured creates a pruning method that prunes `amount` fraction of remaining
+// entries selected at random.
+func NewRandomUnstructured(amount float64) PruneMethod {
+	checkPruneAmount(amount)
+	return &randomUnstructured{amount}
+}
+
+// ComputeMask implements PruneMethod interface.
+func (m *randomUnstructured) ComputeMask(x, mask *ts.Tensor) (*ts.Tensor, error) {
+	scores := x.MustRandLike(false)
+	newMask := pruneMask(scores, mask, m.amount)
+	scores.MustDrop()
+	return newMask, nil
+}
+
+// Structured implements PruneMethod interface.
+func (m *randomUnstructured) Structured() bool {
+	return false
+}
+
+// Structured:
+// ===========
+
+type structured struct {
+	amount float64
+	n      float64 // norm order. Negative means random.
+	dim    int64
+}
+
+// NewLnStructured creates a pruning method that prunes `amount` fraction of remaining slices
+// along dimension `dim` with the lowest Ln-norm, e.g. dim=0 prunes output channels (filters)
+// of a convolution weight and dim=1 prunes input channels. `n` can be `math.Inf(1)`.
+func NewLnStructured(amount float64, n float64, dim int64) PruneMethod {
+	checkPruneAmount(amount)
+	if n <= 0 {
+		err := fmt.Errorf("NewLnStructured() failed: norm order should be positive, got %v", n)
+		panic(err)
+	}
+	return &structured{amount, n, dim}
+}
+
+// NewRandomStructured creates a pruning method that prunes `amount` fraction of remaining slices
+// along dimension `dim` selected at random.
+func NewRandomStructured(amount float64, dim int64) PruneMethod {
+	checkPruneAmount(amount)
+	return &structured{amount, -1, dim}
+}
+
+// ComputeMask implements PruneMethod interface.
+func (m *structured) ComputeMask(x, mask *ts.Tensor) (*ts.Tensor, error) {
+	nd := int64(x.Dim())
+	dim := m.dim
+	if dim < 0 {
+		dim += nd
+	}
+	if dim < 0 || dim >= nd {
+		err := fmt.Errorf("structured pruning dim %v is out of range for tensor of shape %v", m.dim, x.MustSize())
+		return nil, err
+	}
+
+	var reduceDims []int64
+	for i := int64(0); i < nd; i++ {
+		if i != dim {
+			reduceDims = append(reduceDims, i)
+		}
+	}
+	size := x.MustSize()[dim]
+
+	var scores *ts.Tensor
+	switch {
+	case m.n < 0:
+		scores = ts.MustRand([]int64{size}, gotch.Float, x.MustDevice())
+	case len(reduceDims) == 0:
+		scores = x.MustAbs(false)
+	case math.IsInf(m.n, 1):
+		scores = x.MustAbs(false).MustAmax(reduceDims, false, true)
+	default:
+		scores = x.MustAbs(false).MustPowTensorScalar(ts.FloatScalar(m.n), true).MustSumDimIntlist(reduceDims, false, x.DType(), true)
+	}
+
+	// A slice is remaining if any of its entries is not pruned.
+	sliceMask := mask
+	if len(reduceDims) > 0 {
+		sliceMask = mask.MustAmax(reduceDims, false, false)
+	}
+	newSliceMask := pruneMask(scores, sliceMask, m.amount)
+	scores.MustDrop()
+	if len(reduceDims) > 0 {
+		sliceMask.MustDrop()
+	}
+
+	shape := make([]int64, nd)
+	for i := range shape {
+		shape[i] = 1
+	}
+	shape[dim] = size
+	newMask := mask.MustMul(newSliceMask.MustView(shape, true), false)
+
+	return newMask, nil
+}
+
+// Structured implements PruneMethod interface.
+func (m *structured) Structured() bool {
+	return true
+}
+
+// VarStore and Path methods:
+// ==========================
+
+// pathOf returns path of a full variable name and local name of the variable.
+func (vs *VarStore) pathOf(fullName string) (*Path, string) {
+	vs.Lock()
+	defer vs.Unlock()
+
+	parts := strings.Split(fullName, SEP)
+	return &Path{
+		path:     parts[:len(parts)-1],
+		varstore: vs,
+		group:    vs.vars[fullName].Group,
+	}, parts[len(parts)-1]
+}
+
+// prunable returns a parameter variable and its mask (nil if not pruned yet).
+func (vs *VarStore) prunable(fullName string) (*ts.Tensor, *ts.Tensor, error) {
+	vs.Lock()
+	defer vs.Unlock()
+
+	v, ok := vs.vars[fullName]
+	if !ok {
+		err := fmt.Errorf("cannot find a variable with name %q in VarStore", fullName)
+		return nil, nil, err
+	}
+	if v.Type != "parameter" {
+		err := fmt.Errorf("variable %q is a %s. Only parameters can be pruned", fullName, v.Type)
+		return nil, nil, err
+	}
+
+	var mask *ts.Tensor
+	if m, ok := vs.vars[fullName+PruneMaskSuffix]; ok {
+		mask = m.Tensor
+	}
+
+	return v.Tensor, mask, nil
+}
+
+// setPruneMask stores mask as a buffer next to the parameter and applies it to the parameter.
+func (vs *VarStore) setPruneMask(fullName string, x, mask, newMask *ts.Tensor) {
+	ts.NoGrad(func() {
+		if mask != nil {
+			mask.Copy_(newMask)
+		} else {
+			p, name := vs.pathOf(fullName)
+			NewBuffer(p, name+PruneMaskSuffix, newMask)
+		}
+		x.MustMul_(newMask)
+	})
+}
+
+// Prune prunes parameter `name` of the current path with the given method.
+//
+// Pruning can be applied iteratively, new mask is combined with the existing one.
+func (p *Path) Prune(name string, method PruneMethod) error {
+	fullName := p.getpath(name)
+	x, mask, err := p.varstore.prunable(fullName)
+	if err != nil {
+		err = fmt.Errorf("Path.Prune() failed: %w", err)
+		return err
+	}
+
+	currMask := mask
+	if mask == nil {
+		currMask = x.MustOnesLike(false).MustDetach(true)
+	}
+	detached := x.MustDetach(false)
+	newMask, err := method.ComputeMask(detached, currMask)
+	detached.MustDrop()
+	if mask == nil {
+		currMask.MustDrop()
+	}
+	if err != nil {
+		err = fmt.Errorf("Path.Prune() failed: %w", err)
+		return err
+	}
+
+	p.varstore.setPruneMask(fullName, x, mask, newMask)
+	newMask.MustDrop()
+
+	return nil
+}
+
+// MustPrune prunes parameter `name` of the current path with the given method. It panics if error occurred.
+func (p *Path) MustPrune(name string, method PruneMethod) {
+	if err := p.Prune(name, method); err != nil {
+		panic(err)
+	}
+}
+
+// IsPruned returns whether parameter `name` of the current path has a pruning mask.
+func (p *Path) IsPruned(name string) bool {
+	p.varstore.Lock()
+	defer p.varstore.Unlock()
+
+	_, ok := p.varstore.vars[p.getpath(name)+PruneMaskSuffix]
+	return ok
+}
+
+// MakePermanent bakes pruning mask into parameter `name` of the current path and removes the mask buffer.
+func (p *Path) MakePermanent(name string) error {
+	if err := p.varstore.makePermanent(p.getpath(name)); err != nil {
+		err = fmt.Errorf("Path.MakePermanent() failed: %w", err)
+		return err
+	}
+
+	return nil
+}
+
+// GlobalPrune prunes parameters with the given full names (e.g. "fc1.weight") together as if
+// they were a single tensor, i.e., `amount` fraction of all their remaining entries is pruned.
+//
+// NOTE: only unstructured methods are supported.
+func (vs *VarStore) GlobalPrune(names []string, method PruneMethod) error {
+	if method.Structured() {
+		err := fmt.Errorf("VarStore.GlobalPrune() failed: structured pruning methods are not supported")
+		return err
+	}
+
+	var (
+		xs, masks, flatXs, flatMasks []*ts.Tensor
+		sizes                        []int64
+	)
+	for _, name := range names {
+		x, mask, err := vs.prunable(name)
+		if err != nil {
+			err = fmt.Errorf("VarStore.GlobalPrune() failed: %w", err)
+			return err
+		}
+		xs = append(xs, x)
+		masks = append(masks, mask)
+		flatXs = append(flatXs, x.MustDetach(false).MustReshape([]int64{-1}, true))
+		if mask != nil {
+			flatMasks = append(flatMasks, mask.MustReshape([]int64{-1}, false))
+		} else {
+			flatMasks = append(flatMasks, x.MustOnesLike(false).MustDetach(true).MustReshape([]int64{-1}, true))
+		}
+		sizes = append(sizes, int64(x.Numel()))
+	}
+
+	allX := ts.MustCat(flatXs, 0)
+	allMask := ts.MustCat(flatMasks, 0)
+	for i := range flatXs {
+		flatXs[i].MustDrop()
+		flatMasks[i].MustDrop()
+	}
+	newMask, err := method.ComputeMask(allX, allMask)
+	allX.MustDrop()
+	allMask.MustDrop()
+	if err != nil {
+		err = fmt.Errorf("VarStore.GlobalPrune() failed: %w", err)
+		return err
+	}
+
+	newMasks := newMask.MustSplitWithSizes(sizes, 0, true)
+	for i, name := range names {
+		m := newMasks[i].MustReshape(xs[i].MustSize(), true)
+		vs.setPruneMask(name, xs[i], masks[i], m)
+		m.MustDrop()
+	}
+
+	return nil
+}
+
+// updatePruned updates names of pruned parameters and their masks. It should be called
+// with the lock held whenever a parameter or a mask buffer is added or removed.
+func (vs *VarStore) updatePruned() {
+	pruned := make(map[string]string)
+	for name, v := range vs.vars {
+		if v.Type != "buffer" || !strings.HasSuffix(name, PruneMaskSuffix) {
+			continue
+		}
+		paramName := strings.TrimSuffix(name, PruneMaskSuffix)
+		if param, ok := vs.vars[paramName]; ok && param.Type == "parameter" {
+			pruned[paramName] = name
+		}
+	}
+
+	vs.pruned = pruned
+	vs.hasPruned.Store(len(pruned) > 0)
+}
+
+// prunedVars returns names of pruned parameters and their masks. The returned map
+// should not be modified.
+func (vs *VarStore) prunedVars() map[string]string {
+	return vs.pruned
+}
+
+// ApplyPruneMasks multiplies all pruned parameters with their masks in place.
+//
+// It is called after every `Optimizer.Step()`. It should be called explicitly if parameters
+// are modified in another way, e.g. after loading weights.
+func (vs *VarStore) ApplyPruneMasks() {
+	// Cheap check without lock as it is called every optimization step.
+	if !vs.hasPruned.Load() {
+		return
+	}
+
+	vs.Lock()
+	defer vs.Unlock()
+
+	ts.NoGrad(func() {
+		for paramName, maskName := range vs.pruned {
+			vs.vars[paramName].Tensor.MustMul_(vs.vars[maskName].Tensor)
+		}
+	})
+}
+
+func (vs *VarStore) makePermanent(paramName string) error {
+	vs.Lock()
+	defer vs.Unlock()
+
+	maskName := paramName + PruneMaskSuffix
+	mask, ok := vs.vars[maskName]
+	if !ok {
+		err := fmt.Errorf("variable %q is not pruned", paramName)
+		return err
+	}
+	ts.NoGrad(func() {
+		vs.vars[paramName].Tensor.MustMul_(mask.Tensor)
+	})
+	delete(vs.vars, maskName)
+	vs.updatePruned()
+	mask.Tensor.MustDrop()
+
+	return nil
+}
+
+// MakePermanent bakes pruning masks into all pruned parameters and removes mask buffers.
+func (vs *VarStore) MakePermanent() error {
+	vs.Lock()
+	pruned := vs.prunedVars()
+	vs.Unlock()
+
+	for paramName := range pruned {
+		if err := vs.makePermanent(paramName); err != nil {
+			err = fmt.Errorf("VarStore.MakePermanent() failed: %w", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Sparsity report:
+// ================
+
+// ParamSparsity holds sparsity of a parameter.
+type ParamSparsity struct {
+	Name   string
+	Numel  int64
+	Zeros  int64
+	Pruned bool // whether parameter has a pruning mask
+}
+
+// Sparsity returns fraction of zero entries.
+func (s ParamSparsity) Sparsity() float64 {
+	if s.Numel == 0 {
+		return 0
+	}
+	return float64(s.Zeros) / float64(s.Numel)
+}
+
+// SparsityReport holds sparsity of all parameters in a VarStore.
+type SparsityReport struct {
+	Params []ParamSparsity // sorted by name
+	Numel  int64
+	Zeros  int64
+}
+
+// Sparsity returns global fraction of zero entries over all parameters.
+func (r *SparsityReport) Sparsity() float64 {
+	if r.Numel == 0 {
+		return 0
+	}
+	return float64(r.Zeros) / float64(r.Numel)
+}
+
+// String implements Stringer interface for SparsityReport.
+func (r *SparsityReport) String() string {
+	var sb strings.Builder
+	for _, p := range r.Params {
+		pruned := ""
+		if p.Pruned {
+			pruned = " [pruned]"
+		}
+		fmt.Fprintf(&sb, "%s - %d/%d zeros (%.2f%%)%s\n", p.Name, p.Zeros, p.Numel, 100*p.Sparsity(), pruned)
+	}
+	fmt.Fprintf(&sb, "Global sparsity: %d/%d zeros (%.2f%%)\n", r.Zeros, r.Numel, 100*r.Sparsity())
+
+	return sb.String()
+}
+
+// SparsityReport reports number of zero entries of all parameters (buffers are excluded).
+func (vs *VarStore) SparsityReport() *SparsityReport {
+	vs.Lock()
+	defer vs.Unlock()
+
+	pruned := vs.prunedVars()
+	report := new(SparsityReport)
+	for name, v := range vs.vars {
+		if v.Type != "parameter" {
+			continue
+		}
+		zeros := v.Tensor.MustEq(ts.FloatScalar(0), false).MustSum(gotch.Int64, true).Int64Values(true)[0]
+		_, isPruned := pruned[name]
+		p := ParamSparsity{
+			Name:   name,
+			Numel:  int64(v.Tensor.Numel()),
+			Zeros:  zeros,
+			Pruned: isPruned,
+		}
+		report.Params = append(report.Params, p)
+		report.Numel += p.Numel
+		report.Zeros += p.Zeros
+	}
+	sort.Slice(report.Params, func(i, j int) bool {
+		return report.Params[i].Name < report.Params[j].Name
+	})
+
+	return report
+}
@@ -0,0 +1,141 @@
+package nn_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	"github.com/sugarme/gotch/ts"
+)
+
+func zerosOf(x *ts.Tensor) int64 {
+	return x.MustEq(ts.FloatScalar(0), false).MustSum(gotch.Int64, true).Int64Values(true)[0]
+}
+
+func TestPrune_L1Unstructured(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	path := vs.Root().Sub("fc")
+	w := path.MustAdd("weight", ts.MustOfSlice([]float32{1, -2, 3, -4, 5, -6, 7, -8}).MustView([]int64{2, 4}, true), true)
+
+	path.MustPrune("weight", nn.NewL1Unstructured(0.25))
+	want := []float64{0, 0, 3, -4, 5, -6, 7, -8}
+	if got := w.Float64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v\n", want, got)
+	}
+	if !path.IsPruned("weight") {
+		t.Errorf("want weight pruned\n")
+	}
+
+	// Iterative pruning removes a fraction of remaining entries.
+	path.MustPrune("weight", nn.NewL1Unstructured(0.5))
+	want = []float64{0, 0, 0, 0, 0, -6, 7, -8}
+	if got := w.Float64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v\n", want, got)
+	}
+
+	mask := path.MustGet("weight_mask")
+	wantMask := []float64{0, 0, 0, 0, 0, 1, 1, 1}
+	if got := mask.Float64Values(); !reflect.DeepEqual(wantMask, got) {
+		t.Errorf("want mask %v, got %v\n", wantMask, got)
+	}
+}
+
+func TestPrune_Structured(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	conv := nn.NewConv2D(vs.Root().Sub("conv"), 3, 8, 3, nn.DefaultConv2DConfig())
+	path := vs.Root().Sub("conv")
+
+	path.MustPrune("weight", nn.NewLnStructured(0.5, 2, 0))
+	// Half of the filters are all zeros.
+	filterMax := conv.Ws.MustAbs(false).MustAmax([]int64{1, 2, 3}, false, true).Float64Values(true)
+	var prunedFilters int
+	for _, v := range filterMax {
+		if v == 0 {
+			prunedFilters++
+		}
+	}
+	if prunedFilters != 4 {
+		t.Errorf("want 4 pruned filters, got %v\n", prunedFilters)
+	}
+
+	path.MustPrune("weight", nn.NewRandomStructured(0.5, 0))
+	if got := zerosOf(conv.Ws); got != 6*3*3*3 {
+		t.Errorf("want 6 pruned filters (%v zeros), got %v zeros\n", 6*3*3*3, got)
+	}
+}
+
+func TestPrune_RandomUnstructured(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	linear := nn.NewLinear(vs.Root().Sub("fc"), 10, 10, nn.DefaultLinearConfig())
+	vs.Root().Sub("fc").MustPrune("weight", nn.NewRandomUnstructured(0.3))
+	if got := zerosOf(linear.Ws); got != 30 {
+		t.Errorf("want 30 zeros, got %v\n", got)
+	}
+}
+
+func TestPrune_GlobalAndReport(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	root := vs.Root()
+	w1 := root.Sub("fc1").MustAdd("weight", ts.MustOfSlice([]float32{1, 2, 3, 4}), true)
+	w2 := root.Sub("fc2").MustAdd("weight", ts.MustOfSlice([]float32{10, 20, 30, 40}), true)
+
+	err := vs.GlobalPrune([]string{"fc1.weight", "fc2.weight"}, nn.NewL1Unstructured(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zerosOf(w1) != 4 || zerosOf(w2) != 0 {
+		t.Errorf("want smallest entries pruned globally, got %v and %v\n", w1.Float64Values(), w2.Float64Values())
+	}
+
+	err = vs.GlobalPrune([]string{"fc1.weight"}, nn.NewLnStructured(0.5, 1, 0))
+	if err == nil {
+		t.Errorf("want error for structured global pruning\n")
+	}
+
+	report := vs.SparsityReport()
+	if len(report.Params) != 2 || report.Zeros != 4 || report.Numel != 8 || math.Abs(report.Sparsity()-0.5) > 1e-9 {
+		t.Errorf("unexpected sparsity report:\n%v", report)
+	}
+	if !report.Params[0].Pruned || report.Params[0].Name != "fc1.weight" {
+		t.Errorf("want fc1.weight reported as pruned, got %+v\n", report.Params[0])
+	}
+}
+
+func TestPrune_OptimizerStepAndMakePermanent(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	path := vs.Root().Sub("fc")
+	linear := nn.NewLinear(path, 4, 4, nn.DefaultLinearConfig())
+	path.MustPrune("weight", nn.NewL1Unstructured(0.5))
+
+	opt, err := nn.DefaultSGDConfig().Build(vs, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := ts.MustRandn([]int64{8, 4}, gotch.Float, gotch.CPU)
+	loss := linear.Forward(x).MustSum(gotch.Float, true)
+	opt.BackwardStep(loss)
+
+	// Pruned weights stay at zero after optimizer step.
+	if got := zerosOf(linear.Ws); got != 8 {
+		t.Errorf("want 8 zeros after optimizer step, got %v\n", got)
+	}
+
+	if err := vs.MakePermanent(); err != nil {
+		t.Fatal(err)
+	}
+	if path.IsPruned("weight") {
+		t.Errorf("want mask removed after MakePermanent\n")
+	}
+	if got := zerosOf(linear.Ws); got != 8 {
+		t.Errorf("want 8 zeros after MakePermanent, got %v\n", got)
+	}
+
+	// Permanently pruned weights are trained again.
+	loss = linear.Forward(x).MustSum(gotch.Float, true)
+	opt.BackwardStep(loss)
+	if got := zerosOf(linear.Ws); got != 0 {
+		t.Errorf("want no zeros after optimizer step without mask, got %v\n", got)
+	}
+}
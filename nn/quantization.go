@@ -0,0 +1,1032 @@
+package nn
+
+// Post-training quantization: observers, dynamic and static quantized layers.
+//
+// Quantized layers are backed by libtorch `quantized::` operators (fbgemm on x86,
+// qnnpack on ARM) and run on CPU only. The engine can be selected with `ts.SetQEngine()`.
+//
+// Dynamic quantization:
+//
+//	qlinear := nn.NewDynamicQuantizedLinear(linear)
+//	qlstm := nn.NewDynamicQuantizedLSTM(lstm)
+//	n, err := nn.QuantizeDynamic(model) // replaces all Linear and LSTM layers
+//
+// Static quantization:
+//
+//	n, err := nn.PrepareStatic(seq)                 // insert observers
+//	err = nn.Calibrate(seq, next)                   // record activation ranges
+//	n, err = nn.ConvertStatic(seq)                  // replace with quantized layers
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/ts"
+)
+
+// QParams holds affine quantization parameters: x = (q - ZeroPoint) * Scale.
+type QParams struct {
+	Scale     float64
+	ZeroPoint int64
+	DType     gotch.DType
+}
+
+// quantRange returns quantized value range of a quantized dtype.
+func quantRange(dtype gotch.DType, reduceRange bool) (int64, int64) {
+	var qmin, qmax int64
+	switch dtype {
+	case gotch.QUInt8:
+		qmin, qmax = 0, 255
+	case gotch.QInt8:
+		qmin, qmax = -128, 127
+	case gotch.QInt32:
+		qmin, qmax = math.MinInt32, math.MaxInt32
+	default:
+		err := fmt.Errorf("quantRange() failed: unsupported quantized dtype %v", dtype)
+		panic(err)
+	}
+
+	// Reduced range uses 7 bits to avoid overflow in fbgemm kernels.
+	if reduceRange && dtype != gotch.QInt32 {
+		qmin, qmax = qmin/2, qmax/2
+	}
+
+	return qmin, qmax
+}
+
+// CalcQParams calculates quantization parameters that map float range [min, max]
+// to the range of a quantized dtype. The range is always extended to include zero.
+//
+//   - symmetric: if true, range is made symmetric around zero (zero point is 0 for QInt8, 128 for QUInt8).
+//   - reduceRange: if true, quantized range is reduced by 1 bit.
+func CalcQParams(min, max float64, dtype gotch.DType, symmetric, reduceRange bool) QParams {
+	qmin, qmax := quantRange(dtype, reduceRange)
+	min = math.Min(min, 0)
+	max = math.Max(max, 0)
+
+	eps := 1.1920928955078125e-07 // float32 machine epsilon
+
+	var (
+		scale     float64
+		zeroPoint int64
+	)
+	if symmetric {
+		maxAbs := math.Max(-min, max)
+		scale = math.Max(maxAbs/(float64(qmax-qmin)/2), eps)
+		if dtype == gotch.QUInt8 {
+			zeroPoint = (qmin + qmax + 1) / 2
+		}
+	} else {
+		scale = math.Max((max-min)/float64(qmax-qmin), eps)
+		zeroPoint = qmin - int64(math.Round(min/scale))
+		if zeroPoint < qmin {
+			zeroPoint = qmin
+		}
+		if zeroPoint > qmax {
+			zeroPoint = qmax
+		}
+	}
+
+	return QParams{
+		Scale:     scale,
+		ZeroPoint: zeroPoint,
+		DType:     dtype,
+	}
+}
+
+// Observers:
+// ==========
+
+// Observer records statistics of tensors passing through it and
+// calculates quantization parameters from them.
+type Observer interface {
+	// Observe records statistics of the input tensor. It does not take ownership of the tensor.
+	Observe(x *ts.Tensor)
+	// QParams calculates quantization parameters from recorded statistics.
+	QParams() QParams
+}
+
+// ObserverOpts is options for observers.
+//
+//   - DType: quantized dtype. Default=QUInt8
+//   - Symmetric: symmetric quantization range. Default=false
+//   - ReduceRange: reduce quantized range by 1 bit. Default=false
+//   - Bins: number of histogram bins. Only for HistogramObserver. Default=2048
+type ObserverOpts struct {
+	DType       gotch.DType
+	Symmetric   bool
+	ReduceRange bool
+	Bins        int
+}
+
+type ObserverOpt func(*ObserverOpts)
+
+func OptDTypeObserver(v gotch.DType) ObserverOpt {
+	return func(o *ObserverOpts) {
+		o.DType = v
+	}
+}
+
+func OptSymmetricObserver(v bool) ObserverOpt {
+	return func(o *ObserverOpts) {
+		o.Symmetric = v
+	}
+}
+
+func OptReduceRangeObserver(v bool) ObserverOpt {
+	return func(o *ObserverOpts) {
+		o.ReduceRange = v
+	}
+}
+
+func OptBinsObserver(v int) ObserverOpt {
+	return func(o *ObserverOpts) {
+		o.Bins = v
+	}
+}
+
+func DefaultObserverOpts() *ObserverOpts {
+	return &ObserverOpts{
+		DType:       gotch.QUInt8,
+		Symmetric:   false,
+		ReduceRange: false,
+		Bins:        2048,
+	}
+}
+
+// tensorMinMax returns min and max values of a tensor.
+func tensorMinMax(x *ts.Tensor) (float64, float64) {
+	min := x.MustMin(false).Float64Values(true)[0]
+	max := x.MustMax(false).Float64Values(true)[0]
+	return min, max
+}
+
+// MinMaxObserver calculates quantization parameters from running min and max values.
+type MinMaxObserver struct {
+	Min  float64
+	Max  float64
+	opts *ObserverOpts
+	seen bool
+}
+
+// NewMinMaxObserver creates a new MinMaxObserver.
+func NewMinMaxObserver(opts ...ObserverOpt) *MinMaxObserver {
+	o := DefaultObserverOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &MinMaxObserver{
+		Min:  math.Inf(1),
+		Max:  math.Inf(-1),
+		opts: o,
+	}
+}
+
+// Observe implements Observer interface for MinMaxObserver.
+func (o *MinMaxObserver) Observe(x *ts.Tensor) {
+	min, max := tensorMinMax(x)
+	o.Min = math.Min(o.Min, min)
+	o.Max = math.Max(o.Max, max)
+	o.seen = true
+}
+
+// QParams implements Observer interface for MinMaxObserver.
+func (o *MinMaxObserver) QParams() QParams {
+	if !o.seen {
+		return CalcQParams(0, 0, o.opts.DType, o.opts.Symmetric, o.opts.ReduceRange)
+	}
+	return CalcQParams(o.Min, o.Max, o.opts.DType, o.opts.Symmetric, o.opts.ReduceRange)
+}
+
+// HistogramObserver records a running histogram of tensor values and calculates
+// quantization parameters by choosing the range that minimizes the L2 quantization error
+// (clipping error of outliers plus rounding error of values within range).
+type HistogramObserver struct {
+	Min       float64
+	Max       float64
+	Histogram []float64
+	opts      *ObserverOpts
+}
+
+// NewHistogramObserver creates a new HistogramObserver.
+func NewHistogramObserver(opts ...ObserverOpt) *HistogramObserver {
+	o := DefaultObserverOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Bins <= 0 {
+		err := fmt.Errorf("NewHistogramObserver() failed: number of bins should be positive, got %d", o.Bins)
+		panic(err)
+	}
+
+	return &HistogramObserver{
+		opts: o,
+	}
+}
+
+// Observe implements Observer interface for HistogramObserver.
+func (o *HistogramObserver) Observe(x *ts.Tensor) {
+	values := x.MustDetach(false).MustTo(gotch.CPU, true).Float64Values(true)
+	if len(values) == 0 {
+		return
+	}
+
+	min, max := values[0], values[0]
+	for _, v := range values {
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+
+	bins := o.opts.Bins
+	switch {
+	case o.Histogram == nil:
+		o.Min, o.Max = min, max
+		o.Histogram = make([]float64, bins)
+	case min < o.Min || max > o.Max:
+		// Expand range and redistribute existing counts by their bin centers.
+		newMin, newMax := math.Min(min, o.Min), math.Max(max, o.Max)
+		hist := make([]float64, bins)
+		oldWidth := (o.Max - o.Min) / float64(bins)
+		for i, count := range o.Histogram {
+			if count == 0 {
+				continue
+			}
+			center := o.Min + (float64(i)+0.5)*oldWidth
+			hist[histBin(center, newMin, newMax, bins)] += count
+		}
+		o.Min, o.Max, o.Histogram = newMin, newMax, hist
+	}
+
+	for _, v := range values {
+		o.Histogram[histBin(v, o.Min, o.Max, bins)]++
+	}
+}
+
+// histBin returns index of the bin that value v falls in.
+func histBin(v, min, max float64, bins int) int {
+	if max <= min {
+		return 0
+	}
+	idx := int((v - min) / (max - min) * float64(bins))
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= bins {
+		idx = bins - 1
+	}
+	return idx
+}
+
+// quantError approximates L2 quantization error when values in bins [start, end] are
+// quantized to nlevels levels and values outside are clipped.
+func (o *HistogramObserver) quantError(start, end int, nlevels float64) float64 {
+	binWidth := (o.Max - o.Min) / float64(len(o.Histogram))
+	lower := float64(start) * binWidth
+	upper := float64(end+1) * binWidth
+	dstWidth := (upper - lower) / nlevels
+	roundErr := dstWidth * dstWidth / 12
+
+	var norm float64
+	for i, count := range o.Histogram {
+		if count == 0 {
+			continue
+		}
+		center := (float64(i) + 0.5) * binWidth
+		switch {
+		case i < start:
+			norm += count * (lower - center) * (lower - center)
+		case i > end:
+			norm += count * (center - upper) * (center - upper)
+		default:
+			norm += count * roundErr
+		}
+	}
+
+	return norm
+}
+
+// QParams implements Observer interface for HistogramObserver.
+func (o *HistogramObserver) QParams() QParams {
+	if o.Histogram == nil || o.Max <= o.Min {
+		return CalcQParams(o.Min, o.Max, o.opts.DType, o.opts.Symmetric, o.opts.ReduceRange)
+	}
+
+	qmin, qmax := quantRange(o.opts.DType, o.opts.ReduceRange)
+	nlevels := float64(qmax - qmin + 1)
+	bins := len(o.Histogram)
+
+	// Greedily trim the side with less mass one bin at a time and keep the best range.
+	start, end := 0, bins-1
+	bestStart, bestEnd := start, end
+	bestNorm := o.quantError(start, end, nlevels)
+	for end > start {
+		if o.Histogram[start] <= o.Histogram[end] {
+			start++
+		} else {
+			end--
+		}
+		norm := o.quantError(start, end, nlevels)
+		if norm < bestNorm {
+			bestNorm, bestStart, bestEnd = norm, start, end
+		}
+	}
+
+	binWidth := (o.Max - o.Min) / float64(bins)
+	min := o.Min + float64(bestStart)*binWidth
+	max := o.Min + float64(bestEnd+1)*binWidth
+
+	return CalcQParams(min, max, o.opts.DType, o.opts.Symmetric, o.opts.ReduceRange)
+}
+
+// Quantization options:
+// =====================
+
+// QuantOpts is options for quantizing layers.
+//
+//   - PerChannel: quantize weights per output channel. Default=true
+//   - ReduceRange: use 7-bit activation range. Required by fbgemm engine to avoid overflow,
+//     can be disabled for qnnpack. Default=true
+//   - Observer: activation observer constructor for static quantization. Default=HistogramObserver
+type QuantOpts struct {
+	PerChannel  bool
+	ReduceRange bool
+	Observer    func(reduceRange bool) Observer
+}
+
+type QuantOpt func(*QuantOpts)
+
+func OptPerChannelQuant(v bool) QuantOpt {
+	return func(o *QuantOpts) {
+		o.PerChannel = v
+	}
+}
+
+func OptReduceRangeQuant(v bool) QuantOpt {
+	return func(o *QuantOpts) {
+		o.ReduceRange = v
+	}
+}
+
+func OptObserverQuant(v func(reduceRange bool) Observer) QuantOpt {
+	return func(o *QuantOpts) {
+		o.Observer = v
+	}
+}
+
+func DefaultQuantOpts() *QuantOpts {
+	return &QuantOpts{
+		PerChannel:  true,
+		ReduceRange: true,
+		Observer: func(reduceRange bool) Observer {
+			return NewHistogramObserver(OptReduceRangeObserver(reduceRange))
+		},
+	}
+}
+
+func newQuantOpts(opts ...QuantOpt) *QuantOpts {
+	o := DefaultQuantOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// QuantizeWeight quantizes a float weight tensor to symmetric QInt8, either per-tensor or
+// per output channel (dim 0).
+func QuantizeWeight(w *ts.Tensor, perChannel bool) *ts.Tensor {
+	w = w.MustDetach(false).MustContiguous(true)
+	defer w.MustDrop()
+
+	if !perChannel {
+		min, max := tensorMinMax(w)
+		qp := CalcQParams(min, max, gotch.QInt8, true, false)
+		return w.MustQuantizePerTensor(qp.Scale, qp.ZeroPoint, gotch.QInt8, false)
+	}
+
+	outDim := w.MustSize()[0]
+	maxAbs := w.MustReshape([]int64{outDim, -1}, false).MustAbs(true).MustAmax([]int64{1}, false, true).Float64Values(true)
+	scales := make([]float64, outDim)
+	zeroPoints := make([]int64, outDim)
+	for i, v := range maxAbs {
+		qp := CalcQParams(-v, v, gotch.QInt8, true, false)
+		scales[i] = qp.Scale
+		zeroPoints[i] = qp.ZeroPoint
+	}
+	scalesTs := ts.MustOfSlice(scales)
+	zeroPointsTs := ts.MustOfSlice(zeroPoints)
+	retVal := w.MustQuantizePerChannel(scalesTs, zeroPointsTs, 0, gotch.QInt8, false)
+	scalesTs.MustDrop()
+	zeroPointsTs.MustDrop()
+
+	return retVal
+}
+
+// linearWeight returns weight of a Linear layer in [outDim, inDim] layout.
+func linearWeight(l *Linear) *ts.Tensor {
+	return l.Ws.MustT(false)
+}
+
+// Dynamic quantization:
+// =====================
+
+// DynamicQuantizedLinear is a linear layer with int8 weights. Activations are quantized
+// on the fly, inputs and outputs are float tensors.
+type DynamicQuantizedLinear struct {
+	Packed      *ts.PackedParams
+	ReduceRange bool
+}
+
+// NewDynamicQuantizedLinear creates a dynamically quantized linear layer from a float Linear layer.
+func NewDynamicQuantizedLinear(l *Linear, opts ...QuantOpt) *DynamicQuantizedLinear {
+	o := newQuantOpts(opts...)
+	ws := linearWeight(l)
+	qws := QuantizeWeight(ws, o.PerChannel)
+	packed := ts.MustQLinearPrepack(qws, l.Bs)
+	ws.MustDrop()
+	qws.MustDrop()
+
+	return &DynamicQuantizedLinear{
+		Packed:      packed,
+		ReduceRange: o.ReduceRange,
+	}
+}
+
+// Forward implements Module interface for DynamicQuantizedLinear.
+func (l *DynamicQuantizedLinear) Forward(x *ts.Tensor) *ts.Tensor {
+	return x.MustQLinearDynamic(l.Packed, l.ReduceRange, false)
+}
+
+// ForwardT implements ModuleT interface for DynamicQuantizedLinear.
+//
+// NOTE: train param will not be used.
+func (l *DynamicQuantizedLinear) ForwardT(x *ts.Tensor, train bool) *ts.Tensor {
+	return l.Forward(x)
+}
+
+// DynamicQuantizedLSTM is a LSTM layer with int8 weights used for inference.
+// Activations are quantized on the fly, inputs and outputs are float tensors.
+//
+// NOTE: dropout between layers is not applied.
+type DynamicQuantizedLSTM struct {
+	packedIh    []*ts.PackedParams // per layer and direction
+	packedHh    []*ts.PackedParams
+	hiddenDim   int64
+	config      *RNNConfig
+	reduceRange bool
+}
+
+// NewDynamicQuantizedLSTM creates a dynamically quantized LSTM layer from a float LSTM layer.
+func NewDynamicQuantizedLSTM(l *LSTM, opts ...QuantOpt) *DynamicQuantizedLSTM {
+	o := newQuantOpts(opts...)
+
+	// NOTE. NewLSTM creates (wIh, wHh, bIh, bHh) per layer and direction regardless of HasBiases.
+	var packedIh, packedHh []*ts.PackedParams
+	for i := 0; i+4 <= len(l.flatWeights); i += 4 {
+		var bIh, bHh *ts.Tensor
+		if l.config.HasBiases {
+			bIh, bHh = l.flatWeights[i+2], l.flatWeights[i+3]
+		}
+		qwIh := QuantizeWeight(l.flatWeights[i], o.PerChannel)
+		qwHh := QuantizeWeight(l.flatWeights[i+1], o.PerChannel)
+		packedIh = append(packedIh, ts.MustQLinearPrepack(qwIh, bIh))
+		packedHh = append(packedHh, ts.MustQLinearPrepack(qwHh, bHh))
+		qwIh.MustDrop()
+		qwHh.MustDrop()
+	}
+
+	return &DynamicQuantizedLSTM{
+		packedIh:    packedIh,
+		packedHh:    packedHh,
+		hiddenDim:   l.hiddenDim,
+		config:      l.config,
+		reduceRange: o.ReduceRange,
+	}
+}
+
+func (l *DynamicQuantizedLSTM) numDirections() int64 {
+	if l.config.Bidirectional {
+		return 2
+	}
+	return 1
+}
+
+// ZeroState implements RNN interface for DynamicQuantizedLSTM.
+func (l *DynamicQuantizedLSTM) ZeroState(batchDim int64) State {
+	shape := []int64{l.config.NumLayers * l.numDirections(), batchDim, l.hiddenDim}
+	return &LSTMState{
+		Tensor1: ts.MustZeros(shape, gotch.Float, gotch.CPU),
+		Tensor2: ts.MustZeros(shape, gotch.Float, gotch.CPU),
+	}
+}
+
+// Step implements RNN interface for DynamicQuantizedLSTM.
+func (l *DynamicQuantizedLSTM) Step(input *ts.Tensor, inState State) State {
+	ip := input.MustUnsqueeze(1, false)
+	output, state := l.SeqInit(ip, inState)
+	ip.MustDrop()
+	output.MustDrop()
+
+	return state
+}
+
+// Seq implements RNN interface for DynamicQuantizedLSTM.
+func (l *DynamicQuantizedLSTM) Seq(input *ts.Tensor) (*ts.Tensor, State) {
+	batchDim := input.MustSize()[0]
+	if !l.config.BatchFirst {
+		batchDim = input.MustSize()[1]
+	}
+	inState := l.ZeroState(batchDim)
+	output, state := l.SeqInit(input, inState)
+	inState.(*LSTMState).Tensor1.MustDrop()
+	inState.(*LSTMState).Tensor2.MustDrop()
+
+	return output, state
+}
+
+// cell applies a single LSTM step with gate order (input, forget, cell, output).
+func (l *DynamicQuantizedLSTM) cell(x, h, c *ts.Tensor, idx int) (*ts.Tensor, *ts.Tensor) {
+	gi := x.MustQLinearDynamic(l.packedIh[idx], l.reduceRange, false)
+	gh := h.MustQLinearDynamic(l.packedHh[idx], l.reduceRange, false)
+	gates := gi.MustAdd(gh, true)
+	gh.MustDrop()
+	chunks := gates.MustChunk(4, 1, true)
+	inGate := chunks[0].MustSigmoid(true)
+	forgetGate := chunks[1].MustSigmoid(true)
+	cellGate := chunks[2].MustTanh(true)
+	outGate := chunks[3].MustSigmoid(true)
+
+	fc := forgetGate.MustMul(c, true)
+	ig := inGate.MustMul(cellGate, true)
+	cellGate.MustDrop()
+	cNew := fc.MustAdd(ig, true)
+	ig.MustDrop()
+	hNew := cNew.MustTanh(false).MustMul(outGate, true)
+	outGate.MustDrop()
+
+	return hNew, cNew
+}
+
+// SeqInit implements RNN interface for DynamicQuantizedLSTM.
+func (l *DynamicQuantizedLSTM) SeqInit(input *ts.Tensor, inState State) (*ts.Tensor, State) {
+	// Work in [seqLen, batch, features] layout.
+	var layerInput *ts.Tensor
+	if l.config.BatchFirst {
+		layerInput = input.MustTranspose(0, 1, false)
+	} else {
+		layerInput = input.MustShallowClone()
+	}
+	seqLen := layerInput.MustSize()[0]
+
+	h0 := inState.(*LSTMState).Tensor1
+	c0 := inState.(*LSTMState).Tensor2
+	numDirections := l.numDirections()
+	var hs, cs []*ts.Tensor
+	for layer := int64(0); layer < l.config.NumLayers; layer++ {
+		var dirOutputs []*ts.Tensor
+		for dir := int64(0); dir < numDirections; dir++ {
+			idx := layer*numDirections + dir
+			h := h0.MustSelect(0, idx, false)
+			c := c0.MustSelect(0, idx, false)
+			outputs := make([]*ts.Tensor, seqLen)
+			for step := int64(0); step < seqLen; step++ {
+				t := step
+				if dir == 1 {
+					t = seqLen - 1 - step
+				}
+				x := layerInput.MustSelect(0, t, false)
+				hNew, cNew := l.cell(x, h, c, int(idx))
+				x.MustDrop()
+				h.MustDrop()
+				c.MustDrop()
+				h, c = hNew, cNew
+				outputs[t] = h.MustShallowClone()
+			}
+			dirOutputs = append(dirOutputs, ts.MustStack(outputs, 0))
+			for _, o := range outputs {
+				o.MustDrop()
+			}
+			hs = append(hs, h)
+			cs = append(cs, c)
+		}
+		layerInput.MustDrop()
+		layerInput = ts.MustCat(dirOutputs, 2)
+		for _, o := range dirOutputs {
+			o.MustDrop()
+		}
+	}
+
+	output := layerInput
+	if l.config.BatchFirst {
+		output = layerInput.MustTranspose(0, 1, true)
+	}
+	hn := ts.MustStack(hs, 0)
+	cn := ts.MustStack(cs, 0)
+	for i := range hs {
+		hs[i].MustDrop()
+		cs[i].MustDrop()
+	}
+
+	return output, &LSTMState{
+		Tensor1: hn,
+		Tensor2: cn,
+	}
+}
+
+// Static quantization:
+// ====================
+
+// quantizeInput quantizes float input with given quantization parameters.
+// Quantized input is returned as is.
+func quantizeInput(x *ts.Tensor, qp QParams) (*ts.Tensor, bool) {
+	if x.DType() == qp.DType {
+		return x, false
+	}
+	return x.MustQuantizePerTensor(qp.Scale, qp.ZeroPoint, qp.DType, false), true
+}
+
+// QuantizedLinear is a linear layer with int8 weights and quint8 activations.
+//
+// Float inputs are quantized with input quantization parameters and outputs are
+// dequantized to float tensors so that it can be mixed with float layers.
+type QuantizedLinear struct {
+	Packed     *ts.PackedParams
+	InQParams  QParams
+	OutQParams QParams
+}
+
+// NewQuantizedLinear creates a statically quantized linear layer from a float Linear layer
+// and quantization parameters of its input and output activations.
+func NewQuantizedLinear(l *Linear, in, out QParams, opts ...QuantOpt) *QuantizedLinear {
+	o := newQuantOpts(opts...)
+	ws := linearWeight(l)
+	qws := QuantizeWeight(ws, o.PerChannel)
+	packed := ts.MustQLinearPrepack(qws, l.Bs)
+	ws.MustDrop()
+	qws.MustDrop()
+
+	return &QuantizedLinear{
+		Packed:     packed,
+		InQParams:  in,
+		OutQParams: out,
+	}
+}
+
+// Forward implements Module interface for QuantizedLinear.
+func (l *QuantizedLinear) Forward(x *ts.Tensor) *ts.Tensor {
+	qx, created := quantizeInput(x, l.InQParams)
+	qy := qx.MustQLinear(l.Packed, l.OutQParams.Scale, l.OutQParams.ZeroPoint, created)
+	return qy.MustDequantize(true)
+}
+
+// ForwardT implements ModuleT interface for QuantizedLinear.
+//
+// NOTE: train param will not be used.
+func (l *QuantizedLinear) ForwardT(x *ts.Tensor, train bool) *ts.Tensor {
+	return l.Forward(x)
+}
+
+// QuantizedConv2D is a 2D convolution layer with int8 weights and quint8 activations.
+//
+// Float inputs are quantized with input quantization parameters and outputs are
+// dequantized to float tensors so that it can be mixed with float layers.
+type QuantizedConv2D struct {
+	Packed     *ts.PackedParams
+	InQParams  QParams
+	OutQParams QParams
+}
+
+// NewQuantizedConv2D creates a statically quantized conv2d layer from a float Conv2D layer
+// and quantization parameters of its input and output activations.
+//
+// NOTE: only "zeros" padding mode with symmetric padding is supported.
+func NewQuantizedConv2D(c *Conv2D, in, out QParams, opts ...QuantOpt) *QuantizedConv2D {
+	o := newQuantOpts(opts...)
+	cfg := c.Config
+	if cfg.PaddingMode != "" && cfg.PaddingMode != "zeros" {
+		err := fmt.Errorf("NewQuantizedConv2D() failed: unsupported padding mode %q", cfg.PaddingMode)
+		panic(err)
+	}
+
+	padding := cfg.Padding
+	if cfg.PaddingStr != "" {
+		ksizes := c.Ws.MustSize()[2:]
+		pad, symmetric := convPadding(ksizes, cfg.Padding, cfg.Dilation, cfg.PaddingStr)
+		if !symmetric {
+			err := fmt.Errorf("NewQuantizedConv2D() failed: asymmetric %q padding is not supported", cfg.PaddingStr)
+			panic(err)
+		}
+		padding = []int64{pad[2], pad[0]}
+	}
+
+	qws := QuantizeWeight(c.Ws, o.PerChannel)
+	packed := ts.MustQConv2dPrepack(qws, c.Bs, cfg.Stride, padding, cfg.Dilation, cfg.Groups)
+	qws.MustDrop()
+
+	return &QuantizedConv2D{
+		Packed:     packed,
+		InQParams:  in,
+		OutQParams: out,
+	}
+}
+
+// Forward implements Module interface for QuantizedConv2D.
+func (c *QuantizedConv2D) Forward(x *ts.Tensor) *ts.Tensor {
+	qx, created := quantizeInput(x, c.InQParams)
+	qy := qx.MustQConv2d(c.Packed, c.OutQParams.Scale, c.OutQParams.ZeroPoint, created)
+	return qy.MustDequantize(true)
+}
+
+// ForwardT implements ModuleT interface for QuantizedConv2D.
+//
+// NOTE: train param will not be used.
+func (c *QuantizedConv2D) ForwardT(x *ts.Tensor, train bool) *ts.Tensor {
+	return c.Forward(x)
+}
+
+// ObservedLinear wraps a float Linear layer and records its input and output activations
+// during calibration.
+type ObservedLinear struct {
+	*Linear
+	InObserver  Observer
+	OutObserver Observer
+	opts        *QuantOpts
+}
+
+// NewObservedLinear creates an observed linear layer.
+func NewObservedLinear(l *Linear, opts ...QuantOpt) *ObservedLinear {
+	o := newQuantOpts(opts...)
+	return &ObservedLinear{
+		Linear:      l,
+		InObserver:  o.Observer(o.ReduceRange),
+		OutObserver: o.Observer(o.ReduceRange),
+		opts:        o,
+	}
+}
+
+// Forward implements Module interface for ObservedLinear.
+func (l *ObservedLinear) Forward(x *ts.Tensor) *ts.Tensor {
+	l.InObserver.Observe(x)
+	y := l.Linear.Forward(x)
+	l.OutObserver.Observe(y)
+	return y
+}
+
+// ForwardT implements ModuleT interface for ObservedLinear.
+func (l *ObservedLinear) ForwardT(x *ts.Tensor, train bool) *ts.Tensor {
+	return l.Forward(x)
+}
+
+// Convert creates a quantized linear layer from observed statistics.
+func (l *ObservedLinear) Convert() *QuantizedLinear {
+	return NewQuantizedLinear(l.Linear, l.InObserver.QParams(), l.OutObserver.QParams(), OptPerChannelQuant(l.opts.PerChannel))
+}
+
+// ObservedConv2D wraps a float Conv2D layer and records its input and output activations
+// during calibration.
+type ObservedConv2D struct {
+	*Conv2D
+	InObserver  Observer
+	OutObserver Observer
+	opts        *QuantOpts
+}
+
+// NewObservedConv2D creates an observed conv2d layer.
+func NewObservedConv2D(c *Conv2D, opts ...QuantOpt) *ObservedConv2D {
+	o := newQuantOpts(opts...)
+	return &ObservedConv2D{
+		Conv2D:      c,
+		InObserver:  o.Observer(o.ReduceRange),
+		OutObserver: o.Observer(o.ReduceRange),
+		opts:        o,
+	}
+}
+
+// Forward implements Module interface for ObservedConv2D.
+func (c *ObservedConv2D) Forward(x *ts.Tensor) *ts.Tensor {
+	c.InObserver.Observe(x)
+	y := c.Conv2D.Forward(x)
+	c.OutObserver.Observe(y)
+	return y
+}
+
+// ForwardT implements ModuleT interface for ObservedConv2D.
+func (c *ObservedConv2D) ForwardT(x *ts.Tensor, train bool) *ts.Tensor {
+	return c.Forward(x)
+}
+
+// Convert creates a quantized conv2d layer from observed statistics.
+func (c *ObservedConv2D) Convert() *QuantizedConv2D {
+	return NewQuantizedConv2D(c.Conv2D, c.InObserver.QParams(), c.OutObserver.QParams(), OptPerChannelQuant(c.opts.PerChannel))
+}
+
+// Calibrate runs model in eval mode over input batches returned by next until it returns
+// false so that observers inserted by PrepareStatic record activation statistics.
+// Input batches are dropped after use.
+//
+// E.g. with a `dutil.DataLoader` of `*ts.Tensor` batches:
+//
+//	next := func() (*ts.Tensor, bool) {
+//		if !loader.HasNext() {
+//			return nil, false
+//		}
+//		batch, err := loader.Next()
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		return batch.(*ts.Tensor), true
+//	}
+func Calibrate(model ts.ModuleT, next func() (*ts.Tensor, bool)) error {
+	if next == nil {
+		err := fmt.Errorf("Calibrate() failed: nil batch function")
+		return err
+	}
+
+	ts.NoGrad(func() {
+		for {
+			x, ok := next()
+			if !ok {
+				return
+			}
+			out := model.ForwardT(x, false)
+			out.MustDrop()
+			x.MustDrop()
+		}
+	})
+
+	return nil
+}
+
+// Model-level passes:
+// ===================
+
+// mapLayers replaces layers of a Sequential, SequentialT or model struct (recursively) with
+// results of fn. If fn returns nil, the layer is kept. It returns number of replaced layers.
+func mapLayers(model interface{}, fn func(layer interface{}) interface{}) (int, error) {
+	n := 0
+	switch m := model.(type) {
+	case *Sequential:
+		for i, l := range m.layers {
+			if isSequential(l) {
+				count, err := mapLayers(l, fn)
+				if err != nil {
+					return n, err
+				}
+				n += count
+				continue
+			}
+			if nl := fn(l); nl != nil {
+				m.layers[i] = nl.(ts.Module)
+				n++
+			}
+		}
+	case *SequentialT:
+		for i, l := range m.layers {
+			if isSequential(l) {
+				count, err := mapLayers(l, fn)
+				if err != nil {
+					return n, err
+				}
+				n += count
+				continue
+			}
+			if nl := fn(l); nl != nil {
+				m.layers[i] = nl.(ts.ModuleT)
+				n++
+			}
+		}
+	default:
+		v := reflect.ValueOf(model)
+		if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			err := fmt.Errorf("unsupported model type %T. Model should be '*nn.Sequential', '*nn.SequentialT' or a pointer to a model struct", model)
+			return n, err
+		}
+		return mapFields(v, fn, make(map[uintptr]bool))
+	}
+
+	return n, nil
+}
+
+// mapFields replaces layers held by exported fields of interface type (e.g. `ts.Module`,
+// `nn.RNN`) of a model struct with results of fn if assignable. It recurses into nested
+// Sequential, SequentialT and struct pointers. Fields of concrete layer types (e.g. `*nn.Linear`)
+// can not be replaced.
+func mapFields(ptr reflect.Value, fn func(layer interface{}) interface{}, visited map[uintptr]bool) (int, error) {
+	n := 0
+	if visited[ptr.Pointer()] {
+		return n, nil
+	}
+	visited[ptr.Pointer()] = true
+
+	v := ptr.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if !v.Type().Field(i).IsExported() || (f.Kind() != reflect.Interface && f.Kind() != reflect.Ptr) || f.IsNil() {
+			continue
+		}
+
+		layer := f.Interface()
+		if isSequential(layer) {
+			count, err := mapLayers(layer, fn)
+			if err != nil {
+				return n, err
+			}
+			n += count
+			continue
+		}
+		if f.Kind() == reflect.Interface {
+			if nl := fn(layer); nl != nil && reflect.TypeOf(nl).AssignableTo(f.Type()) {
+				f.Set(reflect.ValueOf(nl))
+				n++
+				continue
+			}
+		}
+
+		lv := reflect.ValueOf(layer)
+		if lv.Kind() == reflect.Ptr && lv.Elem().Kind() == reflect.Struct {
+			count, err := mapFields(lv, fn, visited)
+			if err != nil {
+				return n, err
+			}
+			n += count
+		}
+	}
+
+	return n, nil
+}
+
+func isSequential(layer interface{}) bool {
+	switch layer.(type) {
+	case *Sequential, *SequentialT:
+		return true
+	}
+	return false
+}
+
+// QuantizeDynamic replaces all Linear and LSTM layers of a model (recursively) with
+// DynamicQuantizedLinear and DynamicQuantizedLSTM layers. It returns number of replaced layers.
+//
+// Model is a Sequential, SequentialT or a pointer to a model struct. Layers of a model struct
+// are replaced if held by exported fields of interface type, e.g. `ts.Module` for Linear
+// and `nn.RNN` for LSTM.
+func QuantizeDynamic(model interface{}, opts ...QuantOpt) (int, error) {
+	n, err := mapLayers(model, func(layer interface{}) interface{} {
+		switch l := layer.(type) {
+		case *Linear:
+			return NewDynamicQuantizedLinear(l, opts...)
+		case *LSTM:
+			return NewDynamicQuantizedLSTM(l, opts...)
+		}
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("QuantizeDynamic() failed: %w", err)
+		return n, err
+	}
+
+	return n, nil
+}
+
+// PrepareStatic replaces all Linear and Conv2D layers of a model (see QuantizeDynamic)
+// (recursively) with observed layers. It returns number of replaced layers.
+//
+// The model should then be calibrated (see Calibrate) and converted (see ConvertStatic).
+func PrepareStatic(model interface{}, opts ...QuantOpt) (int, error) {
+	n, err := mapLayers(model, func(layer interface{}) interface{} {
+		switch l := layer.(type) {
+		case *Linear:
+			return NewObservedLinear(l, opts...)
+		case *Conv2D:
+			return NewObservedConv2D(l, opts...)
+		}
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("PrepareStatic() failed: %w", err)
+		return n, err
+	}
+
+	return n, nil
+}
+
+// ConvertStatic replaces all observed layers of a model (see QuantizeDynamic)
+// (recursively) with quantized layers. It returns number of replaced layers.
+func ConvertStatic(model interface{}) (int, error) {
+	n, err := mapLayers(model, func(layer interface{}) interface{} {
+		switch l := layer.(type) {
+		case *ObservedLinear:
+			return l.Convert()
+		case *ObservedConv2D:
+			return l.Convert()
+		}
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("ConvertStatic() failed: %w", err)
+		return n, err
+	}
+
+	return n, nil
+}
@@ -0,0 +1,191 @@
+package nn_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	"github.com/sugarme/gotch/ts"
+)
+
+// maxAbsDiff returns max absolute difference between 2 tensors.
+func maxAbsDiff(a, b *ts.Tensor) float64 {
+	return a.MustSub(b, false).MustAbs(true).MustMax(true).Float64Values(true)[0]
+}
+
+func TestCalcQParams(t *testing.T) {
+	tests := []struct {
+		name        string
+		min, max    float64
+		dtype       gotch.DType
+		symmetric   bool
+		reduceRange bool
+		scale       float64
+		zeroPoint   int64
+	}{
+		{"affine quint8", -1, 3, gotch.QUInt8, false, false, 4.0 / 255, 64},
+		{"affine positive range", 1, 2.55, gotch.QUInt8, false, false, 0.01, 0},
+		{"symmetric qint8", -2, 1, gotch.QInt8, true, false, 2 / 127.5, 0},
+		{"symmetric quint8", -1, 1, gotch.QUInt8, true, false, 1 / 127.5, 128},
+		{"reduce range", 0, 1.27, gotch.QUInt8, false, true, 0.01, 0},
+	}
+
+	for _, tt := range tests {
+		qp := nn.CalcQParams(tt.min, tt.max, tt.dtype, tt.symmetric, tt.reduceRange)
+		if math.Abs(qp.Scale-tt.scale) > 1e-9 || qp.ZeroPoint != tt.zeroPoint {
+			t.Errorf("%s - want scale %v, zero point %v, got %v, %v\n", tt.name, tt.scale, tt.zeroPoint, qp.Scale, qp.ZeroPoint)
+		}
+	}
+}
+
+func TestObservers(t *testing.T) {
+	x := ts.MustRandn([]int64{100000}, gotch.Float, gotch.CPU)
+	min := x.MustMin(false).Float64Values(true)[0]
+	max := x.MustMax(false).Float64Values(true)[0]
+
+	minMax := nn.NewMinMaxObserver()
+	minMax.Observe(x)
+	want := nn.CalcQParams(min, max, gotch.QUInt8, false, false)
+	if got := minMax.QParams(); math.Abs(got.Scale-want.Scale) > 1e-9 || got.ZeroPoint != want.ZeroPoint {
+		t.Errorf("MinMaxObserver - want %+v, got %+v\n", want, got)
+	}
+
+	// Histogram observer clips the tails to reduce rounding error of the bulk.
+	hist := nn.NewHistogramObserver()
+	chunks := x.MustChunk(4, 0, false)
+	for _, chunk := range chunks {
+		hist.Observe(chunk)
+	}
+	if got := hist.QParams(); got.Scale >= want.Scale {
+		t.Errorf("HistogramObserver - want scale smaller than %v, got %v\n", want.Scale, got.Scale)
+	}
+}
+
+func TestDynamicQuantizedLinear(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	linear := nn.NewLinear(vs.Root(), 16, 8, nn.DefaultLinearConfig())
+	x := ts.MustRandn([]int64{4, 16}, gotch.Float, gotch.CPU)
+
+	want := linear.Forward(x)
+	got := nn.NewDynamicQuantizedLinear(linear).Forward(x)
+	if diff := maxAbsDiff(want, got); diff > 0.1 {
+		t.Errorf("want quantized output close to float output, got max diff %v\n", diff)
+	}
+}
+
+func TestDynamicQuantizedLSTM(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultRNNConfig()
+	cfg.NumLayers = 2
+	cfg.Bidirectional = true
+	lstm := nn.NewLSTM(vs.Root(), 8, 6, cfg)
+	x := ts.MustRandn([]int64{3, 5, 8}, gotch.Float, gotch.CPU)
+
+	want, wantState := lstm.Seq(x)
+	got, gotState := nn.NewDynamicQuantizedLSTM(lstm).Seq(x)
+	if diff := maxAbsDiff(want, got); diff > 0.1 {
+		t.Errorf("want quantized output close to float output, got max diff %v\n", diff)
+	}
+	if diff := maxAbsDiff(wantState.(*nn.LSTMState).C(), gotState.(*nn.LSTMState).C()); diff > 0.1 {
+		t.Errorf("want quantized cell state close to float cell state, got max diff %v\n", diff)
+	}
+
+	// Without biases: same weights as lstm, whose biases are zeros.
+	noBiasVs := nn.NewVarStore(gotch.CPU)
+	noBiasCfg := nn.DefaultRNNConfig()
+	noBiasCfg.NumLayers = 2
+	noBiasCfg.Bidirectional = true
+	noBiasCfg.HasBiases = false
+	noBias := nn.NewLSTM(noBiasVs.Root(), 8, 6, noBiasCfg)
+	if err := noBiasVs.Copy(vs); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = nn.NewDynamicQuantizedLSTM(noBias).Seq(x)
+	if diff := maxAbsDiff(want, got); diff > 0.1 {
+		t.Errorf("HasBiases=false: want quantized output close to float output, got max diff %v\n", diff)
+	}
+}
+
+type lstmClassifier struct {
+	LSTM nn.RNN
+	FC   ts.Module
+}
+
+func (m *lstmClassifier) Forward(x *ts.Tensor) *ts.Tensor {
+	out, state := m.LSTM.Seq(x)
+	out.MustDrop()
+	h := state.(*nn.LSTMState).H()
+	last := h.MustSelect(0, -1, false)
+	return m.FC.Forward(last)
+}
+
+func TestQuantizeDynamic(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	model := &lstmClassifier{
+		LSTM: nn.NewLSTM(vs.Root().Sub("lstm"), 8, 6, nn.DefaultRNNConfig()),
+		FC:   nn.NewLinear(vs.Root().Sub("fc"), 6, 3, nn.DefaultLinearConfig()),
+	}
+	x := ts.MustRandn([]int64{3, 5, 8}, gotch.Float, gotch.CPU)
+	want := model.Forward(x)
+
+	n, err := nn.QuantizeDynamic(model)
+	if err != nil || n != 2 {
+		t.Fatalf("want 2 quantized layers, got %v (err: %v)\n", n, err)
+	}
+	if _, ok := model.LSTM.(*nn.DynamicQuantizedLSTM); !ok {
+		t.Errorf("want DynamicQuantizedLSTM, got %T\n", model.LSTM)
+	}
+	if _, ok := model.FC.(*nn.DynamicQuantizedLinear); !ok {
+		t.Errorf("want DynamicQuantizedLinear, got %T\n", model.FC)
+	}
+
+	got := model.Forward(x)
+	if diff := maxAbsDiff(want, got); diff > 0.1 {
+		t.Errorf("want quantized output close to float output, got max diff %v\n", diff)
+	}
+}
+
+func TestStaticQuantization(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	seq := nn.SeqT()
+	seq.Add(nn.NewConv2D(vs.Root().Sub("conv"), 3, 4, 3, nn.NewConv2DConfig(nn.WithPaddingStr2D("same"))))
+	seq.AddFn(nn.NewFunc(func(x *ts.Tensor) *ts.Tensor { return x.MustRelu(false) }))
+	seq.Add(nn.NewFlatten())
+	seq.Add(nn.NewLinear(vs.Root().Sub("fc"), 4*6*6, 10, nn.DefaultLinearConfig()))
+
+	var samples []*ts.Tensor
+	for i := 0; i < 16; i++ {
+		samples = append(samples, ts.MustRand([]int64{3, 6, 6}, gotch.Float, gotch.CPU))
+	}
+	x := ts.MustStack(samples[:4], 0)
+	want := seq.ForwardT(x, false)
+
+	n, err := nn.PrepareStatic(seq)
+	if err != nil || n != 2 {
+		t.Fatalf("PrepareStatic() - want 2 observed layers, got %v (err: %v)\n", n, err)
+	}
+
+	i := 0
+	next := func() (*ts.Tensor, bool) {
+		if i >= len(samples) {
+			return nil, false
+		}
+		batch := ts.MustStack(samples[i:i+4], 0)
+		i += 4
+		return batch, true
+	}
+	if err := nn.Calibrate(seq, next); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err = nn.ConvertStatic(seq)
+	if err != nil || n != 2 {
+		t.Fatalf("ConvertStatic() - want 2 quantized layers, got %v (err: %v)\n", n, err)
+	}
+
+	got := seq.ForwardT(x, false)
+	if diff := maxAbsDiff(want, got); diff > 0.2 {
+		t.Errorf("want quantized output close to float output, got max diff %v\n", diff)
+	}
+}
@@ -30,6 +30,15 @@ func (s *Sequential) IsEmpty() (retVal bool) {
 	return len(s.layers) == 0
 }
 
+// Modules returns sub-layers in the order they are applied.
+func (s *Sequential) Modules() []interface{} {
+	modules := make([]interface{}, len(s.layers))
+	for i, l := range s.layers {
+		modules[i] = l
+	}
+	return modules
+}
+
 // Add appends a layer after all the current layers.
 func (s *Sequential) Add(l ts.Module) {
 
@@ -155,6 +164,15 @@ func (s *SequentialT) ForwardT(xs *ts.Tensor, train bool) *ts.Tensor {
 	panic("Shouldn't reached here.")
 }
 
+// Modules returns sub-layers in the order they are applied.
+func (s *SequentialT) Modules() []interface{} {
+	modules := make([]interface{}, len(s.layers))
+	for i, l := range s.layers {
+		modules[i] = l
+	}
+	return modules
+}
+
 // Add appends a layer after all the current layers.
 func (s *SequentialT) Add(l ts.ModuleT) {
 	s.layers = append(s.layers, l)
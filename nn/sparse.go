@@ -3,15 +3,29 @@ package nn
 // Sparse layers
 
 import (
+	"fmt"
+	"log"
+
+	"github.com/sugarme/gotch"
 	"github.com/sugarme/gotch/ts"
 )
 
 // Configuration option for an embedding layer.
+//
+//   - PaddingIdx: if non-negative, entries at this index do not contribute to the gradient
+//     and the embedding vector at this index is initialized to zeros. Negative value means no padding index.
+//   - MaxNorm: if > 0, each embedding vector looked up with norm larger than MaxNorm
+//     is renormalized (in-place) to have norm MaxNorm.
+//   - NormType: the p of the p-norm to compute for the MaxNorm option. Default=2.0
+//   - Freeze: if true, embedding weight is not updated in the learning process.
 type EmbeddingConfig struct {
 	Sparse          bool
 	ScaleGradByFreq bool
 	WsInit          Init
 	PaddingIdx      int64
+	MaxNorm         float64
+	NormType        float64
+	Freeze          bool
 }
 
 func DefaultEmbeddingConfig() *EmbeddingConfig {
@@ -20,6 +34,9 @@ func DefaultEmbeddingConfig() *EmbeddingConfig {
 		ScaleGradByFreq: false,
 		WsInit:          NewRandnInit(0.0, 1.0),
 		PaddingIdx:      -1,
+		MaxNorm:         0.0,
+		NormType:        2.0,
+		Freeze:          false,
 	}
 }
 
@@ -34,21 +51,239 @@ type Embedding struct {
 
 // NewEmbedding creates a new Embedding
 func NewEmbedding(vs *Path, numEmbeddings int64, embeddingDim int64, config *EmbeddingConfig) *Embedding {
+	dims := []int64{numEmbeddings, embeddingDim}
+	x := config.WsInit.InitTensor(dims, vs.Device())
+	ws := newEmbeddingWeight(vs, x, config.PaddingIdx, config.Freeze)
+	x.MustDrop()
+
 	return &Embedding{
-		Ws:     vs.MustNewVar("weight", []int64{numEmbeddings, embeddingDim}, config.WsInit),
+		Ws:     ws,
 		config: config,
 	}
 }
 
+// NewEmbeddingFromPretrained creates a new Embedding with weight copied from
+// given 2D tensor of shape [numEmbeddings, embeddingDim].
+//
+// NOTE. `config.WsInit` is not used. Set `config.Freeze` to keep pretrained weight
+// unchanged while training.
+func NewEmbeddingFromPretrained(vs *Path, weight *ts.Tensor, config *EmbeddingConfig) *Embedding {
+	if weight.Dim() != 2 {
+		log.Fatalf("NewEmbeddingFromPretrained() failed: expected 2D weight tensor, got %v\n", weight.MustSize())
+	}
+
+	// Copy so that the caller's tensor is not trained or modified in place.
+	x := ts.MustZeros(weight.MustSize(), weight.DType(), vs.Device())
+	ts.NoGrad(func() {
+		x.Copy_(weight)
+	})
+	ws := newEmbeddingWeight(vs, x, config.PaddingIdx, config.Freeze)
+	x.MustDrop()
+
+	return &Embedding{
+		Ws:     ws,
+		config: config,
+	}
+}
+
+// newEmbeddingWeight adds embedding weight to VarStore and zeros out row at paddingIdx if specified.
+func newEmbeddingWeight(vs *Path, x *ts.Tensor, paddingIdx int64, freeze bool) *ts.Tensor {
+	numEmbeddings := x.MustSize()[0]
+	if paddingIdx >= numEmbeddings {
+		log.Fatalf("Embedding: padding index (%v) must be smaller than number of embeddings (%v)\n", paddingIdx, numEmbeddings)
+	}
+
+	ws := vs.MustAdd("weight", x, !freeze)
+	if paddingIdx >= 0 {
+		ts.NoGrad(func() {
+			row := ws.MustSelect(0, paddingIdx, false)
+			row.MustZero_()
+			row.MustDrop()
+		})
+	}
+
+	return ws
+}
+
+// renorm renormalizes (in-place) embedding vectors looked up by input indices
+// to have norm at most maxNorm.
+func renorm(ws, xs *ts.Tensor, maxNorm, normType float64) {
+	ts.NoGrad(func() {
+		indices := xs.MustContiguous(false)
+		ws.MustEmbeddingRenorm_(indices, maxNorm, normType)
+		indices.MustDrop()
+	})
+}
+
 // Implement Module, ModuleT interfaces for Embedding:
 // =========================================
 
 // Forward implements Module interface for Embedding
 func (e *Embedding) Forward(xs *ts.Tensor) *ts.Tensor {
+	if e.config.MaxNorm > 0 {
+		renorm(e.Ws, xs, e.config.MaxNorm, e.config.NormType)
+	}
 	return ts.MustEmbedding(e.Ws, xs, e.config.PaddingIdx, e.config.ScaleGradByFreq, e.config.Sparse)
 }
 
 // ForwardT implements ModuleT interface for Embedding
 func (e *Embedding) ForwardT(xs *ts.Tensor, train bool) *ts.Tensor {
-	return ts.MustEmbedding(e.Ws, xs, e.config.PaddingIdx, e.config.ScaleGradByFreq, e.config.Sparse)
+	return e.Forward(xs)
+}
+
+// EmbeddingBag:
+// =============
+
+// Configuration option for an embedding bag layer.
+//
+//   - Mode: reduction over each bag. Either "sum", "mean" or "max". Default="mean"
+//   - IncludeLastOffset: if true, offsets has one additional element equal to the size of input
+//     (CSR format).
+//
+// Other options are the same as `EmbeddingConfig`.
+type EmbeddingBagConfig struct {
+	Mode              string
+	Sparse            bool
+	ScaleGradByFreq   bool
+	IncludeLastOffset bool
+	WsInit            Init
+	PaddingIdx        int64
+	MaxNorm           float64
+	NormType          float64
+	Freeze            bool
+}
+
+func DefaultEmbeddingBagConfig() *EmbeddingBagConfig {
+	return &EmbeddingBagConfig{
+		Mode:              "mean",
+		Sparse:            false,
+		ScaleGradByFreq:   false,
+		IncludeLastOffset: false,
+		WsInit:            NewRandnInit(0.0, 1.0),
+		PaddingIdx:        -1,
+		MaxNorm:           0.0,
+		NormType:          2.0,
+		Freeze:            false,
+	}
+}
+
+// EmbeddingBag computes sums, means or maxes of bags of embeddings without
+// instantiating the intermediate embeddings.
+type EmbeddingBag struct {
+	Ws     *ts.Tensor
+	config *EmbeddingBagConfig
+}
+
+// NewEmbeddingBag creates a new EmbeddingBag.
+func NewEmbeddingBag(vs *Path, numEmbeddings int64, embeddingDim int64, config *EmbeddingBagConfig) *EmbeddingBag {
+	if _, err := embeddingBagMode(config.Mode); err != nil {
+		log.Fatalf("NewEmbeddingBag() failed: %v\n", err)
+	}
+
+	dims := []int64{numEmbeddings, embeddingDim}
+	x := config.WsInit.InitTensor(dims, vs.Device())
+	ws := newEmbeddingWeight(vs, x, config.PaddingIdx, config.Freeze)
+	x.MustDrop()
+
+	return &EmbeddingBag{
+		Ws:     ws,
+		config: config,
+	}
+}
+
+func embeddingBagMode(mode string) (int64, error) {
+	switch mode {
+	case "sum":
+		return 0, nil
+	case "mean":
+		return 1, nil
+	case "max":
+		return 2, nil
+	default:
+		err := fmt.Errorf("invalid embedding bag mode %q. Mode must be one of 'sum', 'mean' or 'max'", mode)
+		return -1, err
+	}
+}
+
+// ForwardBag computes bag reductions.
+//
+//   - input: if 1D, a flat list of indices where bags are delimited by offsets.
+//     If 2D of shape [B, N], B bags (sequences) each of fixed length N, and offsets must be nil.
+//   - offsets: 1D tensor of starting index positions of each bag in input. Nil if input is 2D.
+//   - perSampleWeights: optional (nil) float tensor of the same shape as input. Only supported in "sum" mode.
+//
+// It returns a tensor of shape [B, embeddingDim].
+func (e *EmbeddingBag) ForwardBag(input, offsets, perSampleWeights *ts.Tensor) *ts.Tensor {
+	mode, err := embeddingBagMode(e.config.Mode)
+	if err != nil {
+		log.Fatalf("EmbeddingBag.ForwardBag() failed: %v\n", err)
+	}
+
+	if perSampleWeights != nil && mode != 0 {
+		log.Fatalf("EmbeddingBag.ForwardBag() failed: per sample weights are only supported for mode 'sum', got %q\n", e.config.Mode)
+	}
+
+	var indices, offs, weights *ts.Tensor
+	switch input.Dim() {
+	case 2:
+		if offsets != nil {
+			log.Fatalf("EmbeddingBag.ForwardBag() failed: offsets must be nil when input is 2D, got %v\n", offsets.MustSize())
+		}
+		size := input.MustSize()
+		indices = input.MustReshape([]int64{-1}, false)
+		offs = ts.MustArangeStartStep(ts.IntScalar(0), ts.IntScalar(size[0]*size[1]), ts.IntScalar(size[1]), gotch.Int64, input.MustDevice())
+		if perSampleWeights != nil {
+			weights = perSampleWeights.MustReshape([]int64{-1}, false)
+		}
+	case 1:
+		if offsets == nil {
+			log.Fatalf("EmbeddingBag.ForwardBag() failed: offsets must be specified when input is 1D\n")
+		}
+		indices = input.MustShallowClone()
+		offs = offsets.MustShallowClone()
+		if perSampleWeights != nil {
+			weights = perSampleWeights.MustShallowClone()
+		}
+	default:
+		log.Fatalf("EmbeddingBag.ForwardBag() failed: input must be 1D or 2D, got %v\n", input.MustSize())
+	}
+
+	if weights == nil {
+		weights = ts.NewTensor()
+	}
+
+	if e.config.MaxNorm > 0 {
+		renorm(e.Ws, indices, e.config.MaxNorm, e.config.NormType)
+	}
+
+	var paddingIdx []int64
+	if e.config.PaddingIdx >= 0 {
+		paddingIdx = []int64{e.config.PaddingIdx}
+	}
+
+	out, offset2bag, bagSize, maxIndices := ts.MustEmbeddingBagPaddingIdx(e.Ws, indices, offs, e.config.ScaleGradByFreq, mode, e.config.Sparse, weights, e.config.IncludeLastOffset, paddingIdx)
+	offset2bag.MustDrop()
+	bagSize.MustDrop()
+	maxIndices.MustDrop()
+	indices.MustDrop()
+	offs.MustDrop()
+	weights.MustDrop()
+
+	return out
+}
+
+// Implement Module, ModuleT interfaces for EmbeddingBag:
+// ======================================================
+
+// Forward implements Module interface for EmbeddingBag.
+//
+// Input is a 2D tensor of shape [B, N] of B bags each of fixed length N.
+// Use `ForwardBag()` for variable length bags or per sample weights.
+func (e *EmbeddingBag) Forward(xs *ts.Tensor) *ts.Tensor {
+	return e.ForwardBag(xs, nil, nil)
+}
+
+// ForwardT implements ModuleT interface for EmbeddingBag.
+func (e *EmbeddingBag) ForwardT(xs *ts.Tensor, train bool) *ts.Tensor {
+	return e.ForwardBag(xs, nil, nil)
 }
@@ -1,6 +1,11 @@
 package nn_test
 
 import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -62,3 +67,159 @@ func TestEmbedding(t *testing.T) {
 	cfg.PaddingIdx = 0
 	embeddingTest(cfg, t)
 }
+
+func TestEmbedding_PaddingIdx(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultEmbeddingConfig()
+	cfg.PaddingIdx = 2
+	emb := nn.NewEmbedding(vs.Root(), 5, 3, cfg)
+
+	out := emb.Forward(ts.MustOfSlice([]int64{2}))
+	for _, v := range out.Float64Values(true) {
+		if v != 0 {
+			t.Errorf("want padding embedding values 0, got %v\n", v)
+		}
+	}
+}
+
+func TestEmbedding_MaxNormFreeze(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultEmbeddingConfig()
+	cfg.WsInit = nn.NewConstInit(10.0)
+	cfg.MaxNorm = 1.0
+	cfg.Freeze = true
+	emb := nn.NewEmbedding(vs.Root(), 5, 4, cfg)
+
+	if emb.Ws.MustRequiresGrad() {
+		t.Errorf("want frozen embedding weight not requiring grad\n")
+	}
+
+	out := emb.Forward(ts.MustOfSlice([]int64{0, 1}))
+	norm := out.MustNormScalaroptDim(ts.FloatScalar(2.0), []int64{1}, false, true)
+	for _, v := range norm.Float64Values(true) {
+		if v > 1.0+1e-4 {
+			t.Errorf("want embedding norm <= 1.0, got %v\n", v)
+		}
+	}
+}
+
+func TestEmbeddingFromPretrained(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	weight := ts.MustOnes([]int64{3, 2}, gotch.Float, gotch.CPU)
+	cfg := nn.DefaultEmbeddingConfig()
+	cfg.PaddingIdx = 0
+	emb := nn.NewEmbeddingFromPretrained(vs.Root(), weight, cfg)
+
+	if weight.MustRequiresGrad() {
+		t.Errorf("want pretrained weight not requiring grad\n")
+	}
+	want := []float64{1, 1, 1, 1, 1, 1}
+	if got := weight.Float64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want pretrained weight unchanged %v, got %v\n", want, got)
+	}
+	want = []float64{0, 0, 1, 1, 1, 1}
+	if got := emb.Ws.Float64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want embedding weight %v, got %v\n", want, got)
+	}
+}
+
+func TestEmbeddingBag(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultEmbeddingBagConfig()
+	cfg.Mode = "sum"
+	cfg.WsInit = nn.NewConstInit(1.0)
+	bag := nn.NewEmbeddingBag(vs.Root(), 10, 3, cfg)
+
+	// 2D input: 2 bags of length 4
+	input := ts.MustOfSlice([]int64{1, 2, 4, 5, 4, 3, 2, 9}).MustView([]int64{2, 4}, true)
+	out := bag.Forward(input)
+	want := []int64{2, 3}
+	got := out.MustSize()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want output shape %v, got %v\n", want, got)
+	}
+	for _, v := range out.Float64Values(true) {
+		if v != 4.0 {
+			t.Errorf("want sum 4.0, got %v\n", v)
+		}
+	}
+
+	// 1D input with offsets and per sample weights
+	input = ts.MustOfSlice([]int64{1, 2, 4, 5, 4, 3, 2})
+	offsets := ts.MustOfSlice([]int64{0, 3})
+	weights := ts.MustOfSlice([]float32{1, 1, 1, 2, 2, 2, 2})
+	out = bag.ForwardBag(input, offsets, weights)
+	wantVals := []float64{3, 3, 3, 8, 8, 8}
+	gotVals := out.Float64Values(true)
+	if !reflect.DeepEqual(wantVals, gotVals) {
+		t.Errorf("want %v, got %v\n", wantVals, gotVals)
+	}
+}
+
+func TestLoadWordVectors(t *testing.T) {
+	dir := t.TempDir()
+
+	text := "the 0.1 0.2 0.3\ncat 1.0 2.0 3.0\n"
+	gloveFile := filepath.Join(dir, "glove.txt")
+	if err := os.WriteFile(gloveFile, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w2vTextFile := filepath.Join(dir, "w2v.txt")
+	if err := os.WriteFile(w2vTextFile, []byte("2 3\n"+text), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("2 3\n")
+	for _, w := range []struct {
+		word string
+		vec  []float32
+	}{{"the", []float32{0.1, 0.2, 0.3}}, {"cat", []float32{1, 2, 3}}} {
+		buf.WriteString(w.word + " ")
+		for _, v := range w.vec {
+			binary.Write(&buf, binary.LittleEndian, math.Float32bits(v))
+		}
+		buf.WriteString("\n")
+	}
+	w2vBinFile := filepath.Join(dir, "w2v.bin")
+	if err := os.WriteFile(w2vBinFile, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]float32{
+		"the": {0.1, 0.2, 0.3},
+		"cat": {1, 2, 3},
+	}
+	for _, f := range []struct {
+		file   string
+		binary bool
+	}{{gloveFile, false}, {w2vTextFile, false}, {w2vBinFile, true}} {
+		wv, err := nn.LoadWordVectors(f.file, f.binary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wv.Dim != 3 || !reflect.DeepEqual(want, wv.Vectors) {
+			t.Errorf("%s - want %v, got dim %v, %v\n", f.file, want, wv.Dim, wv.Vectors)
+		}
+	}
+
+	vocab := map[string]int64{"<pad>": 0, "cat": 1, "dog": 2}
+	cfg := nn.DefaultEmbeddingConfig()
+	cfg.WsInit = nn.NewConstInit(0.0)
+	vs := nn.NewVarStore(gotch.CPU)
+	emb, missing, err := nn.NewEmbeddingFromWordVectors(vs.Root(), gloveFile, false, vocab, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantMissing := []string{"<pad>", "dog"}
+	if !reflect.DeepEqual(wantMissing, missing) {
+		t.Errorf("want missing words %v, got %v\n", wantMissing, missing)
+	}
+
+	got := emb.Forward(ts.MustOfSlice([]int64{1})).Float64Values(true)
+	wantVec := []float64{1, 2, 3}
+	if !reflect.DeepEqual(wantVec, got) {
+		t.Errorf("want vector %v, got %v\n", wantVec, got)
+	}
+}
@@ -0,0 +1,440 @@
+package nn
+
+// High-level training loop.
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/ts"
+)
+
+// BatchIterator iterates over mini-batches of (inputs, targets) of one epoch.
+//
+// Returned tensors are owned by the caller.
+type BatchIterator interface {
+	Next() (xs, ys *ts.Tensor, ok bool)
+}
+
+// BatchLoader creates a new BatchIterator at the start of each epoch.
+type BatchLoader func() BatchIterator
+
+type iter2Iterator struct {
+	iter *ts.Iter2
+}
+
+// Next implements BatchIterator interface.
+func (it *iter2Iterator) Next() (xs, ys *ts.Tensor, ok bool) {
+	item, ok := it.iter.Next()
+	if !ok {
+		it.iter.Drop()
+		return nil, nil, false
+	}
+	return item.Data, item.Label, true
+}
+
+// Iter2Loader returns a BatchLoader iterating over xs and ys with ts.Iter2 for each epoch.
+// Small last batch is returned. If shuffle is true, samples are reshuffled every epoch.
+func Iter2Loader(xs, ys *ts.Tensor, batchSize int64, shuffle bool) BatchLoader {
+	return func() BatchIterator {
+		iter := ts.MustNewIter2(xs, ys, batchSize).ReturnSmallLastBatch()
+		if shuffle {
+			iter.Shuffle()
+		}
+		return &iter2Iterator{iter}
+	}
+}
+
+// LossFn computes a scalar loss from model outputs and targets.
+type LossFn func(logits, targets *ts.Tensor) *ts.Tensor
+
+// MetricFn computes a metric value of a batch from model outputs and targets.
+// Epoch values are averages of batch values weighted by batch sizes.
+type MetricFn func(logits, targets *ts.Tensor) float64
+
+// AccuracyMetric is a MetricFn returning classification accuracy of logits.
+func AccuracyMetric(logits, targets *ts.Tensor) float64 {
+	acc := logits.AccuracyForLogits(targets)
+	v := acc.Float64Values()[0]
+	acc.MustDrop()
+	return v
+}
+
+// TrainerOpts are options for a Trainer:
+//   - Epochs: number of epochs to train. Default=1
+//   - Device: device that batches are moved to. Default=CPU
+//   - Metrics: named metrics logged for training and validation batches. Default=none
+//   - Callbacks: callbacks called in the order given. Default=none
+//   - Scheduler: learning rate scheduler. Default=nil
+//   - SchedulerPerBatch: step scheduler after every optimizer step instead of every epoch. Default=false
+//   - ClipGradValue: clip gradients to [-v, v] before optimizer steps if v > 0. Default=0
+//   - ClipGradNorm: clip gradient total norm to v before optimizer steps if v > 0. Default=0
+//   - AccumulationSteps: number of batches to accumulate gradients before an optimizer step. Default=1
+type TrainerOpts struct {
+	Epochs            int
+	Device            gotch.Device
+	Metrics           map[string]MetricFn
+	Callbacks         []Callback
+	Scheduler         *LRScheduler
+	SchedulerPerBatch bool
+	ClipGradValue     float64
+	ClipGradNorm      float64
+	AccumulationSteps int
+}
+
+type TrainerOpt func(*TrainerOpts)
+
+func OptEpochsTrainer(v int) TrainerOpt {
+	return func(o *TrainerOpts) {
+		o.Epochs = v
+	}
+}
+
+func OptDeviceTrainer(v gotch.Device) TrainerOpt {
+	return func(o *TrainerOpts) {
+		o.Device = v
+	}
+}
+
+func OptMetricTrainer(name string, fn MetricFn) TrainerOpt {
+	return func(o *TrainerOpts) {
+		o.Metrics[name] = fn
+	}
+}
+
+func OptCallbacksTrainer(v ...Callback) TrainerOpt {
+	return func(o *TrainerOpts) {
+		o.Callbacks = append(o.Callbacks, v...)
+	}
+}
+
+func OptSchedulerTrainer(v *LRScheduler, perBatch bool) TrainerOpt {
+	return func(o *TrainerOpts) {
+		o.Scheduler = v
+		o.SchedulerPerBatch = perBatch
+	}
+}
+
+func OptClipGradValueTrainer(v float64) TrainerOpt {
+	return func(o *TrainerOpts) {
+		o.ClipGradValue = v
+	}
+}
+
+func OptClipGradNormTrainer(v float64) TrainerOpt {
+	return func(o *TrainerOpts) {
+		o.ClipGradNorm = v
+	}
+}
+
+func OptAccumulationStepsTrainer(v int) TrainerOpt {
+	return func(o *TrainerOpts) {
+		o.AccumulationSteps = v
+	}
+}
+
+func DefaultTrainerOpts() *TrainerOpts {
+	return &TrainerOpts{
+		Epochs:            1,
+		Device:            gotch.CPU,
+		Metrics:           make(map[string]MetricFn),
+		AccumulationSteps: 1,
+	}
+}
+
+// Trainer runs the epoch loop of training a model with an optimizer and evaluates
+// it on validation data after every epoch.
+//
+// Epoch logs hold "loss" and metric values of training batches, "val_loss" and
+// "val_<metric>" values of validation batches and "lr", the first learning rate of
+// the optimizer at the end of the epoch.
+type Trainer struct {
+	Model     ts.ModuleT
+	Loss      LossFn
+	Optimizer *Optimizer
+	Opts      *TrainerOpts
+
+	epoch   int
+	step    int
+	stopped bool
+	metrics []string // sorted metric names
+	history []map[string]float64
+}
+
+// NewTrainer creates a new Trainer.
+func NewTrainer(model ts.ModuleT, loss LossFn, opt *Optimizer, opts ...TrainerOpt) *Trainer {
+	o := DefaultTrainerOpts()
+	for _, option := range opts {
+		option(o)
+	}
+
+	var metrics []string
+	for name := range o.Metrics {
+		metrics = append(metrics, name)
+	}
+	sort.Strings(metrics)
+
+	return &Trainer{
+		Model:     model,
+		Loss:      loss,
+		Optimizer: opt,
+		Opts:      o,
+		metrics:   metrics,
+	}
+}
+
+// Stop requests training to stop at the end of the current epoch.
+func (t *Trainer) Stop() {
+	t.stopped = true
+}
+
+// Epoch returns current epoch (0-based).
+func (t *Trainer) Epoch() int {
+	return t.epoch
+}
+
+// Step returns number of optimizer steps done.
+func (t *Trainer) Step() int {
+	return t.step
+}
+
+// History returns epoch logs of the last Fit.
+func (t *Trainer) History() []map[string]float64 {
+	return t.history
+}
+
+// Fit trains the model for the configured number of epochs or until a callback
+// stops training. Val can be nil to skip validation. It returns epoch logs, and
+// stops with an error if a callback's OnEpochEnd fails.
+func (t *Trainer) Fit(train, val BatchLoader) ([]map[string]float64, error) {
+	if t.Opts.AccumulationSteps < 1 {
+		err := fmt.Errorf("Trainer.Fit() failed: invalid accumulation steps %v", t.Opts.AccumulationSteps)
+		return nil, err
+	}
+
+	t.stopped = false
+	t.history = nil
+	for _, cb := range t.Opts.Callbacks {
+		cb.OnTrainBegin(t)
+	}
+
+	for t.epoch = 0; t.epoch < t.Opts.Epochs && !t.stopped; t.epoch++ {
+		for _, cb := range t.Opts.Callbacks {
+			cb.OnEpochBegin(t, t.epoch)
+		}
+
+		logs, err := t.trainEpoch(train)
+		if err != nil {
+			return t.history, err
+		}
+
+		if val != nil {
+			valLogs, err := t.Evaluate(val)
+			if err != nil {
+				return t.history, err
+			}
+			for k, v := range valLogs {
+				logs["val_"+k] = v
+			}
+		}
+
+		if t.Opts.Scheduler != nil && !t.Opts.SchedulerPerBatch {
+			loss, ok := logs["val_loss"]
+			if !ok {
+				loss = logs["loss"]
+			}
+			t.Opts.Scheduler.Step(WithLoss(loss))
+		}
+		logs["lr"] = t.Optimizer.GetLRs()[0]
+
+		t.history = append(t.history, logs)
+		for _, cb := range t.Opts.Callbacks {
+			if err := cb.OnEpochEnd(t, t.epoch, logs); err != nil {
+				err = fmt.Errorf("Trainer.Fit() failed: %w", err)
+				return t.history, err
+			}
+		}
+	}
+
+	for _, cb := range t.Opts.Callbacks {
+		cb.OnTrainEnd(t, t.history)
+	}
+
+	return t.history, nil
+}
+
+// trainEpoch runs one training epoch and returns averaged loss and metrics.
+func (t *Trainer) trainEpoch(loader BatchLoader) (map[string]float64, error) {
+	accum := t.Opts.AccumulationSteps
+	sums := make(map[string]float64)
+	var (
+		count   float64
+		pending int // batches with accumulated gradients.
+	)
+
+	if err := t.Optimizer.ZeroGrad(); err != nil {
+		return nil, err
+	}
+
+	iter := loader()
+	for batch := 0; ; batch++ {
+		xs, ys, ok := iter.Next()
+		if !ok {
+			break
+		}
+		xs = xs.MustTo(t.Opts.Device, true)
+		ys = ys.MustTo(t.Opts.Device, true)
+		size := float64(xs.MustSize()[0])
+
+		logits := t.Model.ForwardT(xs, true)
+		loss := t.Loss(logits, ys)
+		lossVal := loss.Float64Values()[0]
+		if accum > 1 {
+			loss = loss.MustDivScalar(ts.FloatScalar(float64(accum)), true)
+		}
+		if err := loss.Backward(); err != nil {
+			err = fmt.Errorf("Trainer.Fit() failed: %w", err)
+			return nil, err
+		}
+		loss.MustDrop()
+
+		logs := map[string]float64{"loss": lossVal}
+		ts.NoGrad(func() {
+			for _, name := range t.metrics {
+				logs[name] = t.Opts.Metrics[name](logits, ys)
+			}
+		})
+		logits.MustDrop()
+		xs.MustDrop()
+		ys.MustDrop()
+
+		for k, v := range logs {
+			sums[k] += v * size
+		}
+		count += size
+
+		pending++
+		if pending == accum {
+			if err := t.optimizerStep(); err != nil {
+				return nil, err
+			}
+			pending = 0
+		}
+
+		for _, cb := range t.Opts.Callbacks {
+			cb.OnBatchEnd(t, batch, logs)
+		}
+	}
+
+	// Flush gradients of last incomplete accumulation. Batch losses were divided by
+	// accum, so gradients are rescaled to the mean over the pending batches.
+	if pending > 0 {
+		if pending < accum {
+			if err := t.scaleGrads(float64(accum) / float64(pending)); err != nil {
+				return nil, err
+			}
+		}
+		if err := t.optimizerStep(); err != nil {
+			return nil, err
+		}
+	}
+
+	if count == 0 {
+		err := fmt.Errorf("Trainer.Fit() failed: empty training data")
+		return nil, err
+	}
+
+	for k := range sums {
+		sums[k] /= count
+	}
+	return sums, nil
+}
+
+// scaleGrads multiplies gradients of all trainable variables by v.
+func (t *Trainer) scaleGrads(v float64) error {
+	vs := t.Optimizer.varstore
+	vs.Lock()
+	defer vs.Unlock()
+
+	for _, x := range vs.vars {
+		if !x.Trainable {
+			continue
+		}
+		grad := x.Tensor.MustGrad(false)
+		if grad.MustDefined() {
+			if err := grad.MulScalar_(ts.FloatScalar(v)); err != nil {
+				err = fmt.Errorf("Trainer.Fit() failed: %w", err)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (t *Trainer) optimizerStep() error {
+	if t.Opts.ClipGradValue > 0 {
+		t.Optimizer.ClipGradValue(t.Opts.ClipGradValue)
+	}
+	if t.Opts.ClipGradNorm > 0 {
+		if err := t.Optimizer.ClipGradNorm(t.Opts.ClipGradNorm); err != nil {
+			return err
+		}
+	}
+	if err := t.Optimizer.Step(); err != nil {
+		return err
+	}
+	if err := t.Optimizer.ZeroGrad(); err != nil {
+		return err
+	}
+	t.step++
+
+	if t.Opts.Scheduler != nil && t.Opts.SchedulerPerBatch {
+		t.Opts.Scheduler.Step()
+	}
+
+	return nil
+}
+
+// Evaluate runs the model in evaluation mode over data and returns averaged
+// "loss" and metric values.
+func (t *Trainer) Evaluate(loader BatchLoader) (map[string]float64, error) {
+	sums := make(map[string]float64)
+	var count float64
+
+	ts.NoGrad(func() {
+		iter := loader()
+		for {
+			xs, ys, ok := iter.Next()
+			if !ok {
+				break
+			}
+			xs = xs.MustTo(t.Opts.Device, true)
+			ys = ys.MustTo(t.Opts.Device, true)
+			size := float64(xs.MustSize()[0])
+
+			logits := t.Model.ForwardT(xs, false)
+			loss := t.Loss(logits, ys)
+			sums["loss"] += loss.Float64Values()[0] * size
+			loss.MustDrop()
+			for _, name := range t.metrics {
+				sums[name] += t.Opts.Metrics[name](logits, ys) * size
+			}
+			count += size
+
+			logits.MustDrop()
+			xs.MustDrop()
+			ys.MustDrop()
+		}
+	})
+
+	if count == 0 {
+		err := fmt.Errorf("Trainer.Evaluate() failed: empty data")
+		return nil, err
+	}
+
+	for k := range sums {
+		sums[k] /= count
+	}
+	return sums, nil
+}
@@ -0,0 +1,223 @@
+package nn_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	"github.com/sugarme/gotch/ts"
+)
+
+func trainerData() (xs, ys *ts.Tensor) {
+	xs = ts.MustRandn([]int64{64, 4}, gotch.Float, gotch.CPU)
+	ys = xs.MustArgmax([]int64{1}, false, false)
+	return xs, ys
+}
+
+func crossEntropy(logits, targets *ts.Tensor) *ts.Tensor {
+	return logits.CrossEntropyForLogits(targets)
+}
+
+func TestTrainer_Fit(t *testing.T) {
+	xs, ys := trainerData()
+	vs := nn.NewVarStore(gotch.CPU)
+	model := nn.NewLinear(vs.Root(), 4, 4, nn.DefaultLinearConfig())
+	opt, err := nn.DefaultAdamConfig().Build(vs, 0.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var progress bytes.Buffer
+	var batches int
+	counter := nn.CallbackFuncs{
+		BatchEnd: func(tr *nn.Trainer, batch int, logs map[string]float64) {
+			batches++
+		},
+	}
+	ckpt := filepath.Join(t.TempDir(), "best.gt")
+	checkpoint, err := nn.NewCheckpointBest(vs, ckpt, "val_acc", nn.OptModeMonitor("max"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trainer := nn.NewTrainer(model, crossEntropy, opt,
+		nn.OptEpochsTrainer(20),
+		nn.OptMetricTrainer("acc", nn.AccuracyMetric),
+		nn.OptAccumulationStepsTrainer(2),
+		nn.OptClipGradNormTrainer(1.0),
+		nn.OptCallbacksTrainer(counter, nn.NewProgressLogger(&progress, 0), checkpoint),
+	)
+
+	history, err := trainer.Fit(nn.Iter2Loader(xs, ys, 16, true), nn.Iter2Loader(xs, ys, 32, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history) != 20 || batches != 20*4 {
+		t.Fatalf("want 20 epochs of 4 batches, got %v epochs and %v batches\n", len(history), batches)
+	}
+	// 2 optimizer steps per epoch with 2 accumulation steps.
+	if trainer.Step() != 40 {
+		t.Errorf("want 40 optimizer steps, got %v\n", trainer.Step())
+	}
+	first, last := history[0], history[len(history)-1]
+	for _, k := range []string{"loss", "acc", "val_loss", "val_acc", "lr"} {
+		if _, ok := last[k]; !ok {
+			t.Errorf("want %q in epoch logs, got %v\n", k, last)
+		}
+	}
+	if last["loss"] >= first["loss"] || last["val_acc"] < 0.7 {
+		t.Errorf("want model trained, got first epoch %v, last epoch %v\n", first, last)
+	}
+	if !strings.Contains(progress.String(), "Epoch 20/20: acc=") {
+		t.Errorf("unexpected progress output:\n%v", progress.String())
+	}
+	if _, err := os.Stat(ckpt); err != nil {
+		t.Errorf("want best checkpoint saved: %v\n", err)
+	}
+}
+
+func TestTrainer_EarlyStopping(t *testing.T) {
+	xs, ys := trainerData()
+	vs := nn.NewVarStore(gotch.CPU)
+	model := nn.NewLinear(vs.Root(), 4, 4, nn.DefaultLinearConfig())
+	opt, err := nn.DefaultSGDConfig().Build(vs, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Constant learning rate never improves after the first epoch.
+	es, err := nn.NewEarlyStopping("lr", 2, nn.OptModeMonitor("max"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lrLog bytes.Buffer
+	trainer := nn.NewTrainer(model, crossEntropy, opt,
+		nn.OptEpochsTrainer(10),
+		nn.OptCallbacksTrainer(es, nn.NewLRLogger(&lrLog)),
+	)
+
+	history, err := trainer.Fit(nn.Iter2Loader(xs, ys, 16, false), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 {
+		t.Errorf("want training stopped after 3 epochs, got %v\n", len(history))
+	}
+	if best, epoch := es.Best(); best != 0.1 || epoch != 0 {
+		t.Errorf("want best 0.1 at epoch 0, got %v at epoch %v\n", best, epoch)
+	}
+	if _, ok := history[0]["val_loss"]; ok {
+		t.Errorf("want no validation logs without validation data\n")
+	}
+	if strings.Count(lrLog.String(), "learning rates: [0.1]") != 3 {
+		t.Errorf("unexpected learning rate log:\n%v", lrLog.String())
+	}
+}
+
+func TestTrainer_CallbackErrors(t *testing.T) {
+	if _, err := nn.NewEarlyStopping("val_loss", 2, nn.OptModeMonitor("best")); err == nil {
+		t.Errorf("want error for invalid monitor mode\n")
+	}
+
+	xs, ys := trainerData()
+	vs := nn.NewVarStore(gotch.CPU)
+	model := nn.NewLinear(vs.Root(), 4, 4, nn.DefaultLinearConfig())
+	opt, err := nn.DefaultSGDConfig().Build(vs, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "val_loss" is missing from epoch logs when training without validation data.
+	es, err := nn.NewEarlyStopping("val_loss", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trainer := nn.NewTrainer(model, crossEntropy, opt, nn.OptEpochsTrainer(3), nn.OptCallbacksTrainer(es))
+	history, err := trainer.Fit(nn.Iter2Loader(xs, ys, 16, false), nil)
+	if err == nil || !strings.Contains(err.Error(), `"val_loss" not found`) {
+		t.Errorf("want missing monitored value error, got %v\n", err)
+	}
+	if len(history) != 1 {
+		t.Errorf("want training stopped after 1 epoch, got %v\n", len(history))
+	}
+
+	// Saving to a missing directory fails on the first improvement.
+	ckpt, err := nn.NewCheckpointBest(vs, filepath.Join(t.TempDir(), "missing", "best.gt"), "loss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trainer = nn.NewTrainer(model, crossEntropy, opt, nn.OptEpochsTrainer(3), nn.OptCallbacksTrainer(ckpt))
+	if _, err := trainer.Fit(nn.Iter2Loader(xs, ys, 16, false), nil); err == nil {
+		t.Errorf("want checkpoint saving error\n")
+	}
+}
+
+func TestTrainer_Scheduler(t *testing.T) {
+	xs, ys := trainerData()
+	vs := nn.NewVarStore(gotch.CPU)
+	model := nn.NewLinear(vs.Root(), 4, 4, nn.DefaultLinearConfig())
+	opt, err := nn.DefaultSGDConfig().Build(vs, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := nn.NewStepLR(opt, 1, 0.5).Build()
+	trainer := nn.NewTrainer(model, crossEntropy, opt,
+		nn.OptEpochsTrainer(3),
+		nn.OptSchedulerTrainer(s, false),
+		nn.OptClipGradValueTrainer(0.5),
+	)
+
+	history, err := trainer.Fit(nn.Iter2Loader(xs, ys, 32, false), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{0.05, 0.025, 0.0125}
+	for i, logs := range history {
+		if diff := logs["lr"] - want[i]; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("epoch %v: want lr %v, got %v\n", i, want[i], logs["lr"])
+		}
+	}
+}
+
+func TestTrainer_AccumulationFlush(t *testing.T) {
+	xs, ys := trainerData()
+	xs = xs.MustNarrow(0, 0, 48, true)
+	ys = ys.MustNarrow(0, 0, 48, true)
+
+	fit := func(src *nn.VarStore, batchSize int64, accum int) *nn.Linear {
+		vs := nn.NewVarStore(gotch.CPU)
+		model := nn.NewLinear(vs.Root(), 4, 4, nn.DefaultLinearConfig())
+		if err := vs.Copy(src); err != nil {
+			t.Fatal(err)
+		}
+		opt, err := nn.DefaultSGDConfig().Build(vs, 0.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trainer := nn.NewTrainer(model, crossEntropy, opt, nn.OptAccumulationStepsTrainer(accum))
+		if _, err := trainer.Fit(nn.Iter2Loader(xs, ys, batchSize, false), nil); err != nil {
+			t.Fatal(err)
+		}
+		if trainer.Step() != 1 {
+			t.Fatalf("want 1 optimizer step, got %v\n", trainer.Step())
+		}
+		return model
+	}
+
+	initVs := nn.NewVarStore(gotch.CPU)
+	nn.NewLinear(initVs.Root(), 4, 4, nn.DefaultLinearConfig())
+
+	// 3 batches with 4 accumulation steps are flushed as the mean gradient of the
+	// 3 batches, i.e. the same step as one batch of all samples.
+	accumulated := fit(initVs, 16, 4)
+	full := fit(initVs, 48, 1)
+	if !accumulated.Ws.MustAllclose(full.Ws, 1e-5, 1e-6, false, false) {
+		t.Errorf("want flushed step equal to full batch step, got weights\n%v\nwant\n%v", accumulated.Ws, full.Ws)
+	}
+}
@@ -0,0 +1,226 @@
+package nn
+
+// Upsampling layers.
+
+import (
+	"log"
+	"math"
+
+	"github.com/sugarme/gotch/ts"
+)
+
+// Upsample:
+// =========
+
+// Upsample upsamples a given multi-channel 1D (temporal), 2D (spatial) or 3D (volumetric) data.
+//
+// Input is assumed to be of shape (N, C, L), (N, C, H, W) or (N, C, D, H, W).
+// Output size is either given by `Size` or calculated by multiplying
+// input spatial size with `ScaleFactor`.
+type Upsample struct {
+	Size         []int64
+	ScaleFactor  []float64
+	Mode         string
+	AlignCorners bool
+}
+
+// UpsampleOpts is options for Upsample.
+//
+//   - Size: output spatial size. Single value is applied to all spatial dimensions.
+//   - ScaleFactor: multiplier for spatial size. Single value is applied to all spatial dimensions.
+//   - Mode: upsampling algorithm. One of "nearest" (default), "linear", "bilinear", "bicubic" or "trilinear".
+//   - AlignCorners: if true, corner pixels of input and output are aligned. Only for "linear", "bilinear",
+//     "bicubic" or "trilinear" mode. Default=false
+type UpsampleOpts struct {
+	Size         []int64
+	ScaleFactor  []float64
+	Mode         string
+	AlignCorners bool
+}
+
+type UpsampleOpt func(*UpsampleOpts)
+
+func OptSizeUpsample(v []int64) UpsampleOpt {
+	return func(o *UpsampleOpts) {
+		o.Size = v
+	}
+}
+
+func OptScaleFactorUpsample(v []float64) UpsampleOpt {
+	return func(o *UpsampleOpts) {
+		o.ScaleFactor = v
+	}
+}
+
+func OptModeUpsample(v string) UpsampleOpt {
+	return func(o *UpsampleOpts) {
+		o.Mode = v
+	}
+}
+
+func OptAlignCornersUpsample(v bool) UpsampleOpt {
+	return func(o *UpsampleOpts) {
+		o.AlignCorners = v
+	}
+}
+
+func DefaultUpsampleOpts() *UpsampleOpts {
+	return &UpsampleOpts{
+		Size:         nil,
+		ScaleFactor:  nil,
+		Mode:         "nearest",
+		AlignCorners: false,
+	}
+}
+
+// NewUpsample creates a new Upsample layer. Either `Size` or `ScaleFactor` option should be specified.
+func NewUpsample(opts ...UpsampleOpt) *Upsample {
+	o := DefaultUpsampleOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if (len(o.Size) == 0) == (len(o.ScaleFactor) == 0) {
+		log.Fatalf("NewUpsample() failed: either size or scale factor should be specified. Got size: %v, scale factor: %v\n", o.Size, o.ScaleFactor)
+	}
+
+	switch o.Mode {
+	case "nearest":
+		if o.AlignCorners {
+			log.Fatalf("NewUpsample() failed: align corners option can only be set with the interpolating modes: linear | bilinear | bicubic | trilinear\n")
+		}
+	case "linear", "bilinear", "bicubic", "trilinear":
+	default:
+		log.Fatalf("NewUpsample() failed: unsupported mode %q. Mode should be one of 'nearest', 'linear', 'bilinear', 'bicubic' or 'trilinear'\n", o.Mode)
+	}
+
+	return &Upsample{
+		Size:         o.Size,
+		ScaleFactor:  o.ScaleFactor,
+		Mode:         o.Mode,
+		AlignCorners: o.AlignCorners,
+	}
+}
+
+// outputSize calculates output spatial size and scales (if any) for each spatial dimension.
+func (u *Upsample) outputSize(inputSize []int64) ([]int64, [][]float64) {
+	nd := len(inputSize) - 2
+	size := make([]int64, nd)
+	scales := make([][]float64, nd)
+
+	if len(u.Size) > 0 {
+		if len(u.Size) != 1 && len(u.Size) != nd {
+			log.Fatalf("Upsample: expected size of 1 or %d elements, got %v\n", nd, u.Size)
+		}
+		for i := range size {
+			if len(u.Size) == 1 {
+				size[i] = u.Size[0]
+			} else {
+				size[i] = u.Size[i]
+			}
+		}
+
+		return size, scales
+	}
+
+	if len(u.ScaleFactor) != 1 && len(u.ScaleFactor) != nd {
+		log.Fatalf("Upsample: expected scale factor of 1 or %d elements, got %v\n", nd, u.ScaleFactor)
+	}
+	for i := range size {
+		scale := u.ScaleFactor[0]
+		if len(u.ScaleFactor) > 1 {
+			scale = u.ScaleFactor[i]
+		}
+		size[i] = int64(math.Floor(float64(inputSize[i+2]) * scale))
+		scales[i] = []float64{scale}
+	}
+
+	return size, scales
+}
+
+// Forward implements Module interface for Upsample.
+func (u *Upsample) Forward(x *ts.Tensor) *ts.Tensor {
+	inputSize := x.MustSize()
+	size, scales := u.outputSize(inputSize)
+
+	switch {
+	case len(inputSize) == 3 && u.Mode == "nearest":
+		return x.MustUpsampleNearest1d(size, scales[0], false)
+	case len(inputSize) == 4 && u.Mode == "nearest":
+		return x.MustUpsampleNearest2d(size, scales[0], scales[1], false)
+	case len(inputSize) == 5 && u.Mode == "nearest":
+		return x.MustUpsampleNearest3d(size, scales[0], scales[1], scales[2], false)
+	case len(inputSize) == 3 && u.Mode == "linear":
+		return x.MustUpsampleLinear1d(size, u.AlignCorners, scales[0], false)
+	case len(inputSize) == 4 && u.Mode == "bilinear":
+		return x.MustUpsampleBilinear2d(size, u.AlignCorners, scales[0], scales[1], false)
+	case len(inputSize) == 4 && u.Mode == "bicubic":
+		return x.MustUpsampleBicubic2d(size, u.AlignCorners, scales[0], scales[1], false)
+	case len(inputSize) == 5 && u.Mode == "trilinear":
+		return x.MustUpsampleTrilinear3d(size, u.AlignCorners, scales[0], scales[1], scales[2], false)
+	default:
+		log.Fatalf("Upsample: mode %q does not support input of shape %v\n", u.Mode, inputSize)
+		return nil
+	}
+}
+
+// ForwardT implements ModuleT interface for Upsample.
+//
+// NOTE: train param will not be used.
+func (u *Upsample) ForwardT(x *ts.Tensor, train bool) *ts.Tensor {
+	return u.Forward(x)
+}
+
+// PixelShuffle:
+// =============
+
+// PixelShuffle rearranges elements in a tensor of shape (*, C * r^2, H, W)
+// to a tensor of shape (*, C, H * r, W * r), where r is an upscale factor.
+//
+// Paper: https://arxiv.org/abs/1609.05158
+type PixelShuffle struct {
+	UpscaleFactor int64
+}
+
+// NewPixelShuffle creates a new PixelShuffle layer.
+func NewPixelShuffle(upscaleFactor int64) *PixelShuffle {
+	return &PixelShuffle{upscaleFactor}
+}
+
+// Forward implements Module interface for PixelShuffle.
+func (m *PixelShuffle) Forward(x *ts.Tensor) *ts.Tensor {
+	return x.MustPixelShuffle(m.UpscaleFactor, false)
+}
+
+// ForwardT implements ModuleT interface for PixelShuffle.
+//
+// NOTE: train param will not be used.
+func (m *PixelShuffle) ForwardT(x *ts.Tensor, train bool) *ts.Tensor {
+	return m.Forward(x)
+}
+
+// PixelUnshuffle:
+// ===============
+
+// PixelUnshuffle reverses PixelShuffle operation by rearranging elements in a tensor of shape
+// (*, C, H * r, W * r) to a tensor of shape (*, C * r^2, H, W), where r is a downscale factor.
+type PixelUnshuffle struct {
+	DownscaleFactor int64
+}
+
+// NewPixelUnshuffle creates a new PixelUnshuffle layer.
+func NewPixelUnshuffle(downscaleFactor int64) *PixelUnshuffle {
+	return &PixelUnshuffle{downscaleFactor}
+}
+
+// Forward implements Module interface for PixelUnshuffle.
+func (m *PixelUnshuffle) Forward(x *ts.Tensor) *ts.Tensor {
+	return x.MustPixelUnshuffle(m.DownscaleFactor, false)
+}
+
+// ForwardT implements ModuleT interface for PixelUnshuffle.
+//
+// NOTE: train param will not be used.
+func (m *PixelUnshuffle) ForwardT(x *ts.Tensor, train bool) *ts.Tensor {
+	return m.Forward(x)
+}
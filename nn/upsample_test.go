@@ -0,0 +1,93 @@
+package nn_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	"github.com/sugarme/gotch/ts"
+)
+
+func TestUpsample(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []int64
+		opts  []nn.UpsampleOpt
+		want  []int64
+	}{
+		{"nearest1d", []int64{2, 3, 5}, []nn.UpsampleOpt{nn.OptScaleFactorUpsample([]float64{2})}, []int64{2, 3, 10}},
+		{"nearest2d", []int64{2, 3, 4, 5}, []nn.UpsampleOpt{nn.OptScaleFactorUpsample([]float64{2, 3})}, []int64{2, 3, 8, 15}},
+		{"nearest3d size", []int64{1, 1, 2, 2, 2}, []nn.UpsampleOpt{nn.OptSizeUpsample([]int64{3})}, []int64{1, 1, 3, 3, 3}},
+		{"linear", []int64{2, 3, 5}, []nn.UpsampleOpt{nn.OptModeUpsample("linear"), nn.OptSizeUpsample([]int64{7})}, []int64{2, 3, 7}},
+		{"bilinear", []int64{2, 3, 4, 5}, []nn.UpsampleOpt{nn.OptModeUpsample("bilinear"), nn.OptAlignCornersUpsample(true), nn.OptScaleFactorUpsample([]float64{1.5})}, []int64{2, 3, 6, 7}},
+		{"bicubic", []int64{2, 3, 4, 5}, []nn.UpsampleOpt{nn.OptModeUpsample("bicubic"), nn.OptSizeUpsample([]int64{8, 10})}, []int64{2, 3, 8, 10}},
+		{"trilinear", []int64{1, 1, 2, 2, 2}, []nn.UpsampleOpt{nn.OptModeUpsample("trilinear"), nn.OptScaleFactorUpsample([]float64{2})}, []int64{1, 1, 4, 4, 4}},
+	}
+
+	for _, tt := range tests {
+		x := ts.MustRandn(tt.input, gotch.Float, gotch.CPU)
+		out := nn.NewUpsample(tt.opts...).Forward(x)
+		got := out.MustSize()
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("%s - want output shape %v, got %v\n", tt.name, tt.want, got)
+		}
+		x.MustDrop()
+		out.MustDrop()
+	}
+}
+
+func TestPixelShuffle(t *testing.T) {
+	x := ts.MustRandn([]int64{2, 8, 3, 4}, gotch.Float, gotch.CPU)
+	shuffled := nn.NewPixelShuffle(2).Forward(x)
+	want := []int64{2, 2, 6, 8}
+	got := shuffled.MustSize()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want output shape %v, got %v\n", want, got)
+	}
+
+	unshuffled := nn.NewPixelUnshuffle(2).Forward(shuffled)
+	if !unshuffled.MustEqual(x, false) {
+		t.Errorf("want PixelUnshuffle to reverse PixelShuffle\n")
+	}
+}
+
+func TestFoldUnfold(t *testing.T) {
+	x := ts.MustOnes([]int64{1, 2, 4, 4}, gotch.Float, gotch.CPU)
+	unfold := nn.NewUnfold([]int64{2, 2}, nn.OptStrideFold([]int64{2, 2}))
+	blocks := unfold.Forward(x)
+	want := []int64{1, 8, 4}
+	got := blocks.MustSize()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want Unfold output shape %v, got %v\n", want, got)
+	}
+
+	// Non-overlapping blocks: Fold reverses Unfold.
+	fold := nn.NewFold([]int64{4, 4}, []int64{2, 2}, nn.OptStrideFold([]int64{2, 2}))
+	folded := fold.Forward(blocks)
+	if !folded.MustEqual(x, false) {
+		t.Errorf("want Fold to reverse Unfold with non-overlapping blocks\n")
+	}
+}
+
+func TestFlattenUnflatten(t *testing.T) {
+	x := ts.MustRandn([]int64{2, 3, 4, 5}, gotch.Float, gotch.CPU)
+
+	seq := nn.Seq()
+	seq.Add(nn.NewFlatten())
+	seq.Add(nn.NewUnflatten(1, []int64{3, 20}))
+	out := seq.Forward(x)
+
+	want := []int64{2, 3, 20}
+	got := out.MustSize()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want output shape %v, got %v\n", want, got)
+	}
+
+	out = nn.NewFlatten(nn.OptStartDimFlatten(0), nn.OptEndDimFlatten(1)).Forward(x)
+	want = []int64{6, 4, 5}
+	got = out.MustSize()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want output shape %v, got %v\n", want, got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/sugarme/gotch"
 	"github.com/sugarme/gotch/ts"
@@ -27,8 +28,10 @@ type Var struct {
 // It specifies a SINGLE device where all variables are stored.
 type VarStore struct {
 	sync.Mutex
-	device gotch.Device
-	vars   map[string]Var
+	device    gotch.Device
+	vars      map[string]Var
+	pruned    map[string]string // names of pruned parameters and their masks. See updatePruned().
+	hasPruned atomic.Bool       // whether pruned is not empty, readable without lock.
 }
 
 // Path is variable store with an associated path for variables naming.
@@ -466,6 +469,7 @@ func (vs *VarStore) Destroy() {
 
 		delete(vs.vars, n)
 	}
+	vs.updatePruned()
 
 	vs.Unlock()
 
@@ -585,6 +589,9 @@ func (p *Path) add(name string, newTs *ts.Tensor, trainable bool, varType string
 		Persitent: persistent,
 	}
 	p.varstore.vars[path] = v
+	if _, ok := p.varstore.vars[path+PruneMaskSuffix]; ok || strings.HasSuffix(path, PruneMaskSuffix) {
+		p.varstore.updatePruned()
+	}
 
 	return tensor, nil
 }
@@ -666,6 +673,9 @@ func (p *Path) Remove(name string) error {
 	}
 
 	delete(p.varstore.vars, name)
+	if _, ok := p.varstore.pruned[name]; ok || strings.HasSuffix(name, PruneMaskSuffix) {
+		p.varstore.updatePruned()
+	}
 	return nil
 }
 
@@ -0,0 +1,102 @@
+package serve
+
+// HTTP JSON endpoint.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/ts"
+)
+
+// TensorJSON is the JSON form of a single-sample tensor: flattened values in row-major
+// order and the tensor shape. Input tensors are created with float32 dtype.
+//
+// Example: `{"data": [1, 2, 3, 4, 5, 6], "shape": [2, 3]}`
+type TensorJSON struct {
+	Data  []float64 `json:"data"`
+	Shape []int64   `json:"shape"`
+}
+
+type errorJSON struct {
+	Error string `json:"error"`
+}
+
+// Handler returns an http.Handler accepting POST requests with a TensorJSON body and
+// replying with the model output as TensorJSON.
+//
+// Status codes: 400 for malformed inputs, 405 for non-POST methods, 503 when the request
+// queue is full or the server is closed and 500 for model errors.
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(s.serveHTTP)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, errorJSON{"method not allowed"})
+		return
+	}
+
+	var in TensorJSON
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{fmt.Sprintf("invalid JSON: %v", err)})
+		return
+	}
+	input, err := in.tensor()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{err.Error()})
+		return
+	}
+	defer input.MustDrop()
+
+	output, err := s.Predict(r.Context(), input)
+	switch {
+	case errors.Is(err, ErrQueueFull), errors.Is(err, ErrClosed):
+		writeJSON(w, http.StatusServiceUnavailable, errorJSON{err.Error()})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, errorJSON{err.Error()})
+		return
+	}
+
+	out := TensorJSON{
+		Data:  output.Float64Values(),
+		Shape: output.MustSize(),
+	}
+	output.MustDrop()
+	writeJSON(w, http.StatusOK, out)
+}
+
+// tensor creates a tensor from the JSON form.
+func (t TensorJSON) tensor() (*ts.Tensor, error) {
+	numel := int64(1)
+	for _, d := range t.Shape {
+		if d < 0 {
+			return nil, fmt.Errorf("invalid shape %v", t.Shape)
+		}
+		numel *= d
+	}
+	if len(t.Data) == 0 || int64(len(t.Data)) != numel {
+		return nil, fmt.Errorf("data length %v mismatched shape %v", len(t.Data), t.Shape)
+	}
+
+	x, err := ts.OfSlice(t.Data)
+	if err != nil {
+		return nil, err
+	}
+	x, err = x.Totype(gotch.Float, true)
+	if err != nil {
+		return nil, err
+	}
+	return x.View(t.Shape, true)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
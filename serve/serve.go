@@ -0,0 +1,359 @@
+package serve
+
+// Dynamic-batching inference server.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/ts"
+)
+
+var (
+	// ErrQueueFull is returned when the request queue is full.
+	ErrQueueFull = errors.New("serve: request queue is full")
+	// ErrClosed is returned for requests sent to a closed server.
+	ErrClosed = errors.New("serve: server closed")
+)
+
+// Model runs a forward pass on a batch of inputs. Output first dimension
+// should be the batch dimension.
+//
+// `*ts.CModule` implements Model.
+type Model interface {
+	Forward(batch *ts.Tensor) (*ts.Tensor, error)
+}
+
+type moduleT struct {
+	m ts.ModuleT
+}
+
+// Forward implements Model interface.
+func (m moduleT) Forward(batch *ts.Tensor) (*ts.Tensor, error) {
+	return m.m.ForwardT(batch, false), nil
+}
+
+// NewModuleT wraps a ts.ModuleT as a Model running in evaluation mode (train=false).
+func NewModuleT(m ts.ModuleT) Model {
+	return moduleT{m}
+}
+
+// Replica is a model replica placed on a device. Each replica is run by its own goroutine.
+type Replica struct {
+	Model  Model
+	Device gotch.Device
+}
+
+// ServerOpts are options for a Server:
+//   - MaxBatchSize: maximum number of samples in a batch. Default=32
+//   - MaxLatency: maximum time a batch waits for more samples after its first sample. Default=5ms
+//   - QueueSize: maximum number of pending requests. Requests beyond are rejected with ErrQueueFull. Default=1024
+type ServerOpts struct {
+	MaxBatchSize int
+	MaxLatency   time.Duration
+	QueueSize    int
+}
+
+type ServerOpt func(*ServerOpts)
+
+func OptMaxBatchSize(v int) ServerOpt {
+	return func(o *ServerOpts) {
+		o.MaxBatchSize = v
+	}
+}
+
+func OptMaxLatency(v time.Duration) ServerOpt {
+	return func(o *ServerOpts) {
+		o.MaxLatency = v
+	}
+}
+
+func OptQueueSize(v int) ServerOpt {
+	return func(o *ServerOpts) {
+		o.QueueSize = v
+	}
+}
+
+func DefaultServerOpts() *ServerOpts {
+	return &ServerOpts{
+		MaxBatchSize: 32,
+		MaxLatency:   5 * time.Millisecond,
+		QueueSize:    1024,
+	}
+}
+
+type result struct {
+	output *ts.Tensor
+	err    error
+}
+
+type request struct {
+	ctx   context.Context
+	input *ts.Tensor
+	resp  chan result
+}
+
+// Server batches concurrent single-sample requests and runs them on a pool of model replicas.
+//
+// Requests are collected into a batch until either MaxBatchSize samples are queued or
+// MaxLatency has elapsed since the first sample. Batches are stacked along a new first
+// dimension and sent to the next idle replica, where the forward pass runs without
+// tracking gradients. When all replicas are busy, requests wait in a bounded queue;
+// once the queue is full, new requests are rejected with ErrQueueFull.
+type Server struct {
+	opts     *ServerOpts
+	replicas []Replica
+	queue    chan *request
+	batches  chan []*request
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewServer creates a server and starts its batching and replica goroutines.
+func NewServer(replicas []Replica, opts ...ServerOpt) (*Server, error) {
+	if len(replicas) == 0 {
+		err := fmt.Errorf("NewServer() failed: at least one replica is required")
+		return nil, err
+	}
+	for i, r := range replicas {
+		if r.Model == nil {
+			err := fmt.Errorf("NewServer() failed: replica %v has nil model", i)
+			return nil, err
+		}
+	}
+
+	o := DefaultServerOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.MaxBatchSize < 1 || o.QueueSize < 1 || o.MaxLatency < 0 {
+		err := fmt.Errorf("NewServer() failed: invalid options %+v", *o)
+		return nil, err
+	}
+
+	s := &Server{
+		opts:     o,
+		replicas: replicas,
+		queue:    make(chan *request, o.QueueSize),
+		batches:  make(chan []*request),
+	}
+
+	s.wg.Add(len(replicas))
+	for _, r := range replicas {
+		go s.runReplica(r)
+	}
+	go s.batchLoop()
+
+	return s, nil
+}
+
+// Predict sends a single sample (without batch dimension) to the server and waits for
+// its output. Returned output is on CPU.
+//
+// It returns ErrQueueFull immediately if the request queue is full and ctx.Err() if ctx
+// is done before the output is ready.
+func (s *Server) Predict(ctx context.Context, input *ts.Tensor) (*ts.Tensor, error) {
+	// The request holds its own reference to input as the caller may drop input
+	// once Predict returns (e.g. after ctx is done) while the batch is still running.
+	req := &request{
+		ctx:   ctx,
+		input: input.MustShallowClone(),
+		resp:  make(chan result, 1),
+	}
+
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		req.input.MustDrop()
+		return nil, ErrClosed
+	}
+	select {
+	case s.queue <- req:
+		s.mu.RUnlock()
+	default:
+		s.mu.RUnlock()
+		req.input.MustDrop()
+		return nil, ErrQueueFull
+	}
+
+	select {
+	case res := <-req.resp:
+		return res.output, res.err
+	case <-ctx.Done():
+		// A replica still responds to the request. Drop its output.
+		go func() {
+			if res := <-req.resp; res.output != nil {
+				res.output.MustDrop()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// Close stops accepting requests, waits for queued requests to complete and stops
+// replica goroutines.
+func (s *Server) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// batchLoop collects queued requests into batches and hands them to replicas.
+func (s *Server) batchLoop() {
+	defer close(s.batches)
+
+	timer := time.NewTimer(s.opts.MaxLatency)
+	if !timer.Stop() {
+		<-timer.C
+	}
+
+	for {
+		req, ok := <-s.queue
+		if !ok {
+			return
+		}
+
+		batch := []*request{req}
+		timer.Reset(s.opts.MaxLatency)
+		open := true
+	collect:
+		for len(batch) < s.opts.MaxBatchSize {
+			select {
+			case req, ok := <-s.queue:
+				if !ok {
+					open = false
+					break collect
+				}
+				batch = append(batch, req)
+			case <-timer.C:
+				break collect
+			}
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+
+		s.batches <- batch
+		if !open {
+			return
+		}
+	}
+}
+
+// runReplica runs batches on a replica until the server is closed.
+func (s *Server) runReplica(r Replica) {
+	defer s.wg.Done()
+
+	// Grad mode is thread local in libtorch.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	for batch := range s.batches {
+		ts.NoGrad(func() {
+			s.runBatch(r, batch)
+		})
+	}
+}
+
+func (s *Server) runBatch(r Replica, batch []*request) {
+	// Skip requests already given up by their callers.
+	var (
+		reqs   []*request
+		inputs []*ts.Tensor
+	)
+	defer func() {
+		for _, req := range batch {
+			req.input.MustDrop()
+		}
+	}()
+
+	for _, req := range batch {
+		if req.ctx.Err() != nil {
+			req.resp <- result{err: req.ctx.Err()}
+			continue
+		}
+		reqs = append(reqs, req)
+		inputs = append(inputs, req.input)
+	}
+	if len(reqs) == 0 {
+		return
+	}
+
+	outputs, err := forwardBatch(r, inputs)
+	if err != nil {
+		for _, req := range reqs {
+			req.resp <- result{err: err}
+		}
+		return
+	}
+	for i, req := range reqs {
+		if err := req.ctx.Err(); err != nil {
+			outputs[i].MustDrop()
+			req.resp <- result{err: err}
+			continue
+		}
+		req.resp <- result{output: outputs[i]}
+	}
+}
+
+// forwardBatch stacks inputs, runs a forward pass on a replica and splits outputs per sample.
+func forwardBatch(r Replica, inputs []*ts.Tensor) ([]*ts.Tensor, error) {
+	stacked, err := ts.Stack(inputs, 0)
+	if err != nil {
+		err = fmt.Errorf("forwardBatch() failed: stacking inputs: %w", err)
+		return nil, err
+	}
+	xs, err := stacked.To(r.Device, true)
+	if err != nil {
+		err = fmt.Errorf("forwardBatch() failed: %w", err)
+		return nil, err
+	}
+
+	out, err := r.Model.Forward(xs)
+	if err != nil {
+		xs.MustDrop()
+		err = fmt.Errorf("forwardBatch() failed: %w", err)
+		return nil, err
+	}
+	// A model may return its input (e.g. identity). It is then dropped by `To()` below.
+	if out != xs {
+		xs.MustDrop()
+	}
+	out, err = out.To(gotch.CPU, true)
+	if err != nil {
+		err = fmt.Errorf("forwardBatch() failed: %w", err)
+		return nil, err
+	}
+
+	size, err := out.Size()
+	if err != nil || len(size) == 0 || size[0] != int64(len(inputs)) {
+		out.MustDrop()
+		err = fmt.Errorf("forwardBatch() failed: expected output batch size %v, got shape %v", len(inputs), size)
+		return nil, err
+	}
+
+	outputs := make([]*ts.Tensor, len(inputs))
+	for i := range inputs {
+		outputs[i] = out.MustSelect(0, int64(i), false).MustContiguous(true)
+	}
+	out.MustDrop()
+
+	return outputs, nil
+}
@@ -0,0 +1,209 @@
+package serve_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	"github.com/sugarme/gotch/serve"
+	"github.com/sugarme/gotch/ts"
+)
+
+// doubleModel doubles its inputs and records batch sizes.
+type doubleModel struct {
+	mu      sync.Mutex
+	sizes   []int64
+	started chan struct{}
+	release chan struct{}
+}
+
+func (m *doubleModel) Forward(batch *ts.Tensor) (*ts.Tensor, error) {
+	m.mu.Lock()
+	m.sizes = append(m.sizes, batch.MustSize()[0])
+	m.mu.Unlock()
+
+	if m.started != nil {
+		m.started <- struct{}{}
+		<-m.release
+	}
+
+	return batch.MustMulScalar(ts.FloatScalar(2), false), nil
+}
+
+func TestServer_Batching(t *testing.T) {
+	model := &doubleModel{}
+	s, err := serve.NewServer([]serve.Replica{{Model: model, Device: gotch.CPU}}, serve.OptMaxBatchSize(8), serve.OptMaxLatency(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			x := ts.MustOfSlice([]float32{float32(i), 1})
+			out, err := s.Predict(context.Background(), x)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			if got := out.Float64Values(); got[0] != float64(2*i) || got[1] != 2 {
+				errs[i] = errors.New("unexpected output")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("request %v: %v\n", i, err)
+		}
+	}
+
+	var total, largest int64
+	for _, size := range model.sizes {
+		total += size
+		if size > largest {
+			largest = size
+		}
+	}
+	if total != n || largest > 8 || largest < 2 {
+		t.Errorf("want %v samples batched in batches of at most 8, got batch sizes %v\n", n, model.sizes)
+	}
+}
+
+// identityModel returns its input batch as is.
+type identityModel struct{}
+
+func (identityModel) Forward(batch *ts.Tensor) (*ts.Tensor, error) {
+	return batch, nil
+}
+
+func TestServer_IdentityModel(t *testing.T) {
+	s, err := serve.NewServer([]serve.Replica{{Model: identityModel{}, Device: gotch.CPU}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	out, err := s.Predict(context.Background(), ts.MustOfSlice([]float32{3, 4}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.Float64Values(); got[0] != 3 || got[1] != 4 {
+		t.Errorf("want output [3 4], got %v\n", got)
+	}
+}
+
+func TestServer_Backpressure(t *testing.T) {
+	model := &doubleModel{
+		started: make(chan struct{}, 16),
+		release: make(chan struct{}),
+	}
+	s, err := serve.NewServer([]serve.Replica{{Model: model, Device: gotch.CPU}}, serve.OptMaxBatchSize(1), serve.OptQueueSize(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x := ts.MustOfSlice([]float32{1})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.Predict(context.Background(), x)
+	}()
+	<-model.started
+
+	// The busy replica holds back the batcher and then the queue.
+	var full bool
+	for i := 0; i < 20 && !full; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err := s.Predict(ctx, x)
+		cancel()
+		full = errors.Is(err, serve.ErrQueueFull)
+	}
+	if !full {
+		t.Errorf("want ErrQueueFull while replica is busy\n")
+	}
+
+	close(model.release)
+	wg.Wait()
+	s.Close()
+
+	if _, err := s.Predict(context.Background(), x); !errors.Is(err, serve.ErrClosed) {
+		t.Errorf("want ErrClosed after Close, got %v\n", err)
+	}
+}
+
+func TestServer_HTTP(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	linear := nn.NewLinear(vs.Root(), 3, 2, nn.DefaultLinearConfig())
+	replicas := []serve.Replica{
+		{Model: serve.NewModuleT(linear), Device: gotch.CPU},
+		{Model: serve.NewModuleT(linear), Device: gotch.CPU},
+	}
+	s, err := serve.NewServer(replicas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	body, _ := json.Marshal(serve.TensorJSON{Data: []float64{1, 2, 3}, Shape: []int64{3}})
+	resp, err := http.Post(srv.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want status 200, got %v\n", resp.StatusCode)
+	}
+	var out serve.TensorJSON
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := linear.Forward(ts.MustOfSlice([]float32{1, 2, 3}).MustView([]int64{1, 3}, true)).Float64Values()
+	if len(out.Shape) != 1 || out.Shape[0] != 2 || len(out.Data) != 2 {
+		t.Fatalf("want output of shape [2], got %+v\n", out)
+	}
+	for i := range want {
+		if math.Abs(want[i]-out.Data[i]) > 1e-5 {
+			t.Errorf("want %v, got %v\n", want, out.Data)
+		}
+	}
+
+	body, _ = json.Marshal(serve.TensorJSON{Data: []float64{1, 2}, Shape: []int64{3}})
+	resp, err = http.Post(srv.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("want status 400 for mismatched shape, got %v\n", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("want status 405 for GET, got %v\n", resp.StatusCode)
+	}
+}
@@ -0,0 +1,31 @@
+package tensorboard
+
+import (
+	"fmt"
+
+	"github.com/sugarme/gotch/nn"
+)
+
+// NewCallback creates a nn.Trainer callback writing epoch logs (e.g. "loss", "val_loss")
+// as scalars and learning rates of all optimizer parameter groups (see AddLRs) at the
+// end of every epoch. Epochs are used as steps.
+func NewCallback(w *Writer) nn.Callback {
+	return nn.CallbackFuncs{
+		EpochEnd: func(t *nn.Trainer, epoch int, logs map[string]float64) error {
+			for tag, value := range logs {
+				if tag == "lr" { // written per parameter group below.
+					continue
+				}
+				if err := w.AddScalar(tag, value, int64(epoch)); err != nil {
+					err = fmt.Errorf("tensorboard callback - writing scalar failed: %w", err)
+					return err
+				}
+			}
+			if err := w.AddLRs(t.Optimizer, int64(epoch)); err != nil {
+				err = fmt.Errorf("tensorboard callback - writing learning rates failed: %w", err)
+				return err
+			}
+			return nil
+		},
+	}
+}
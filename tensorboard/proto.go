@@ -0,0 +1,125 @@
+package tensorboard
+
+// Minimal protobuf encoding of TensorBoard Event and Summary messages.
+//
+// Field numbers follow tensorflow/core/util/event.proto, tensorflow/core/framework/summary.proto
+// and tensorboard/plugins/hparams/plugin_data.proto.
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// message is a protobuf message being encoded.
+type message []byte
+
+func (m message) key(field int, wire int) message {
+	return m.varint(uint64(field<<3 | wire))
+}
+
+func (m message) varint(v uint64) message {
+	return binary.AppendUvarint(m, v)
+}
+
+func (m message) int64(field int, v int64) message {
+	return m.key(field, wireVarint).varint(uint64(v))
+}
+
+func (m message) double(field int, v float64) message {
+	return binary.LittleEndian.AppendUint64(m.key(field, wireFixed64), math.Float64bits(v))
+}
+
+func (m message) float(field int, v float32) message {
+	return binary.LittleEndian.AppendUint32(m.key(field, wireFixed32), math.Float32bits(v))
+}
+
+func (m message) bytes(field int, v []byte) message {
+	return append(m.key(field, wireBytes).varint(uint64(len(v))), v...)
+}
+
+func (m message) string(field int, v string) message {
+	return m.bytes(field, []byte(v))
+}
+
+func (m message) message(field int, v message) message {
+	return m.bytes(field, v)
+}
+
+// packedDoubles encodes a packed repeated double field.
+func (m message) packedDoubles(field int, vs []float64) message {
+	var packed []byte
+	for _, v := range vs {
+		packed = binary.LittleEndian.AppendUint64(packed, math.Float64bits(v))
+	}
+	return m.bytes(field, packed)
+}
+
+// Event fields.
+const (
+	eventWallTime    = 1
+	eventStep        = 2
+	eventFileVersion = 3
+	eventSummary     = 5
+)
+
+// Summary.Value fields.
+const (
+	valueTag         = 1
+	valueSimpleValue = 2
+	valueImage       = 4
+	valueHisto       = 5
+	valueTensor      = 8
+	valueMetadata    = 9
+)
+
+// summary encodes a Summary with a single value.
+func summary(value message) message {
+	return message{}.message(1, value)
+}
+
+// pluginMetadata encodes SummaryMetadata with plugin data.
+func pluginMetadata(plugin string, content []byte) message {
+	pluginData := message{}.string(1, plugin)
+	if len(content) > 0 {
+		pluginData = pluginData.bytes(2, content)
+	}
+	return message{}.message(1, pluginData)
+}
+
+// stringTensor encodes a TensorProto holding a single string of shape [1].
+func stringTensor(s string) message {
+	const dtString = 7
+	shape := message{}.message(2, message{}.int64(1, 1))
+	return message{}.int64(1, dtString).message(2, shape).string(8, s)
+}
+
+// protoValue encodes a google.protobuf.Value from a Go value.
+func protoValue(v interface{}) message {
+	switch x := v.(type) {
+	case bool:
+		b := int64(0)
+		if x {
+			b = 1
+		}
+		return message{}.int64(4, b)
+	case string:
+		return message{}.string(3, x)
+	case int:
+		return message{}.double(2, float64(x))
+	case int64:
+		return message{}.double(2, float64(x))
+	case float32:
+		return message{}.double(2, float64(x))
+	case float64:
+		return message{}.double(2, x)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,36 @@
+package tensorboard
+
+// TFRecord framing.
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+)
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// maskedCRC returns the masked CRC32C checksum of data as used in TFRecord files.
+func maskedCRC(data []byte) uint32 {
+	crc := crc32.Checksum(data, crc32c)
+	return ((crc >> 15) | (crc << 17)) + 0xa282ead8
+}
+
+// writeRecord writes data as a TFRecord:
+//
+//	uint64 length
+//	uint32 masked crc of length
+//	byte   data[length]
+//	uint32 masked crc of data
+func writeRecord(w io.Writer, data []byte) error {
+	header := binary.LittleEndian.AppendUint64(nil, uint64(len(data)))
+	header = binary.LittleEndian.AppendUint32(header, maskedCRC(header))
+	footer := binary.LittleEndian.AppendUint32(nil, maskedCRC(data))
+
+	for _, b := range [][]byte{header, data, footer} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+// Package tensorboard writes TensorBoard event files (tfevents) without TensorFlow.
+//
+// Example:
+//
+//	w, err := tensorboard.NewWriter("runs/exp1")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer w.Close()
+//	w.AddScalar("loss", loss, step)
+//
+// Then run `tensorboard --logdir runs`.
+package tensorboard
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	"github.com/sugarme/gotch/ts"
+)
+
+// HistogramBins is number of buckets of histograms written by AddHistogram.
+var HistogramBins = 30
+
+// Writer writes summaries to an event file in a log directory.
+//
+// Writer is safe for concurrent use.
+type Writer struct {
+	mu   sync.Mutex
+	file *os.File
+	path string
+}
+
+// NewWriter creates a log directory if needed and a new event file in it.
+func NewWriter(logdir string) (*Writer, error) {
+	if err := os.MkdirAll(logdir, 0755); err != nil {
+		err = fmt.Errorf("NewWriter() failed: %w", err)
+		return nil, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	now := time.Now()
+	path := filepath.Join(logdir, fmt.Sprintf("events.out.tfevents.%d.%s.%d", now.Unix(), hostname, now.Nanosecond()))
+	file, err := os.Create(path)
+	if err != nil {
+		err = fmt.Errorf("NewWriter() failed: %w", err)
+		return nil, err
+	}
+
+	w := &Writer{file: file, path: path}
+	event := message{}.double(eventWallTime, wallTime()).string(eventFileVersion, "brain.Event:2")
+	if err := w.writeRecord(event); err != nil {
+		file.Close()
+		err = fmt.Errorf("NewWriter() failed: %w", err)
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func wallTime() float64 {
+	return float64(time.Now().UnixNano()) / 1e9
+}
+
+// Path returns path of the event file.
+func (w *Writer) Path() string {
+	return w.path
+}
+
+func (w *Writer) writeRecord(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return writeRecord(w.file, data)
+}
+
+// writeSummary writes an event holding a summary value.
+func (w *Writer) writeSummary(value message, step int64) error {
+	event := message{}.double(eventWallTime, wallTime()).int64(eventStep, step).message(eventSummary, summary(value))
+	return w.writeRecord(event)
+}
+
+// AddScalar writes a scalar value.
+func (w *Writer) AddScalar(tag string, value float64, step int64) error {
+	v := message{}.string(valueTag, tag).float(valueSimpleValue, float32(value))
+	if err := w.writeSummary(v, step); err != nil {
+		return fmt.Errorf("AddScalar() failed: %w", err)
+	}
+	return nil
+}
+
+// AddHistogram writes a histogram of all values of a tensor in HistogramBins buckets of equal width.
+func (w *Writer) AddHistogram(tag string, values *ts.Tensor, step int64) error {
+	histo, err := histogram(values.Float64Values(), HistogramBins)
+	if err != nil {
+		return fmt.Errorf("AddHistogram() failed: %w", err)
+	}
+
+	v := message{}.string(valueTag, tag).message(valueHisto, histo)
+	if err := w.writeSummary(v, step); err != nil {
+		return fmt.Errorf("AddHistogram() failed: %w", err)
+	}
+	return nil
+}
+
+// histogram encodes a HistogramProto.
+func histogram(values []float64, bins int) (message, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("empty values")
+	}
+
+	min, max := math.Inf(1), math.Inf(-1)
+	var sum, sumSquares float64
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("non-finite value %v", v)
+		}
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+		sum += v
+		sumSquares += v * v
+	}
+
+	if max == min {
+		bins = 1
+	}
+	width := (max - min) / float64(bins)
+	limits := make([]float64, bins)
+	counts := make([]float64, bins)
+	for i := range limits {
+		limits[i] = min + float64(i+1)*width
+	}
+	limits[bins-1] = max
+	for _, v := range values {
+		i := bins - 1
+		if width > 0 {
+			i = int((v - min) / width)
+			if i >= bins {
+				i = bins - 1
+			}
+		}
+		counts[i]++
+	}
+
+	return message{}.
+		double(1, min).
+		double(2, max).
+		double(3, float64(len(values))).
+		double(4, sum).
+		double(5, sumSquares).
+		packedDoubles(6, limits).
+		packedDoubles(7, counts), nil
+}
+
+// AddImage writes an image tensor of shape [channel, height, width] (or with a leading batch
+// dimension of size 1) with 1 (grayscale), 3 (RGB) or 4 (RGBA) channels. As in `vision.Save`,
+// values should range from 0 to 255.
+func (w *Writer) AddImage(tag string, img *ts.Tensor, step int64) error {
+	encoded, err := imageSummary(img)
+	if err != nil {
+		return fmt.Errorf("AddImage() failed: %w", err)
+	}
+
+	v := message{}.string(valueTag, tag).message(valueImage, encoded)
+	if err := w.writeSummary(v, step); err != nil {
+		return fmt.Errorf("AddImage() failed: %w", err)
+	}
+	return nil
+}
+
+// imageSummary encodes a Summary.Image with PNG data.
+func imageSummary(img *ts.Tensor) (message, error) {
+	shape := img.MustSize()
+	if len(shape) == 4 && shape[0] == 1 {
+		shape = shape[1:]
+	}
+	if len(shape) != 3 || (shape[0] != 1 && shape[0] != 3 && shape[0] != 4) {
+		return nil, fmt.Errorf("expected image of shape [C, H, W] with 1, 3 or 4 channels, got %v", img.MustSize())
+	}
+	c, h, wd := int(shape[0]), int(shape[1]), int(shape[2])
+
+	x := img.MustTo(gotch.CPU, false).MustTotype(gotch.Uint8, true).MustView([]int64{shape[0], shape[1], shape[2]}, true)
+	pixels := x.MustPermute([]int64{1, 2, 0}, true).MustContiguous(true).Int64Values(true)
+
+	var m image.Image
+	switch c {
+	case 1:
+		gray := image.NewGray(image.Rect(0, 0, wd, h))
+		for i, p := range pixels {
+			gray.Pix[i] = uint8(p)
+		}
+		m = gray
+	default:
+		rgba := image.NewNRGBA(image.Rect(0, 0, wd, h))
+		for i := 0; i < h*wd; i++ {
+			px := pixels[i*c : (i+1)*c]
+			a := uint8(255)
+			if c == 4 {
+				a = uint8(px[3])
+			}
+			rgba.SetNRGBA(i%wd, i/wd, color.NRGBA{uint8(px[0]), uint8(px[1]), uint8(px[2]), a})
+		}
+		m = rgba
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		return nil, err
+	}
+
+	return message{}.
+		int64(1, int64(h)).
+		int64(2, int64(wd)).
+		int64(3, int64(c)).
+		bytes(4, buf.Bytes()), nil
+}
+
+// AddText writes a text summary shown in TensorBoard text dashboard. Markdown is supported.
+func (w *Writer) AddText(tag, text string, step int64) error {
+	v := message{}.
+		string(valueTag, tag).
+		message(valueMetadata, pluginMetadata("text", nil)).
+		message(valueTensor, stringTensor(text))
+	if err := w.writeSummary(v, step); err != nil {
+		return fmt.Errorf("AddText() failed: %w", err)
+	}
+	return nil
+}
+
+// AddHparams writes hyperparameters of a run and its resulting metrics for TensorBoard
+// HParams dashboard. Hparam values can be bool, string, int, int64, float32 or float64.
+func (w *Writer) AddHparams(hparams map[string]interface{}, metrics map[string]float64) error {
+	names := make([]string, 0, len(hparams))
+	for name := range hparams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	metricNames := make([]string, 0, len(metrics))
+	for name := range metrics {
+		metricNames = append(metricNames, name)
+	}
+	sort.Strings(metricNames)
+
+	var experiment, start message
+	for _, name := range names {
+		value := protoValue(hparams[name])
+		if value == nil {
+			return fmt.Errorf("AddHparams() failed: unsupported type %T of hparam %q", hparams[name], name)
+		}
+		experiment = experiment.message(4, message{}.string(1, name))
+		start = start.message(1, message{}.string(1, name).message(2, value))
+	}
+	for _, name := range metricNames {
+		experiment = experiment.message(5, message{}.message(1, message{}.string(2, name)))
+	}
+	start = start.double(5, wallTime())
+	end := message{}.int64(1, 1).double(2, wallTime()) // STATUS_SUCCESS
+
+	// HParamsPluginData oneof: experiment = 2, session_start_info = 3, session_end_info = 4.
+	writePlugin := func(tag string, field int, data message) error {
+		content := message{}.message(field, data)
+		v := message{}.string(valueTag, tag).message(valueMetadata, pluginMetadata("hparams", content))
+		return w.writeSummary(v, 0)
+	}
+
+	if err := writePlugin("_hparams_/experiment", 2, experiment); err != nil {
+		return fmt.Errorf("AddHparams() failed: %w", err)
+	}
+	if err := writePlugin("_hparams_/session_start_info", 3, start); err != nil {
+		return fmt.Errorf("AddHparams() failed: %w", err)
+	}
+	for _, name := range metricNames {
+		if err := w.AddScalar(name, metrics[name], 0); err != nil {
+			return fmt.Errorf("AddHparams() failed: %w", err)
+		}
+	}
+	if err := writePlugin("_hparams_/session_end_info", 4, end); err != nil {
+		return fmt.Errorf("AddHparams() failed: %w", err)
+	}
+
+	return nil
+}
+
+// AddLRs writes learning rates of all optimizer parameter groups as scalars
+// tagged "lr/group_<i>".
+func (w *Writer) AddLRs(opt *nn.Optimizer, step int64) error {
+	for i, lr := range opt.GetLRs() {
+		if err := w.AddScalar(fmt.Sprintf("lr/group_%d", i), lr, step); err != nil {
+			return fmt.Errorf("AddLRs() failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// Flush commits written events to storage.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Sync()
+}
+
+// Close flushes and closes the event file.
+func (w *Writer) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return w.file.Close()
+}
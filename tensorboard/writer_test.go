@@ -0,0 +1,241 @@
+package tensorboard_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image/png"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	"github.com/sugarme/gotch/tensorboard"
+	"github.com/sugarme/gotch/ts"
+)
+
+// fields are decoded protobuf fields: uint64 for varint and fixed-size fields, []byte for
+// length-delimited fields.
+type fields map[int][]interface{}
+
+func decode(t *testing.T, data []byte) fields {
+	t.Helper()
+	f := make(fields)
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		data = data[n:]
+		field, wire := int(key>>3), key&7
+		switch wire {
+		case 0:
+			v, n := binary.Uvarint(data)
+			f[field] = append(f[field], v)
+			data = data[n:]
+		case 1:
+			f[field] = append(f[field], binary.LittleEndian.Uint64(data))
+			data = data[8:]
+		case 2:
+			l, n := binary.Uvarint(data)
+			f[field] = append(f[field], data[n:n+int(l)])
+			data = data[n+int(l):]
+		case 5:
+			f[field] = append(f[field], uint64(binary.LittleEndian.Uint32(data)))
+			data = data[4:]
+		default:
+			t.Fatalf("unexpected wire type %v", wire)
+		}
+	}
+	return f
+}
+
+func (f fields) bytes(field int) []byte {
+	return f[field][0].([]byte)
+}
+
+func (f fields) sub(t *testing.T, field int) fields {
+	return decode(t, f.bytes(field))
+}
+
+func masked(data []byte) uint32 {
+	crc := crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli))
+	return ((crc >> 15) | (crc << 17)) + 0xa282ead8
+}
+
+// TestMasked checks masked against the CRC32C check value of "123456789" (0xe3069283)
+// rotated right by 15 bits plus the TFRecord mask delta 0xa282ead8, so that record
+// checks in readEvents do not only compare the writer with itself.
+func TestMasked(t *testing.T) {
+	data := []byte("123456789")
+	if crc := crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)); crc != 0xe3069283 {
+		t.Fatalf("want crc32c 0xe3069283, got %#x\n", crc)
+	}
+	if got := masked(data); got != 0xc78ab0e5 {
+		t.Errorf("want masked crc 0xc78ab0e5, got %#x\n", got)
+	}
+}
+
+// readEvents reads and verifies records of an event file.
+func readEvents(t *testing.T, path string) []fields {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []fields
+	for len(data) > 0 {
+		l := binary.LittleEndian.Uint64(data)
+		if masked(data[:8]) != binary.LittleEndian.Uint32(data[8:12]) {
+			t.Fatalf("invalid length crc")
+		}
+		record := data[12 : 12+l]
+		if masked(record) != binary.LittleEndian.Uint32(data[12+l:]) {
+			t.Fatalf("invalid data crc")
+		}
+		events = append(events, decode(t, record))
+		data = data[16+l:]
+	}
+	return events
+}
+
+// summaryValue returns Summary.Value of an event.
+func summaryValue(t *testing.T, event fields) fields {
+	return event.sub(t, 5).sub(t, 1)
+}
+
+func TestWriter(t *testing.T) {
+	w, err := tensorboard.NewWriter(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.AddScalar("loss", 0.25, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddHistogram("weights", ts.MustOfSlice([]float32{0, 1, 1, 2, 4}), 3); err != nil {
+		t.Fatal(err)
+	}
+	img := ts.MustOfSlice([]uint8{255, 0, 0, 0, 255, 0, 0, 0, 255, 10, 20, 30}).MustView([]int64{3, 2, 2}, true)
+	if err := w.AddImage("image", img, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddText("notes", "**hello**", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddHparams(map[string]interface{}{"lr": 0.1, "optimizer": "adam"}, map[string]float64{"hparam/acc": 0.9}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddImage("bad", ts.MustOfSlice([]float32{1, 2}), 0); err == nil {
+		t.Errorf("want error for invalid image shape\n")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	events := readEvents(t, w.Path())
+	if len(events) != 9 {
+		t.Fatalf("want 9 events, got %v\n", len(events))
+	}
+	if v := string(events[0].bytes(3)); v != "brain.Event:2" {
+		t.Errorf("want file version event, got %q\n", v)
+	}
+
+	// Scalar.
+	if step := events[1][2][0].(uint64); step != 3 {
+		t.Errorf("want step 3, got %v\n", step)
+	}
+	scalar := summaryValue(t, events[1])
+	if tag := string(scalar.bytes(1)); tag != "loss" {
+		t.Errorf("want tag loss, got %q\n", tag)
+	}
+	if v := math.Float32frombits(uint32(scalar[2][0].(uint64))); v != 0.25 {
+		t.Errorf("want scalar 0.25, got %v\n", v)
+	}
+
+	// Histogram.
+	histo := summaryValue(t, events[2]).sub(t, 5)
+	min := math.Float64frombits(histo[1][0].(uint64))
+	max := math.Float64frombits(histo[2][0].(uint64))
+	num := math.Float64frombits(histo[3][0].(uint64))
+	sum := math.Float64frombits(histo[4][0].(uint64))
+	if min != 0 || max != 4 || num != 5 || sum != 8 {
+		t.Errorf("unexpected histogram min %v, max %v, num %v, sum %v\n", min, max, num, sum)
+	}
+	var count float64
+	counts := histo.bytes(7)
+	for i := 0; i < len(counts); i += 8 {
+		count += math.Float64frombits(binary.LittleEndian.Uint64(counts[i:]))
+	}
+	if count != 5 {
+		t.Errorf("want bucket counts sum to 5, got %v\n", count)
+	}
+
+	// Image.
+	image := summaryValue(t, events[3]).sub(t, 4)
+	if image[1][0].(uint64) != 2 || image[2][0].(uint64) != 2 || image[3][0].(uint64) != 3 {
+		t.Errorf("unexpected image height, width, colorspace: %v\n", image)
+	}
+	decoded, err := png.Decode(bytes.NewReader(image.bytes(4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pixel (0, 1) has red 0, green 0, blue 20.
+	if r, g, b, _ := decoded.At(0, 1).RGBA(); r>>8 != 0 || g>>8 != 0 || b>>8 != 20 {
+		t.Errorf("unexpected pixel %v %v %v\n", r>>8, g>>8, b>>8)
+	}
+
+	// Text.
+	text := summaryValue(t, events[4])
+	if plugin := string(text.sub(t, 9).sub(t, 1).bytes(1)); plugin != "text" {
+		t.Errorf("want text plugin, got %q\n", plugin)
+	}
+	if s := string(text.sub(t, 8).bytes(8)); s != "**hello**" {
+		t.Errorf("want text **hello**, got %q\n", s)
+	}
+
+	// Hparams.
+	wantTags := []string{"_hparams_/experiment", "_hparams_/session_start_info", "hparam/acc", "_hparams_/session_end_info"}
+	for i, want := range wantTags {
+		if tag := string(summaryValue(t, events[5+i]).bytes(1)); tag != want {
+			t.Errorf("want tag %q, got %q\n", want, tag)
+		}
+	}
+	content := summaryValue(t, events[6]).sub(t, 9).sub(t, 1).sub(t, 2)
+	start := content.sub(t, 3)
+	if len(start[1]) != 2 {
+		t.Errorf("want 2 hparams in session start info, got %v\n", len(start[1]))
+	}
+}
+
+func TestCallback(t *testing.T) {
+	w, err := tensorboard.NewWriter(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vs := nn.NewVarStore(gotch.CPU)
+	model := nn.NewLinear(vs.Root(), 2, 1, nn.DefaultLinearConfig())
+	opt, err := nn.DefaultSGDConfig().Build(vs, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mse := func(logits, targets *ts.Tensor) *ts.Tensor {
+		return logits.MustMseLoss(targets, 1, false)
+	}
+	trainer := nn.NewTrainer(model, mse, opt, nn.OptEpochsTrainer(2), nn.OptCallbacksTrainer(tensorboard.NewCallback(w)))
+
+	xs := ts.MustRandn([]int64{8, 2}, gotch.Float, gotch.CPU)
+	ys := ts.MustRandn([]int64{8, 1}, gotch.Float, gotch.CPU)
+	if _, err := trainer.Fit(nn.Iter2Loader(xs, ys, 4, false), nil); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	tags := make(map[string]int)
+	for _, event := range readEvents(t, w.Path())[1:] {
+		tags[string(summaryValue(t, event).bytes(1))]++
+	}
+	if tags["loss"] != 2 || tags["lr/group_0"] != 2 || len(tags) != 2 {
+		t.Errorf("want loss and lr/group_0 written for 2 epochs, got %v\n", tags)
+	}
+}
@@ -20,6 +20,19 @@ func LoadHwc(path string) (*Tensor, error) {
 	return newTensor(ctensor), nil
 }
 
+// LoadHwcFromMemory decodes an encoded image (e.g. jpg, png) and returns a tensor of
+// shape [height, width, channels] on success.
+func LoadHwcFromMemory(data []byte) (*Tensor, error) {
+
+	ctensor := lib.AtLoadImageFromMemory(data)
+	err := TorchErr()
+	if err != nil {
+		return nil, err
+	}
+
+	return newTensor(ctensor), nil
+}
+
 // SaveHwc save an image from tensor. It expects a tensor of shape [height,
 // width, channels]
 func SaveHwc(ts *Tensor, path string) error {
@@ -0,0 +1,242 @@
+package ts
+
+// Composite TorchScript values and conversion of IValue to Go values.
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/sugarme/gotch"
+)
+
+// NamedTuple is a TorchScript NamedTuple value.
+type NamedTuple struct {
+	Fields []string
+	Values []*IValue
+}
+
+// Get returns value of a named tuple field.
+func (nt *NamedTuple) Get(field string) (*IValue, bool) {
+	for i, f := range nt.Fields {
+		if f == field {
+			return nt.Values[i], true
+		}
+	}
+	return nil, false
+}
+
+// Object is an instance of a TorchScript class (custom object) returned from a module.
+type Object struct {
+	ClassName string
+	Attrs     map[string]*IValue
+}
+
+var (
+	ivalueType = reflect.TypeOf(&IValue{})
+	tensorType = reflect.TypeOf(&Tensor{})
+	deviceType = reflect.TypeOf(gotch.Device{})
+)
+
+// Unmarshal stores the ivalue in the value pointed to by out.
+//
+// Structs can be filled from string-keyed dicts (e.g. `Dict[str, Tensor]`), named tuples,
+// objects and plain tuples (by field order). Struct fields are matched by `ivalue` tag
+// or case-insensitively by field name. Fields tagged with `ivalue:"-"` are skipped.
+// None values (e.g. `Optional[Tensor]`) leave fields at their zero values.
+//
+// Example:
+//
+//	type Detection struct {
+//		Boxes  *ts.Tensor `ivalue:"boxes"`
+//		Scores *ts.Tensor `ivalue:"scores"`
+//		Labels *ts.Tensor `ivalue:"labels"`
+//		Masks  *ts.Tensor `ivalue:"masks"` // Optional[Tensor]
+//	}
+//	var dets []Detection
+//	err := out.Unmarshal(&dets)
+func (iv *IValue) Unmarshal(out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		err := fmt.Errorf("Unmarshal() failed: expected non-nil pointer, got %T", out)
+		return err
+	}
+
+	if err := assignIValue(v.Elem(), iv); err != nil {
+		err = fmt.Errorf("Unmarshal() failed: %w", err)
+		return err
+	}
+
+	return nil
+}
+
+// assignIValue assigns src to dst. Src can be an *IValue or a Go value held by an IValue.
+func assignIValue(dst reflect.Value, src interface{}) error {
+	if iv, ok := src.(*IValue); ok {
+		if dst.Type() == ivalueType {
+			dst.Set(reflect.ValueOf(iv))
+			return nil
+		}
+		if iv == nil || iv.kind == NoneVal {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		src = iv.value
+	}
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	sv := reflect.ValueOf(src)
+	if sv.Type().AssignableTo(dst.Type()) {
+		dst.Set(sv)
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.Type() == tensorType {
+			return fmt.Errorf("can not assign %T to *Tensor", src)
+		}
+		elem := reflect.New(dst.Type().Elem())
+		if err := assignIValue(elem.Elem(), src); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+
+	case reflect.Struct:
+		if dst.Type() == deviceType {
+			return fmt.Errorf("can not assign %T to gotch.Device", src)
+		}
+		return assignStruct(dst, src)
+
+	case reflect.Slice:
+		if sv.Kind() != reflect.Slice {
+			return fmt.Errorf("can not assign %T to %v", src, dst.Type())
+		}
+		s := reflect.MakeSlice(dst.Type(), sv.Len(), sv.Len())
+		for i := 0; i < sv.Len(); i++ {
+			if err := assignIValue(s.Index(i), sv.Index(i).Interface()); err != nil {
+				return fmt.Errorf("index %v: %w", i, err)
+			}
+		}
+		dst.Set(s)
+		return nil
+
+	case reflect.Map:
+		if sv.Kind() != reflect.Map || !sv.Type().Key().ConvertibleTo(dst.Type().Key()) {
+			return fmt.Errorf("can not assign %T to %v", src, dst.Type())
+		}
+		m := reflect.MakeMapWithSize(dst.Type(), sv.Len())
+		iter := sv.MapRange()
+		for iter.Next() {
+			val := reflect.New(dst.Type().Elem()).Elem()
+			if err := assignIValue(val, iter.Value().Interface()); err != nil {
+				return fmt.Errorf("key %v: %w", iter.Key(), err)
+			}
+			m.SetMapIndex(iter.Key().Convert(dst.Type().Key()), val)
+		}
+		dst.Set(m)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		switch sv.Kind() {
+		case reflect.Int64, reflect.Float64:
+			dst.Set(sv.Convert(dst.Type()))
+			return nil
+		}
+
+	case reflect.Bool, reflect.String:
+		if sv.Kind() == dst.Kind() {
+			dst.Set(sv.Convert(dst.Type()))
+			return nil
+		}
+	}
+
+	return fmt.Errorf("can not assign %T to %v", src, dst.Type())
+}
+
+// assignStruct fills struct fields from a dict, named tuple, object or tuple.
+func assignStruct(dst reflect.Value, src interface{}) error {
+	var (
+		named map[string]interface{}
+		elems []interface{}
+	)
+
+	switch v := src.(type) {
+	case *NamedTuple:
+		named = make(map[string]interface{}, len(v.Fields))
+		for i, f := range v.Fields {
+			named[f] = v.Values[i]
+		}
+	case *Object:
+		named = make(map[string]interface{}, len(v.Attrs))
+		for k, attr := range v.Attrs {
+			named[k] = attr
+		}
+	default:
+		sv := reflect.ValueOf(src)
+		switch {
+		case sv.Kind() == reflect.Map && sv.Type().Key().Kind() == reflect.String:
+			named = make(map[string]interface{}, sv.Len())
+			iter := sv.MapRange()
+			for iter.Next() {
+				named[iter.Key().String()] = iter.Value().Interface()
+			}
+		case sv.Kind() == reflect.Slice:
+			for i := 0; i < sv.Len(); i++ {
+				elems = append(elems, sv.Index(i).Interface())
+			}
+		default:
+			return fmt.Errorf("can not assign %T to %v", src, dst.Type())
+		}
+	}
+
+	typ := dst.Type()
+	pos := 0
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" { // unexported
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("ivalue"); ok {
+			if tag == "-" {
+				continue
+			}
+			name = tag
+		}
+
+		var (
+			val   interface{}
+			found bool
+		)
+		if named != nil {
+			val, found = named[name]
+			if !found {
+				for k, v := range named {
+					if strings.EqualFold(k, name) {
+						val, found = v, true
+						break
+					}
+				}
+			}
+		} else if pos < len(elems) {
+			val, found = elems[pos], true
+			pos++
+		}
+		if !found {
+			continue
+		}
+
+		if err := assignIValue(dst.Field(i), val); err != nil {
+			return fmt.Errorf("field %q: %w", field.Name, err)
+		}
+	}
+
+	return nil
+}
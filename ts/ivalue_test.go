@@ -0,0 +1,161 @@
+package ts_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/ts"
+)
+
+func TestIValueOutputs(t *testing.T) {
+	src := `
+def forward(self, x):
+    return {"boxes": x, "scores": x * 2}
+
+def maybe(self, x, flag: bool) -> Optional[Tensor]:
+    if flag:
+        return x
+    return None
+
+def device(self, x):
+    return x.device
+
+def detections(self, x) -> List[Dict[str, Tensor]]:
+    return [{"boxes": x}, {"boxes": x + 1}]
+
+def mixed(self, x):
+    return x, 3, "label"
+
+def count(self, d: Dict[str, Tensor]) -> int:
+    return len(d)
+`
+	m, err := ts.CompileTorchScript(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := ts.TensorFrom([]float32{1, 2})
+
+	out, err := m.Method("forward", ts.NewIValue(x))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict, ok := out.Value().(map[string]*ts.Tensor)
+	if !ok || len(dict) != 2 {
+		t.Fatalf("Expected map[string]*Tensor with 2 entries, got %T %v\n", out.Value(), out.Value())
+	}
+	if got := dict["scores"].Float64Values(); !reflect.DeepEqual([]float64{2, 4}, got) {
+		t.Errorf("Expected scores [2 4], got %v\n", got)
+	}
+
+	var det struct {
+		Boxes  *ts.Tensor
+		Scores *ts.Tensor `ivalue:"scores"`
+		Masks  *ts.Tensor `ivalue:"masks"`
+	}
+	if err := out.Unmarshal(&det); err != nil {
+		t.Fatal(err)
+	}
+	if det.Boxes == nil || det.Scores == nil || det.Masks != nil {
+		t.Errorf("Unexpected unmarshalled value: %+v\n", det)
+	}
+
+	none, err := m.Method("maybe", ts.NewIValue(x), ts.NewIValue(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if none.Kind() != ts.NoneVal {
+		t.Errorf("Expected None, got %v\n", none.Name())
+	}
+
+	device, err := m.Method("device", ts.NewIValue(x))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.Kind() != ts.DeviceVal || device.Value().(gotch.Device) != gotch.CPU {
+		t.Errorf("Expected CPU device, got %v\n", device.Value())
+	}
+
+	dets, err := m.Method("detections", ts.NewIValue(x))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var boxes []struct {
+		Boxes *ts.Tensor `ivalue:"boxes"`
+	}
+	if err := dets.Unmarshal(&boxes); err != nil {
+		t.Fatal(err)
+	}
+	if len(boxes) != 2 || !reflect.DeepEqual([]float64{2, 3}, boxes[1].Boxes.Float64Values()) {
+		t.Errorf("Unexpected detections: %+v\n", boxes)
+	}
+
+	mixed, err := m.Method("mixed", ts.NewIValue(x))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tuple struct {
+		X     *ts.Tensor
+		N     int
+		Label string
+	}
+	if err := mixed.Unmarshal(&tuple); err != nil {
+		t.Fatal(err)
+	}
+	if tuple.N != 3 || tuple.Label != "label" {
+		t.Errorf("Unexpected tuple: %+v\n", tuple)
+	}
+
+	n, err := m.Method("count", ts.NewIValue(map[string]*ts.Tensor{"a": x, "b": x, "c": x}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Value().(int64) != 3 {
+		t.Errorf("Expected dict of 3 entries, got %v\n", n.Value())
+	}
+}
+
+func TestIValueUnmarshal(t *testing.T) {
+	x := ts.TensorFrom([]float32{1})
+	nt := &ts.NamedTuple{
+		Fields: []string{"logits", "score", "extra"},
+		Values: []*ts.IValue{ts.NewIValue(x), ts.NewIValue(0.5), ts.NewIValue(nil)},
+	}
+	obj := &ts.Object{
+		ClassName: "__torch__.Vocab",
+		Attrs: map[string]*ts.IValue{
+			"itos": ts.NewIValue([]string{"a", "b"}),
+			"size": ts.NewIValue(int64(2)),
+		},
+	}
+
+	type output struct {
+		Logits *ts.Tensor
+		Score  float32
+		Extra  *float64
+		Vocab  struct {
+			Itos []string
+			Size int
+		} `ivalue:"-"`
+	}
+
+	var out output
+	if err := ts.NewIValue(nt).Unmarshal(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Logits != x || out.Score != 0.5 || out.Extra != nil {
+		t.Errorf("Unexpected named tuple output: %+v\n", out)
+	}
+
+	if err := ts.NewIValue(obj).Unmarshal(&out.Vocab); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]string{"a", "b"}, out.Vocab.Itos) || out.Vocab.Size != 2 {
+		t.Errorf("Unexpected object output: %+v\n", out.Vocab)
+	}
+
+	var wrong struct{ Logits string }
+	if err := ts.NewIValue(nt).Unmarshal(&wrong); err == nil {
+		t.Errorf("Expected error for mismatched field type\n")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"io"
 	"log"
 	"reflect"
+	"runtime"
 	"unsafe"
 
 	"github.com/sugarme/gotch"
@@ -37,8 +38,11 @@ const (
 	StringVal                 // string
 	TensorListVal             // []*Tensor
 	GenericListVal            // []*IValue
-	GenericDictVal            // map[IValue]IValue - 2 elements
+	GenericDictVal            // map[K]V (e.g. map[string]*Tensor, map[string]*IValue)
 	GenericVal                // *IValue
+	DeviceVal                 // gotch.Device
+	NamedTupleVal             // *NamedTuple
+	ObjectVal                 // *Object
 )
 
 type IValue struct {
@@ -135,7 +139,25 @@ func NewIValue(v interface{}) *IValue {
 		// TODO: exclude map of type other than IValue type
 		retVal.kind = GenericDictVal
 		retVal.name = "GenericDict"
+	case "struct":
+		switch v.(type) {
+		case gotch.Device:
+			retVal.kind = DeviceVal
+			retVal.name = "Device"
+		default:
+			log.Fatalf("NewIValue method call - Unsupported type (%T)\n", v)
+		}
 	case "ptr":
+		switch v.(type) {
+		case *NamedTuple:
+			retVal.kind = NamedTupleVal
+			retVal.name = "NamedTuple"
+			return retVal
+		case *Object:
+			retVal.kind = ObjectVal
+			retVal.name = "Object"
+			return retVal
+		}
 		val := reflect.Indirect(reflect.ValueOf(v))
 		fieldName := val.Type().Field(2).Name
 		switch fieldName {
@@ -392,11 +414,31 @@ func (iv *IValue) ToCIValue() (*CIValue, error) {
 				cvals = append(cvals, cval.civalue)
 			}
 
-		// TODO: map[int64]Tensor
-		// TODO: map[float64]Tensor
-		// TODO: map[string]Tensor
-		// TODO: map[bool]Tensor
-		// ...
+		// string keys, e.g. map[string]*Tensor, map[string]*IValue, map[string]int64
+		case keyType == "string":
+			m := reflect.ValueOf(iv.value)
+			iter := m.MapRange()
+			for iter.Next() {
+				key, err := NewIValue(iter.Key().String()).ToCIValue()
+				if err != nil {
+					return nil, err
+				}
+				var val *IValue
+				switch v := iter.Value().Interface().(type) {
+				case *IValue:
+					val = v
+				case *Tensor:
+					val = &IValue{value: v, kind: TensorVal, name: "Tensor"}
+				default:
+					val = NewIValue(v)
+				}
+				cval, err := val.ToCIValue()
+				if err != nil {
+					err = fmt.Errorf("ToCIValue method call err - GenericDict case: %v\n", err)
+					return nil, err
+				}
+				cvals = append(cvals, key.civalue, cval.civalue)
+			}
 
 		default:
 			log.Fatalf("ToCIValue method call - GenericDict case: unsupported key type(%v) or value type(%v) \n", keyType, valType)
@@ -409,6 +451,36 @@ func (iv *IValue) ToCIValue() (*CIValue, error) {
 		}
 		return &CIValue{civalue: dict}, nil
 
+	case "Device":
+		cval := lib.AtiDevice(iv.value.(gotch.Device).CInt())
+		if err := TorchErr(); err != nil {
+			return nil, err
+		}
+		return &CIValue{civalue: cval}, nil
+
+	case "NamedTuple":
+		// NOTE. Field names are not passed, named tuple is passed as a plain tuple.
+		nt := iv.value.(*NamedTuple)
+		var cvals []lib.Civalue
+		for _, v := range nt.Values {
+			cval, err := v.ToCIValue()
+			if err != nil {
+				err = fmt.Errorf("ToCIValue method call err - NamedTuple case: %v\n", err)
+				return nil, err
+			}
+			cvals = append(cvals, cval.civalue)
+		}
+
+		tuple := lib.AtiTuple(cvals, len(cvals))
+		if err := TorchErr(); err != nil {
+			return nil, err
+		}
+		return &CIValue{civalue: tuple}, nil
+
+	case "Object":
+		err := fmt.Errorf("ToCIValue method call - Object case: TorchScript objects can not be created from Go")
+		return nil, err
+
 	case "Generic":
 		err := fmt.Errorf("ToCIValue method call - Generic case: unsupport type(%v)\n", reflect.TypeOf(iv.value).Kind().String())
 		return nil, err
@@ -480,45 +552,61 @@ func IValueFromC(cval *CIValue) (*IValue, error) {
 			name:  "Bool",
 		}, nil
 
-	case 5: // Tuple []IValue 2 elements
+	case 5: // Tuple []IValue
 		// 1. Determine tuple length
 		len := lib.AtiTupleLength(cval.civalue)
 		if err := TorchErr(); err != nil {
 			return nil, err
 		}
 		// 2. Call with first pointer and length
-		ptr1 := (*lib.Civalue)(unsafe.Pointer(C.malloc(0)))
-		lib.AtiToTuple(cval.civalue, ptr1, int(len))
-		if err := TorchErr(); err != nil {
+		civalues, err := civaluesFromC(int(len), func(ptr *lib.Civalue) {
+			lib.AtiToTuple(cval.civalue, ptr, int(len))
+		})
+		if err != nil {
 			return nil, err
 		}
 
-		// 3. Get list of Civalue tuple elements
-		var civalues []CIValue
-		civalues = append(civalues, CIValue{civalue: *ptr1})
-		currPtr := ptr1
-		for i := 1; i < int(len); i++ {
-			nextPtr := (*lib.Civalue)(unsafe.Pointer(uintptr(unsafe.Pointer(currPtr)) + unsafe.Sizeof(ptr1)))
-			civalues = append(civalues, CIValue{civalue: *nextPtr})
-			currPtr = nextPtr
-		}
-
-		// 4. Get Ivalue from Civalue for each tuple element
-		// Determine element kind
-		v, err := IValueFromC(&civalues[0])
-		if err != nil {
+		// 3. Named tuple
+		var data lib.NameData
+		dataPtr := lib.PStore.Set(&data)
+		defer lib.PStore.Free(dataPtr)
+		lib.AtiTupleFieldNames(cval.civalue, dataPtr)
+		if err := TorchErr(); err != nil {
 			return nil, err
 		}
-		elemName := v.Name()
-		switch elemName {
-		case "Tensor":
-			var vals []*Tensor
+		if data.Names != nil {
+			nt := &NamedTuple{Fields: data.Names}
 			for _, civalue := range civalues {
 				v, err := IValueFromC(&civalue)
 				if err != nil {
 					return nil, err
 				}
+				nt.Values = append(nt.Values, v)
+			}
+			return &IValue{
+				value: nt,
+				kind:  NamedTupleVal,
+				name:  "NamedTuple",
+			}, nil
+		}
 
+		// 4. Get Ivalue from Civalue for each tuple element
+		var ivalues []*IValue
+		allTensors := true
+		for _, civalue := range civalues {
+			v, err := IValueFromC(&civalue)
+			if err != nil {
+				return nil, err
+			}
+			if v.Name() != "Tensor" {
+				allTensors = false
+			}
+			ivalues = append(ivalues, v)
+		}
+
+		if allTensors && len > 0 {
+			var vals []*Tensor
+			for _, v := range ivalues {
 				vals = append(vals, v.Value().(*Tensor))
 			}
 			if len == 2 {
@@ -534,66 +622,19 @@ func IValueFromC(cval *CIValue) (*IValue, error) {
 					name:  "TensorList",
 				}, nil
 			}
-		case "IntList":
-			var vals []int64
-			for _, civalue := range civalues {
-				v, err := IValueFromC(&civalue)
-				if err != nil {
-					return nil, err
-				}
-				vals = append(vals, v.Value().(int64))
-			}
-			return &IValue{
-				value: vals,
-				kind:  IntListVal,
-				name:  "IntList",
-			}, nil
-		case "BoolList":
-			var vals []bool
-			for _, civalue := range civalues {
-				v, err := IValueFromC(&civalue)
-				if err != nil {
-					return nil, err
-				}
-				vals = append(vals, v.Value().(bool))
-			}
-			return &IValue{
-				value: vals,
-				kind:  BoolListVal,
-				name:  "BoolList",
-			}, nil
-		case "DoubleList":
-			var vals []float64
-			for _, civalue := range civalues {
-				v, err := IValueFromC(&civalue)
-				if err != nil {
-					return nil, err
-				}
-				vals = append(vals, v.Value().(float64))
-			}
-			return &IValue{
-				value: vals,
-				kind:  DoubleListVal,
-				name:  "DoubleList",
-			}, nil
-
-		default:
-			var vals []interface{}
-			for _, civalue := range civalues {
-				v, err := IValueFromC(&civalue)
-				if err != nil {
-					return nil, err
-				}
-				vals = append(vals, v)
-			}
+		}
 
-			return &IValue{
-				value: vals,
-				kind:  TupleVal,
-				name:  "Tuple",
-			}, nil
+		var vals []interface{}
+		for _, v := range ivalues {
+			vals = append(vals, v)
 		}
 
+		return &IValue{
+			value: vals,
+			kind:  TupleVal,
+			name:  "Tuple",
+		}, nil
+
 	case 6: // IntList
 		// 1. Len
 		len := lib.AtiLength(cval.civalue)
@@ -742,32 +783,33 @@ func IValueFromC(cval *CIValue) (*IValue, error) {
 			return nil, err
 		}
 		// 2. Call with first pointer and length
-		ptr1 := (*lib.Civalue)(unsafe.Pointer(C.malloc(0)))
-		lib.AtiToGenericList(cval.civalue, ptr1, int(len))
-		if err := TorchErr(); err != nil {
+		civalues, err := civaluesFromC(int(len), func(ptr *lib.Civalue) {
+			lib.AtiToGenericList(cval.civalue, ptr, int(len))
+		})
+		if err != nil {
 			return nil, err
 		}
 
-		// 3. Get values
-		var civalues []CIValue
-		civalues = append(civalues, CIValue{civalue: *ptr1})
-		currPtr := ptr1
-		for i := 1; i < int(len); i++ {
-			nextPtr := (*lib.Civalue)(unsafe.Pointer(uintptr(unsafe.Pointer(currPtr)) + unsafe.Sizeof(ptr1)))
-			civalues = append(civalues, CIValue{civalue: *nextPtr})
-			currPtr = nextPtr
-		}
-
-		// 4. Get Ivalue from Civalue for each tuple element
+		// 3. Get Ivalue from Civalue for each list element
 		var vals []interface{}
+		var ivalues []*IValue
 		var itemTyp string
 		for _, civalue := range civalues {
 			v, err := IValueFromC(&civalue)
 			if err != nil {
 				return nil, err
 			}
-			itemTyp = reflect.TypeOf(v.value).Kind().String()
+			typ := "none"
+			if v.value != nil {
+				typ = reflect.TypeOf(v.value).Kind().String()
+			}
+			// mixed item types (e.g. List[Optional[Tensor]]) fall back to []*IValue
+			if itemTyp != "" && typ != itemTyp {
+				typ = "mixed"
+			}
+			itemTyp = typ
 			vals = append(vals, v.value)
+			ivalues = append(ivalues, v)
 		}
 
 		switch itemTyp {
@@ -813,7 +855,12 @@ func IValueFromC(cval *CIValue) (*IValue, error) {
 			}, nil
 
 		default:
-			log.Fatalf("IValueFromC method call - GenericList case: Unsupported item type (%v)\n", itemTyp)
+			// e.g. List[Dict[str, Tensor]], List[Optional[Tensor]]
+			return &IValue{
+				value: ivalues,
+				kind:  GenericListVal,
+				name:  "GenericList",
+			}, nil
 		}
 
 	case 13: // GenericDict map[IValue]IValue
@@ -823,105 +870,149 @@ func IValueFromC(cval *CIValue) (*IValue, error) {
 			return nil, err
 		}
 		// 2. Call with first pointer and length
-		ptr1 := (*lib.Civalue)(unsafe.Pointer(C.malloc(0)))
-		lib.AtiToGenericDict(cval.civalue, ptr1, int(numVals))
-		if err := TorchErr(); err != nil {
+		civalues, err := civaluesFromC(int(numVals)*2, func(ptr *lib.Civalue) {
+			lib.AtiToGenericDict(cval.civalue, ptr, int(numVals))
+		})
+		if err != nil {
 			return nil, err
 		}
 
-		// 3. Get values
-
-		// TODO: Need to drill down a specific type
-		var civalues []CIValue
-		civalues = append(civalues, CIValue{civalue: *ptr1})
-		currPtr := ptr1
-		for i := 1; i < int(numVals)*2; i++ {
-			nextPtr := (*lib.Civalue)(unsafe.Pointer(uintptr(unsafe.Pointer(currPtr)) + unsafe.Sizeof(ptr1)))
-			civalues = append(civalues, CIValue{civalue: *nextPtr})
-			currPtr = nextPtr
-		}
-
-		// 4. Get Ivalue from Civalue for each element
-		var vals []interface{}
-		var itemTyp string
-		for _, civalue := range civalues {
+		// 3. Get Ivalue from Civalue for each key and value
+		var keys, vals []*IValue
+		var keyTyp, valTyp string
+		for i, civalue := range civalues {
 			v, err := IValueFromC(&civalue)
 			if err != nil {
 				return nil, err
 			}
-			itemTyp = reflect.TypeOf(v.value).Kind().String()
-			vals = append(vals, v.value)
-		}
-
-		switch itemTyp {
-		case "string":
-			var specVals map[string]string = make(map[string]string)
-			for i := 0; i < len(vals); i += 2 {
-				specVals[vals[i].(string)] = vals[i+1].(string)
+			typ := "none"
+			if v.value != nil {
+				typ = reflect.TypeOf(v.value).String()
 			}
-			return &IValue{
-				value: specVals,
-				kind:  GenericDictVal,
-				name:  "GenericDict",
-			}, nil
-		case "int":
-			var specVals map[int]int = make(map[int]int)
-			for i := 0; i < len(vals); i += 2 {
-				specVals[vals[i].(int)] = vals[i+1].(int)
+			if i%2 == 0 {
+				keys = append(keys, v)
+				keyTyp = typ
+			} else {
+				if valTyp != "" && typ != valTyp {
+					typ = "mixed"
+				}
+				vals = append(vals, v)
+				valTyp = typ
 			}
-			return &IValue{
-				value: specVals,
-				kind:  GenericDictVal,
-				name:  "GenericDict",
-			}, nil
-		case "int32":
-			var specVals map[int32]int32 = make(map[int32]int32)
-			for i := 0; i < len(vals); i += 2 {
-				specVals[vals[i].(int32)] = vals[i+1].(int32)
+		}
+
+		// 4. Typed map if possible, otherwise map of *IValue
+		var m reflect.Value
+		switch {
+		case numVals == 0:
+			m = reflect.ValueOf(map[string]*IValue{})
+		case valTyp == "mixed" || valTyp == "none" || valTyp == "[]interface {}":
+			m = reflect.MakeMap(reflect.MapOf(reflect.TypeOf(keys[0].value), reflect.TypeOf(&IValue{})))
+		default:
+			m = reflect.MakeMap(reflect.MapOf(reflect.TypeOf(keys[0].value), reflect.TypeOf(vals[0].value)))
+		}
+		for i := range keys {
+			if keys[i].value == nil || reflect.TypeOf(keys[i].value).String() != keyTyp {
+				err := fmt.Errorf("IValueFromC - GenericDict case: unsupported key type (%v)", keys[i].Name())
+				return nil, err
 			}
-			return &IValue{
-				value: specVals,
-				kind:  GenericDictVal,
-				name:  "GenericDict",
-			}, nil
-		case "int64":
-			var specVals map[int64]int64 = make(map[int64]int64)
-			for i := 0; i < len(vals); i += 2 {
-				specVals[vals[i].(int64)] = vals[i+1].(int64)
+			if m.Type().Elem() == reflect.TypeOf(&IValue{}) {
+				m.SetMapIndex(reflect.ValueOf(keys[i].value), reflect.ValueOf(vals[i]))
+			} else {
+				m.SetMapIndex(reflect.ValueOf(keys[i].value), reflect.ValueOf(vals[i].value))
 			}
-			return &IValue{
-				value: specVals,
-				kind:  GenericDictVal,
-				name:  "GenericDict",
-			}, nil
-		case "float32":
-			var specVals map[float32]float32 = make(map[float32]float32)
-			for i := 0; i < len(vals); i += 2 {
-				specVals[vals[i].(float32)] = vals[i+1].(float32)
+		}
+
+		return &IValue{
+			value: m.Interface(),
+			kind:  GenericDictVal,
+			name:  "GenericDict",
+		}, nil
+
+	case 14: // Object
+		obj := &Object{
+			ClassName: lib.AtiObjectTypeName(cval.civalue),
+			Attrs:     make(map[string]*IValue),
+		}
+		if err := TorchErr(); err != nil {
+			return nil, err
+		}
+
+		var data lib.NameData
+		dataPtr := lib.PStore.Set(&data)
+		defer lib.PStore.Free(dataPtr)
+		lib.AtiObjectAttributeNames(cval.civalue, dataPtr)
+		if err := TorchErr(); err != nil {
+			return nil, err
+		}
+		for _, name := range data.Names {
+			cattr := lib.AtiObjectGetattr_(cval.civalue, name)
+			if err := TorchErr(); err != nil {
+				return nil, err
 			}
-			return &IValue{
-				value: specVals,
-				kind:  GenericDictVal,
-				name:  "GenericDict",
-			}, nil
-		case "float64":
-			var specVals map[float64]float64 = make(map[float64]float64)
-			for i := 0; i < len(vals); i += 2 {
-				specVals[vals[i].(float64)] = vals[i+1].(float64)
+			attr, err := IValueFromC(&CIValue{cattr})
+			if err != nil {
+				err = fmt.Errorf("IValueFromC - Object case: attribute %q: %w", name, err)
+				return nil, err
 			}
-			return &IValue{
-				value: specVals,
-				kind:  GenericDictVal,
-				name:  "GenericDict",
-			}, nil
+			obj.Attrs[name] = attr
 		}
 
+		return &IValue{
+			value: obj,
+			kind:  ObjectVal,
+			name:  "Object",
+		}, nil
+
+	case 15: // Device
+		v := lib.AtiToDevice(cval.civalue)
+		if err := TorchErr(); err != nil {
+			return nil, err
+		}
+		var device gotch.Device
+		switch {
+		case v == -1:
+			device = gotch.CPU
+		case v >= 0:
+			device = gotch.CudaBuilder(uint(v))
+		default:
+			err := fmt.Errorf("IValueFromC - Device case: unsupported device (%v)", v)
+			return nil, err
+		}
+		return &IValue{
+			value: device,
+			kind:  DeviceVal,
+			name:  "Device",
+		}, nil
+
 	default:
 		err := fmt.Errorf("IValueFromC - Unsupported type (tag value: %v)\n", tag)
 		return nil, err
 	}
+}
+
+// civaluesFromC allocates an array of n civalue pointers, fills it with fn
+// and returns its elements.
+func civaluesFromC(n int, fn func(ptr *lib.Civalue)) ([]CIValue, error) {
+	if n == 0 {
+		return nil, nil
+	}
 
-	panic("Shouldn't reach here.")
+	nbytes := C.size_t(n) * C.size_t(unsafe.Sizeof(uintptr(0)))
+	dataPtr := C.malloc(nbytes)
+	defer C.free(dataPtr)
+	fn((*lib.Civalue)(dataPtr))
+	if err := TorchErr(); err != nil {
+		return nil, err
+	}
+
+	ptrs := (*[1 << 27]lib.Civalue)(dataPtr)[:n:n]
+	civalues := make([]CIValue, n)
+	for i, ptr := range ptrs {
+		civalues[i] = CIValue{civalue: ptr}
+	}
+
+	return civalues, nil
 }
 
 func (iv *IValue) Value() interface{} {
@@ -951,8 +1042,63 @@ func (cm *CModule) Drop() {
 	}
 }
 
+// CModuleOptions constructs options to load/save a JIT module.
+type CModuleOptions struct {
+	// ExtraFiles maps file names to contents of extra files (e.g. labels, normalization constants)
+	// stored in the module archive.
+	//
+	// On loading, only the keys are used and the values are filled with contents found in the archive
+	// (missing files are set to empty strings). On saving, all entries are written to the archive.
+	ExtraFiles map[string]string
+}
+
+type CModuleOpt func(*CModuleOptions)
+
+func DefaultCModuleOptions() *CModuleOptions {
+	return &CModuleOptions{
+		ExtraFiles: nil,
+	}
+}
+
+// WithExtraFiles reads/writes extra files from/to a module archive.
+func WithExtraFiles(files map[string]string) CModuleOpt {
+	return func(o *CModuleOptions) {
+		o.ExtraFiles = files
+	}
+}
+
+// moduleLoad loads a JIT module reading extra files if specified.
+func moduleLoad(path string, device gotch.Device, onDevice bool, opts ...CModuleOpt) (*CModule, error) {
+	o := DefaultCModuleOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var names []string
+	for name := range o.ExtraFiles {
+		names = append(names, name)
+	}
+
+	cmodule, contents := lib.AtmLoadWithExtraFiles(path, device.CInt(), onDevice, names)
+	if err := TorchErr(); err != nil {
+		return nil, err
+	}
+
+	for i, name := range names {
+		o.ExtraFiles[name] = contents[i]
+	}
+
+	return &CModule{cmodule}, nil
+}
+
 // Loads a PyTorch saved JIT model from a file.
-func ModuleLoad(path string) (*CModule, error) {
+//
+// Optional WithExtraFiles option reads extra files stored in the model archive.
+func ModuleLoad(path string, opts ...CModuleOpt) (*CModule, error) {
+	if len(opts) > 0 {
+		return moduleLoad(path, gotch.CPU, false, opts...)
+	}
+
 	cmodule := lib.AtmLoad(path)
 	if err := TorchErr(); err != nil {
 		return nil, err
@@ -966,7 +1112,11 @@ func ModuleLoad(path string) (*CModule, error) {
 //
 // This function loads the model directly on the specified device,
 // which means it also allows loading a GPU model on the CPU without having a CUDA enabled GPU.
-func ModuleLoadOnDevice(path string, device gotch.Device) (*CModule, error) {
+func ModuleLoadOnDevice(path string, device gotch.Device, opts ...CModuleOpt) (*CModule, error) {
+	if len(opts) > 0 {
+		return moduleLoad(path, device, true, opts...)
+	}
+
 	cmodule := lib.AtmLoadOnDevice(path, device.CInt())
 	if err := TorchErr(); err != nil {
 		return nil, err
@@ -975,6 +1125,25 @@ func ModuleLoadOnDevice(path string, device gotch.Device) (*CModule, error) {
 	return &CModule{cmodule}, nil
 }
 
+// CompileTorchScript compiles TorchScript source code into a new module.
+//
+// Functions defined in the source are compiled as methods of the module and
+// should take `self` as first argument. E.g.:
+//
+//	def forward(self, x):
+//	    return x * 2
+//
+//	def normalize(self, x, mean: float, std: float):
+//	    return (x - mean) / std
+func CompileTorchScript(src string) (*CModule, error) {
+	cmodule := lib.AtmCompile(src)
+	if err := TorchErr(); err != nil {
+		return nil, err
+	}
+
+	return &CModule{cmodule}, nil
+}
+
 // Loads a PyTorch saved JIT model from a read instance.
 func ModuleLoadData(stream io.Reader) (*CModule, error) {
 
@@ -1121,8 +1290,25 @@ func (cm *CModule) To(device gotch.Device, kind gotch.DType, nonBlocking bool) {
 }
 
 // Save save CModule to a specified path.
-func (cm *CModule) Save(file string) error {
-	lib.AtmSave(cm.Cmodule, file)
+//
+// Optional WithExtraFiles option writes extra files to the model archive.
+func (cm *CModule) Save(file string, opts ...CModuleOpt) error {
+	o := DefaultCModuleOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if len(o.ExtraFiles) == 0 {
+		lib.AtmSave(cm.Cmodule, file)
+		return TorchErr()
+	}
+
+	var names, contents []string
+	for name, content := range o.ExtraFiles {
+		names = append(names, name)
+		contents = append(contents, content)
+	}
+	lib.AtmSaveWithExtraFiles(cm.Cmodule, file, names, contents)
 	return TorchErr()
 }
 
@@ -1148,6 +1334,137 @@ func (cm *CModule) NamedParameters() ([]NamedTensor, error) {
 	return namedTensors, nil
 }
 
+// NamedBuffers loads named buffers (e.g. batch-norm running statistics) from a module.
+func (cm *CModule) NamedBuffers() ([]NamedTensor, error) {
+	var data lib.LoadData
+	dataPtr := lib.PStore.Set(&data)
+	defer lib.PStore.Free(dataPtr)
+	lib.AtmNamedBuffers(cm.Cmodule, dataPtr)
+	if err := TorchErr(); err != nil {
+		return nil, err
+	}
+
+	var namedTensors []NamedTensor
+	for _, v := range data.NamedCtensors {
+		namedTensor := NamedTensor{
+			Name:   v.Name,
+			Tensor: newTensor(v.Ctensor),
+		}
+
+		namedTensors = append(namedTensors, namedTensor)
+	}
+
+	return namedTensors, nil
+}
+
+// NamedCModule is a submodule with its dotted name (e.g. "encoder.layers.0").
+type NamedCModule struct {
+	Name   string
+	Module *CModule
+}
+
+// NamedModules returns all submodules of a module recursively (the module itself is not included).
+//
+// NOTE. Returned submodules share parameters and attributes with the parent module
+// and should be dropped with Drop() when no longer used.
+func (cm *CModule) NamedModules() ([]NamedCModule, error) {
+	var data lib.LoadModuleData
+	dataPtr := lib.PStore.Set(&data)
+	defer lib.PStore.Free(dataPtr)
+	lib.AtmNamedModules(cm.Cmodule, dataPtr)
+	if err := TorchErr(); err != nil {
+		return nil, err
+	}
+
+	var namedModules []NamedCModule
+	for _, v := range data.NamedCmodules {
+		namedModules = append(namedModules, NamedCModule{
+			Name:   v.Name,
+			Module: &CModule{v.Cmodule},
+		})
+	}
+
+	return namedModules, nil
+}
+
+// Submodule returns a submodule by its dotted name (e.g. "encoder.layers.0").
+//
+// NOTE. The submodule shares parameters and attributes with the parent module.
+func (cm *CModule) Submodule(name string) (*CModule, error) {
+	cmodule := lib.AtmGetSubmodule(cm.Cmodule, name)
+	if err := TorchErr(); err != nil {
+		return nil, err
+	}
+
+	return &CModule{cmodule}, nil
+}
+
+// MethodNames returns names of all methods defined in a module, including "forward".
+func (cm *CModule) MethodNames() ([]string, error) {
+	var data lib.NameData
+	dataPtr := lib.PStore.Set(&data)
+	defer lib.PStore.Free(dataPtr)
+	lib.AtmMethodNames(cm.Cmodule, dataPtr)
+	if err := TorchErr(); err != nil {
+		return nil, err
+	}
+
+	return data.Names, nil
+}
+
+// Method calls a method of the module (e.g. "encode", "generate") with some ivalue inputs.
+func (cm *CModule) Method(name string, args ...*IValue) (*IValue, error) {
+	var civaluesPtr *lib.Civalue
+	if len(args) > 0 {
+		civalues := make([]lib.Civalue, len(args))
+		for i, arg := range args {
+			civalue, err := arg.ToCIValue()
+			if err != nil {
+				return nil, err
+			}
+			civalues[i] = civalue.civalue
+		}
+
+		// Write civalue pointers to C memory.
+		nbytes := int(unsafe.Sizeof(civalues[0])) * len(civalues)
+		dataPtr := C.malloc(C.size_t(nbytes))
+		defer C.free(dataPtr)
+		copy((*[1 << 27]lib.Civalue)(dataPtr)[:len(civalues):len(civalues)], civalues)
+		civaluesPtr = (*lib.Civalue)(dataPtr)
+	}
+
+	civ := lib.AtmMethod_(cm.Cmodule, name, civaluesPtr, len(args))
+	if err := TorchErr(); err != nil {
+		return nil, err
+	}
+
+	return IValueFromC(&CIValue{civ})
+}
+
+// GetAttribute returns value of a module attribute (e.g. a vocabulary, a config value).
+func (cm *CModule) GetAttribute(name string) (*IValue, error) {
+	civ := lib.AtmGetAttribute(cm.Cmodule, name)
+	if err := TorchErr(); err != nil {
+		return nil, err
+	}
+
+	return IValueFromC(&CIValue{civ})
+}
+
+// SetAttribute sets value of an existing module attribute.
+//
+// NOTE. The value should match the attribute type declared in TorchScript.
+func (cm *CModule) SetAttribute(name string, value *IValue) error {
+	cval, err := value.ToCIValue()
+	if err != nil {
+		return err
+	}
+	defer lib.AtiFree(cval.civalue)
+
+	lib.AtmSetAttribute(cm.Cmodule, name, cval.civalue)
+	return TorchErr()
+}
+
 // GetProfilingMode get CModule profiling mode
 func (cm *CModule) GetProfilingMode() bool {
 	retVal := lib.AtmGetProfilingMode()
@@ -1182,6 +1499,96 @@ func (cm *CModule) SetEval() {
 	}
 }
 
+// WithGraphExecutorOptimize runs fn with graph executor optimization passes on
+// TorchScript graphs enabled or disabled, and restores the previous setting afterwards.
+//
+// NOTE. The setting is kept per thread by libtorch, so fn runs locked to the current
+// OS thread. Modules should be run in fn without starting new goroutines.
+func WithGraphExecutorOptimize(b bool, fn func()) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	prev := lib.AtGetGraphExecutorOptimize()
+	if err := TorchErr(); err != nil {
+		log.Fatal(err)
+	}
+	lib.AtSetGraphExecutorOptimize(b)
+	if err := TorchErr(); err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		lib.AtSetGraphExecutorOptimize(prev)
+		if err := TorchErr(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	fn()
+}
+
+// GetTensorExprFuserEnabled returns whether TensorExpr (NNC) fuser is enabled.
+func GetTensorExprFuserEnabled() bool {
+	retVal := lib.AtmGetTensorExprFuserEnabled()
+	if err := TorchErr(); err != nil {
+		log.Fatal(err)
+	}
+
+	return retVal
+}
+
+// SetTensorExprFuserEnabled enables/disables TensorExpr (NNC) fuser globally. The fuser
+// is used by profiling graph executor (see CModule.SetProfilingMode).
+func SetTensorExprFuserEnabled(b bool) {
+	lib.AtmSetTensorExprFuserEnabled(b)
+	if err := TorchErr(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Freeze returns a frozen copy of the module, equivalent to `torch.jit.freeze`.
+//
+// Parameters and attributes are inlined into the graph as constants and submodules
+// are folded into forward method. The module should be in inference mode (see SetEval).
+// Optional preservedAttrs are attributes and methods other than forward kept on the frozen module.
+func (cm *CModule) Freeze(preservedAttrs ...string) (*CModule, error) {
+	cmodule := lib.AtmFreeze(cm.Cmodule, preservedAttrs, true)
+	if err := TorchErr(); err != nil {
+		return nil, err
+	}
+
+	return &CModule{cmodule}, nil
+}
+
+// OptimizeForInference returns an optimized copy of the module, equivalent to `torch.jit.optimize_for_inference`.
+//
+// The module is frozen if not already frozen and further optimized for inference
+// (e.g. conv-bn fusion, conv-add/mul folding, MKLDNN layout on CPU).
+// Optional otherMethods are methods other than forward to be optimized as well.
+func (cm *CModule) OptimizeForInference(otherMethods ...string) (*CModule, error) {
+	cmodule := lib.AtmOptimizeForInference(cm.Cmodule, otherMethods)
+	if err := TorchErr(); err != nil {
+		return nil, err
+	}
+
+	return &CModule{cmodule}, nil
+}
+
+// GraphString returns TorchScript graph (IR) of a method for debugging.
+// Method name is default to "forward".
+func (cm *CModule) GraphString(methodName ...string) (string, error) {
+	name := "forward"
+	if len(methodName) > 0 {
+		name = methodName[0]
+	}
+
+	retVal := lib.AtmGraphString(cm.Cmodule, name)
+	if err := TorchErr(); err != nil {
+		return "", err
+	}
+
+	return retVal, nil
+}
+
 // Implement Module for CModule:
 // =============================
 
@@ -2,6 +2,7 @@ package ts_test
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/sugarme/gotch/ts"
@@ -114,3 +115,168 @@ func TestModuleForwardIValue(t *testing.T) {
 	 * } */
 
 }
+
+func TestModuleMethodAndAttribute(t *testing.T) {
+	foo, err := ts.ModuleLoad("foo1.gt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := foo.MethodNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]string{"forward"}, names) {
+		t.Errorf("Expected method names: [forward], got: %v\n", names)
+	}
+
+	iv1 := ts.NewIValue(ts.TensorFrom([]int64{42}))
+	iv2 := ts.NewIValue(ts.TensorFrom([]int64{1337}))
+	res, err := foo.Method("forward", iv1, iv2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := int(res.Value().(*ts.Tensor).Float64Values()[0]); got != 1421 {
+		t.Errorf("Expected value: 1421, got: %v\n", got)
+	}
+
+	if _, err := foo.Method("encode"); err == nil {
+		t.Errorf("Expected error for undefined method\n")
+	}
+
+	if err := foo.SetAttribute("training", ts.NewIValue(false)); err != nil {
+		t.Fatal(err)
+	}
+	training, err := foo.GetAttribute("training")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if training.Value().(bool) {
+		t.Errorf("Expected attribute 'training' to be false\n")
+	}
+
+	buffers, err := foo.NamedBuffers()
+	if err != nil || len(buffers) != 0 {
+		t.Errorf("Expected no buffers, got %v (err: %v)\n", buffers, err)
+	}
+	modules, err := foo.NamedModules()
+	if err != nil || len(modules) != 0 {
+		t.Errorf("Expected no submodules, got %v (err: %v)\n", modules, err)
+	}
+}
+
+func TestCompileTorchScriptAndExtraFiles(t *testing.T) {
+	src := `
+def forward(self, x, y):
+    return x * 2 + y
+
+def scale(self, x, s: float):
+    return x * s
+`
+	m, err := ts.CompileTorchScript(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x := ts.TensorFrom([]float32{1, 2})
+	y := ts.TensorFrom([]float32{10, 20})
+	res, err := m.ForwardTs([]*ts.Tensor{x, y})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Float64Values(); !reflect.DeepEqual([]float64{12, 24}, got) {
+		t.Errorf("Expected forward output [12 24], got %v\n", got)
+	}
+
+	scaled, err := m.Method("scale", ts.NewIValue(x), ts.NewIValue(3.0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := scaled.Value().(*ts.Tensor).Float64Values(); !reflect.DeepEqual([]float64{3, 6}, got) {
+		t.Errorf("Expected scale output [3 6], got %v\n", got)
+	}
+
+	file := t.TempDir() + "/compiled.pt"
+	extra := map[string]string{"labels.txt": "cat\ndog", "mean": "0.5"}
+	if err := m.Save(file, ts.WithExtraFiles(extra)); err != nil {
+		t.Fatal(err)
+	}
+
+	loadedExtra := map[string]string{"labels.txt": "", "mean": "", "missing": ""}
+	loaded, err := ts.ModuleLoad(file, ts.WithExtraFiles(loadedExtra))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"labels.txt": "cat\ndog", "mean": "0.5", "missing": ""}
+	if !reflect.DeepEqual(want, loadedExtra) {
+		t.Errorf("Expected extra files %q, got %q\n", want, loadedExtra)
+	}
+
+	res, err = loaded.ForwardTs([]*ts.Tensor{x, y})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Float64Values(); !reflect.DeepEqual([]float64{12, 24}, got) {
+		t.Errorf("Expected loaded forward output [12 24], got %v\n", got)
+	}
+
+	if _, err := ts.CompileTorchScript("def forward(self, x):\n    return undefined_fn(x)\n"); err == nil {
+		t.Errorf("Expected compile error\n")
+	}
+}
+
+func TestModuleFreezeAndOptimize(t *testing.T) {
+	m, err := ts.CompileTorchScript("def forward(self, x):\n    return x * 2 + 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetEval()
+
+	graph, err := m.GraphString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(graph, "aten::mul") {
+		t.Errorf("Expected graph to contain aten::mul, got:\n%v\n", graph)
+	}
+	if _, err := m.GraphString("undefined"); err == nil {
+		t.Errorf("Expected error for undefined method\n")
+	}
+
+	frozen, err := m.Freeze()
+	if err != nil {
+		t.Fatal(err)
+	}
+	optimized, err := m.OptimizeForInference()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x := ts.TensorFrom([]float32{1, 2})
+	for _, mod := range []*ts.CModule{frozen, optimized} {
+		res, err := mod.ForwardTs([]*ts.Tensor{x})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.Float64Values(); !reflect.DeepEqual([]float64{3, 5}, got) {
+			t.Errorf("Expected output [3 5], got %v\n", got)
+		}
+	}
+
+	ts.WithGraphExecutorOptimize(false, func() {
+		res, err := m.ForwardTs([]*ts.Tensor{x})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.Float64Values(); !reflect.DeepEqual([]float64{3, 5}, got) {
+			t.Errorf("Expected output [3 5] without graph optimization, got %v\n", got)
+		}
+	})
+
+	old := ts.GetTensorExprFuserEnabled()
+	ts.SetTensorExprFuserEnabled(!old)
+	if got := ts.GetTensorExprFuserEnabled(); got == old {
+		t.Errorf("Expected TensorExpr fuser enabled %v, got %v\n", !old, got)
+	}
+	ts.SetTensorExprFuserEnabled(old)
+}
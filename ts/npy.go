@@ -283,7 +283,12 @@ func ReadNpy(filepath string) (*Tensor, error) {
 	}
 	defer f.Close()
 
-	r := bufio.NewReader(f)
+	return ReadNpyFrom(f)
+}
+
+// ReadNpyFrom reads .npy data (e.g. from memory or an archive) and returns the stored tensor.
+func ReadNpyFrom(reader io.Reader) (*Tensor, error) {
+	r := bufio.NewReader(reader)
 
 	h, err := readHeader(r)
 	if err != nil {
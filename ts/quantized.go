@@ -0,0 +1,226 @@
+package ts
+
+// Quantized operators backed by libtorch `quantized::` ops (fbgemm/qnnpack on CPU).
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+
+	lib "github.com/sugarme/gotch/libtch"
+)
+
+// QEngine is a backend engine used by quantized operators.
+type QEngine int
+
+const (
+	QEngineNone    QEngine = iota // no quantized engine available
+	QEngineFBGEMM                 // fbgemm - x86 servers
+	QEngineQNNPACK                // qnnpack - ARM/mobile
+	QEngineOneDNN                 // oneDNN
+	QEngineX86                    // x86 (fbgemm + oneDNN)
+)
+
+func (e QEngine) String() string {
+	switch e {
+	case QEngineNone:
+		return "none"
+	case QEngineFBGEMM:
+		return "fbgemm"
+	case QEngineQNNPACK:
+		return "qnnpack"
+	case QEngineOneDNN:
+		return "onednn"
+	case QEngineX86:
+		return "x86"
+	default:
+		return fmt.Sprintf("QEngine(%d)", int(e))
+	}
+}
+
+// SetQEngine sets the global engine used by quantized operators.
+func SetQEngine(e QEngine) error {
+	lib.AtqSetEngine(int32(e))
+	if err := TorchErr(); err != nil {
+		err = fmt.Errorf("SetQEngine() failed: %w", err)
+		return err
+	}
+
+	return nil
+}
+
+// MustSetQEngine sets the global quantized engine. It panics if error occurred.
+func MustSetQEngine(e QEngine) {
+	if err := SetQEngine(e); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// GetQEngine returns the current global quantized engine.
+func GetQEngine() (QEngine, error) {
+	e := lib.AtqEngine()
+	if err := TorchErr(); err != nil {
+		err = fmt.Errorf("GetQEngine() failed: %w", err)
+		return QEngineNone, err
+	}
+
+	return QEngine(e), nil
+}
+
+// PackedParams holds weights (and optional bias) prepacked for a quantized engine.
+//
+// It is an opaque libtorch custom class object (e.g. LinearPackedParamsBase, Conv2dPackedParamsBase)
+// and can only be consumed by the matching quantized operator.
+type PackedParams struct {
+	civalue lib.Civalue
+}
+
+func newPackedParams(civalue lib.Civalue) *PackedParams {
+	p := &PackedParams{civalue}
+	runtime.SetFinalizer(p, freePackedParams)
+	return p
+}
+
+func freePackedParams(p *PackedParams) error {
+	if p.civalue == nil {
+		return nil
+	}
+	lib.AtiFree(p.civalue)
+	p.civalue = nil
+	return TorchErr()
+}
+
+// Drop frees up C memory held by packed params.
+func (p *PackedParams) Drop() error {
+	return freePackedParams(p)
+}
+
+// MustDrop frees up C memory held by packed params. It panics if error occurred.
+func (p *PackedParams) MustDrop() {
+	if err := p.Drop(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// optionalCtensor returns C tensor of an optional tensor. Nil tensor maps to undefined tensor.
+func optionalCtensor(x *Tensor) lib.Ctensor {
+	if x == nil {
+		return nil
+	}
+	return x.ctensor
+}
+
+// QLinearPrepack packs a per-tensor or per-channel qint8 quantized weight of shape [out, in]
+// and an optional float bias of shape [out] for quantized linear operators.
+func QLinearPrepack(weight, bias *Tensor) (*PackedParams, error) {
+	civalue := lib.AtqLinearPrepack(weight.ctensor, optionalCtensor(bias))
+	if err := TorchErr(); err != nil {
+		err = fmt.Errorf("QLinearPrepack() failed: %w", err)
+		return nil, err
+	}
+
+	return newPackedParams(civalue), nil
+}
+
+// MustQLinearPrepack packs weight and bias for quantized linear operators. It panics if error occurred.
+func MustQLinearPrepack(weight, bias *Tensor) *PackedParams {
+	p, err := QLinearPrepack(weight, bias)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return p
+}
+
+// QLinear applies a quantized linear transformation to a quint8 quantized input.
+// The output is quantized with the given scale and zero point.
+func (ts *Tensor) QLinear(packed *PackedParams, scale float64, zeroPoint int64, del bool) (*Tensor, error) {
+	if del {
+		defer ts.MustDrop()
+	}
+	ctensor := lib.AtqLinear(ts.ctensor, packed.civalue, scale, zeroPoint)
+	if err := TorchErr(); err != nil {
+		err = fmt.Errorf("QLinear() failed: %w", err)
+		return nil, err
+	}
+
+	return newTensor(ctensor, "QLinear"), nil
+}
+
+func (ts *Tensor) MustQLinear(packed *PackedParams, scale float64, zeroPoint int64, del bool) *Tensor {
+	retVal, err := ts.QLinear(packed, scale, zeroPoint, del)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return retVal
+}
+
+// QLinearDynamic applies a dynamically quantized linear transformation to a float input.
+// The input is quantized on the fly and the output is a float tensor.
+func (ts *Tensor) QLinearDynamic(packed *PackedParams, reduceRange bool, del bool) (*Tensor, error) {
+	if del {
+		defer ts.MustDrop()
+	}
+	creduceRange := int32(0)
+	if reduceRange {
+		creduceRange = 1
+	}
+	ctensor := lib.AtqLinearDynamic(ts.ctensor, packed.civalue, creduceRange)
+	if err := TorchErr(); err != nil {
+		err = fmt.Errorf("QLinearDynamic() failed: %w", err)
+		return nil, err
+	}
+
+	return newTensor(ctensor, "QLinearDynamic"), nil
+}
+
+func (ts *Tensor) MustQLinearDynamic(packed *PackedParams, reduceRange bool, del bool) *Tensor {
+	retVal, err := ts.QLinearDynamic(packed, reduceRange, del)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return retVal
+}
+
+// QConv2dPrepack packs a qint8 quantized weight of shape [out, in/groups, kH, kW]
+// and an optional float bias of shape [out] for quantized conv2d operator.
+func QConv2dPrepack(weight, bias *Tensor, stride, padding, dilation []int64, groups int64) (*PackedParams, error) {
+	civalue := lib.AtqConv2dPrepack(weight.ctensor, optionalCtensor(bias), stride, len(stride), padding, len(padding), dilation, len(dilation), groups)
+	if err := TorchErr(); err != nil {
+		err = fmt.Errorf("QConv2dPrepack() failed: %w", err)
+		return nil, err
+	}
+
+	return newPackedParams(civalue), nil
+}
+
+// MustQConv2dPrepack packs weight and bias for quantized conv2d operator. It panics if error occurred.
+func MustQConv2dPrepack(weight, bias *Tensor, stride, padding, dilation []int64, groups int64) *PackedParams {
+	p, err := QConv2dPrepack(weight, bias, stride, padding, dilation, groups)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return p
+}
+
+// QConv2d applies a quantized 2D convolution to a quint8 quantized input of shape [N, C, H, W].
+// The output is quantized with the given scale and zero point.
+func (ts *Tensor) QConv2d(packed *PackedParams, scale float64, zeroPoint int64, del bool) (*Tensor, error) {
+	if del {
+		defer ts.MustDrop()
+	}
+	ctensor := lib.AtqConv2d(ts.ctensor, packed.civalue, scale, zeroPoint)
+	if err := TorchErr(); err != nil {
+		err = fmt.Errorf("QConv2d() failed: %w", err)
+		return nil, err
+	}
+
+	return newTensor(ctensor, "QConv2d"), nil
+}
+
+func (ts *Tensor) MustQConv2d(packed *PackedParams, scale float64, zeroPoint int64, del bool) *Tensor {
+	retVal, err := ts.QConv2d(packed, scale, zeroPoint, del)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return retVal
+}
@@ -1255,6 +1255,12 @@ func MustGradSetEnabled(b bool) bool {
 	return state
 }
 
+// ManualSeed sets the seed of the torch random number generator, e.g. to make weight
+// initialization reproducible.
+func ManualSeed(seed int64) {
+	lib.AtManualSeed(seed)
+}
+
 // NoGrad runs a closure without keeping track of gradients.
 func NoGrad(fn func()) {
 	// Switch off Grad
@@ -0,0 +1,18 @@
+package vision
+
+// Image decoders of dutil.ShardDataset fields.
+
+import (
+	"github.com/sugarme/gotch/dutil"
+)
+
+func init() {
+	for _, ext := range []string{"jpg", "jpeg", "png", "bmp", "tga"} {
+		dutil.RegisterDecoder(ext, decodeImage)
+	}
+}
+
+// decodeImage decodes an image field to a uint8 tensor of shape [channel, height, width].
+func decodeImage(data []byte) (interface{}, error) {
+	return Decode(data)
+}
@@ -73,38 +73,54 @@ func (p *params) roundFilters(filters int64) int64 {
 	return newFilters
 }
 
+// sameConv2D is a Conv2D with same padding.
+type sameConv2D struct {
+	conv *nn.Conv2D
+	k    int64
+	s    []int64
+}
+
 // Conv2D with same padding
 func enConv2d(vs *nn.Path, i, o, k int64, c *nn.Conv2DConfig, train bool) ts.ModuleT {
 	conv2d := nn.NewConv2D(vs, i, o, k, c)
-	s := c.Stride
-
-	return nn.NewFunc(func(xs *ts.Tensor) *ts.Tensor {
-		size := xs.MustSize()
-		ih := size[2]
-		iw := size[3]
-		oh := (ih + s[0] - 1) / s[0]
-		ow := (iw + s[0] - 1) / s[0]
-
-		var padH int64 = 0
-		if (((oh - 1) * s[0]) + k - ih) > 0 {
-			padH = ((oh - 1) * s[0]) + k - ih
-		}
-		var padW int64 = 0
-		if (((ow - 1) * s[0]) + k - iw) > 0 {
-			padW = ((ow - 1) * s[0]) + k - iw
-		}
 
-		var res *ts.Tensor
-		if padW > 0 || padH > 0 {
-			zeroP2D := xs.MustZeroPad2d(padW/2, padW-padW/2, padH/2, padH-padH/2, false)
-			res = zeroP2D.ApplyT(conv2d, train)
-			zeroP2D.MustDrop()
-			return res
-		} else {
-			res = xs.ApplyT(conv2d, train)
-			return res
-		}
-	})
+	return &sameConv2D{conv: conv2d, k: k, s: c.Stride}
+}
+
+// ForwardT implements ModuleT for sameConv2D.
+func (c *sameConv2D) ForwardT(xs *ts.Tensor, train bool) *ts.Tensor {
+	s, k := c.s, c.k
+	size := xs.MustSize()
+	ih := size[2]
+	iw := size[3]
+	oh := (ih + s[0] - 1) / s[0]
+	ow := (iw + s[0] - 1) / s[0]
+
+	var padH int64 = 0
+	if (((oh - 1) * s[0]) + k - ih) > 0 {
+		padH = ((oh - 1) * s[0]) + k - ih
+	}
+	var padW int64 = 0
+	if (((ow - 1) * s[0]) + k - iw) > 0 {
+		padW = ((ow - 1) * s[0]) + k - iw
+	}
+
+	var res *ts.Tensor
+	if padW > 0 || padH > 0 {
+		zeroP2D := xs.MustZeroPad2d(padW/2, padW-padW/2, padH/2, padH-padH/2, false)
+		res = zeroP2D.ApplyT(c.conv, train)
+		zeroP2D.MustDrop()
+		return res
+	} else {
+		res = xs.ApplyT(c.conv, train)
+		return res
+	}
+}
+
+// Unwrap implements nn.Unwrapper: padding is applied to the input only, so a following
+// batch-norm layer can be folded into the convolution.
+func (c *sameConv2D) Unwrap() interface{} {
+	return c.conv
 }
 
 func newParams(width, depth float64, res int64, dropout float64) *params {
@@ -200,6 +216,12 @@ func block(p *nn.Path, args BlockArgs) ts.ModuleT {
 
 	projectBn := nn.BatchNorm2D(p.Sub("_bn2"), finalOup, bn2d)
 
+	modules := []interface{}{expansion, depthwiseConv, depthwiseBn}
+	if se != nil {
+		modules = append(modules, se)
+	}
+	modules = append(modules, projectConv, projectBn)
+
 	return nn.NewFuncT(func(xs *ts.Tensor, train bool) *ts.Tensor {
 		var ys *ts.Tensor
 		if args.ExpandRatio == 1 {
@@ -239,7 +261,7 @@ func block(p *nn.Path, args BlockArgs) ts.ModuleT {
 		} else {
 			return ys6
 		}
-	})
+	}, modules...)
 }
 
 func efficientnet(p *nn.Path, params *params, nclasses int64) ts.ModuleT {
@@ -319,7 +341,7 @@ func efficientnet(p *nn.Path, params *params, nclasses int64) ts.ModuleT {
 		res := tmp10.ApplyT(classifier, train)
 		tmp10.MustDrop()
 		return res
-	})
+	}, convStem, bn0, blocks, convHead, bn1, classifier)
 
 }
 
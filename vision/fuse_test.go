@@ -0,0 +1,90 @@
+package vision_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	"github.com/sugarme/gotch/ts"
+	"github.com/sugarme/gotch/vision"
+)
+
+// randomizeBatchNorms sets random running statistics and affine parameters of all
+// batch-norm layers so that folding them is not trivial.
+func randomizeBatchNorms(vs *nn.VarStore) {
+	vars := vs.Variables()
+	ts.NoGrad(func() {
+		for name := range vars {
+			if !strings.HasSuffix(name, "running_mean") {
+				continue
+			}
+			prefix := strings.TrimSuffix(name, "running_mean")
+			for _, suffix := range []string{"running_mean", "running_var", "weight", "bias"} {
+				x, ok := vars[prefix+suffix]
+				if !ok {
+					continue
+				}
+				var src *ts.Tensor
+				switch suffix {
+				case "running_var", "weight":
+					src = ts.MustRand(x.MustSize(), gotch.Float, gotch.CPU).MustAddScalar(ts.FloatScalar(0.5), true)
+				default:
+					src = ts.MustRandn(x.MustSize(), gotch.Float, gotch.CPU).MustMulScalar(ts.FloatScalar(0.1), true)
+				}
+				x.Copy_(src)
+				src.MustDrop()
+			}
+		}
+	})
+}
+
+func testFuseModel(t *testing.T, name string, newModel func(p *nn.Path) ts.ModuleT, size int64) {
+	vs := nn.NewVarStore(gotch.CPU)
+	model := newModel(vs.Root())
+	randomizeBatchNorms(vs)
+
+	x := ts.MustRandn([]int64{1, 3, size, size}, gotch.Float, gotch.CPU)
+	var want, got *ts.Tensor
+	ts.NoGrad(func() {
+		want = model.ForwardT(x, false)
+	})
+
+	n, err := nn.FuseModel(model)
+	if err != nil {
+		t.Fatalf("%s - FuseModel() failed: %v\n", name, err)
+	}
+	if n == 0 {
+		t.Errorf("%s - want fused conv/batch-norm pairs, got none\n", name)
+	}
+
+	ts.NoGrad(func() {
+		got = model.ForwardT(x, false)
+	})
+	diff := want.MustSub(got, false).MustAbs(true).MustMax(true).Float64Values()[0]
+	scale := want.MustAbs(false).MustMax(true).Float64Values()[0]
+	if diff > 1e-3*scale+1e-5 {
+		t.Errorf("%s - want fused output equal to eval-mode output, got max diff %v (max output %v)\n", name, diff, scale)
+	}
+	x.MustDrop()
+	want.MustDrop()
+	got.MustDrop()
+}
+
+func TestFuseModel_Vision(t *testing.T) {
+	testFuseModel(t, "ResNet18", func(p *nn.Path) ts.ModuleT {
+		return vision.ResNet18(p, 10)
+	}, 64)
+	testFuseModel(t, "ResNet50", func(p *nn.Path) ts.ModuleT {
+		return vision.ResNet50(p, 10)
+	}, 64)
+	testFuseModel(t, "VGG11BN", func(p *nn.Path) ts.ModuleT {
+		return vision.VGG11BN(p, 10)
+	}, 224)
+	testFuseModel(t, "MobileNetV2", func(p *nn.Path) ts.ModuleT {
+		return vision.MobileNetV2(p, 10)
+	}, 64)
+	testFuseModel(t, "EfficientNetB0", func(p *nn.Path) ts.ModuleT {
+		return vision.EfficientNetB0(p, 10)
+	}, 64)
+}
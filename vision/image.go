@@ -45,6 +45,21 @@ func Load(path string) (*ts.Tensor, error) {
 	return loadedTs, nil
 }
 
+// Decode decodes an encoded image (e.g. jpg, png) from memory.
+//
+// On success returns a tensor of shape [channel, height, width].
+func Decode(data []byte) (*ts.Tensor, error) {
+	tensor, err := ts.LoadHwcFromMemory(data)
+	if err != nil {
+		return nil, err
+	}
+
+	decodedTs := hwcToCHW(tensor)
+	tensor.MustDrop()
+
+	return decodedTs, nil
+}
+
 // Save saves an image to a file.
 //
 // This expects as input a tensor of shape [channel, height, width].
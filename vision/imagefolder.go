@@ -0,0 +1,357 @@
+package vision
+
+// Lazy disk-backed image dataset.
+
+import (
+	"container/list"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sugarme/gotch/ts"
+)
+
+// ImageTransformer transforms a decoded image tensor of shape [channel, height, width].
+// It is satisfied by `aug.Transformer` (e.g. composed by `aug.Compose()`).
+type ImageTransformer interface {
+	Transform(x *ts.Tensor) *ts.Tensor
+}
+
+// ImageSample is a sample of an ImageFolder.
+type ImageSample struct {
+	Image *ts.Tensor // image of shape [channel, height, width]. Uint8 unless transformed.
+	Label int        // class index.
+	Path  string     `collate:"-"`
+}
+
+type ImageFolderOptions struct {
+	Transform  ImageTransformer // transform applied to decoded images. Default=nil
+	CacheSize  int              // number of decoded images kept in a LRU cache. Default=0 (no cache)
+	Extensions []string         // image file extensions. Default=".jpg", ".jpeg", ".png", ".bmp", ".tga"
+}
+
+type ImageFolderOption func(*ImageFolderOptions)
+
+func NewImageFolderOptions(options ...ImageFolderOption) ImageFolderOptions {
+	opts := ImageFolderOptions{
+		Transform:  nil,
+		CacheSize:  0,
+		Extensions: []string{".jpg", ".jpeg", ".png", ".bmp", ".tga"},
+	}
+
+	for _, o := range options {
+		o(&opts)
+	}
+
+	return opts
+}
+
+func WithTransform(t ImageTransformer) ImageFolderOption {
+	return func(o *ImageFolderOptions) {
+		o.Transform = t
+	}
+}
+
+// WithCacheSize caches decoded images (before transform) of the n most recently used samples.
+func WithCacheSize(n int) ImageFolderOption {
+	return func(o *ImageFolderOptions) {
+		o.CacheSize = n
+	}
+}
+
+func WithExtensions(exts ...string) ImageFolderOption {
+	return func(o *ImageFolderOptions) {
+		o.Extensions = exts
+	}
+}
+
+// ImageFolder is a dataset of image files decoded on access. It implements `dutil.Dataset`
+// with samples of type ImageSample.
+//
+// Images are decoded by `Load()` in Item and transformed by the Transform option.
+// It can be used with typed data loaders via `dutil.FromDataset[vision.ImageSample]()`
+// and `dutil.CollateStruct()`.
+type ImageFolder struct {
+	paths     []string
+	labels    []int
+	classes   []string
+	transform ImageTransformer
+	cache     *imageCache
+}
+
+// NewImageFolder creates an ImageFolder from a directory with a subdirectory per class:
+//
+//	root/class_x/xxx.jpg
+//	root/class_y/nested/yyy.png
+//
+// Classes are indexed in sorted order of subdirectory names.
+func NewImageFolder(root string, opts ...ImageFolderOption) (*ImageFolder, error) {
+	o := NewImageFolderOptions(opts...)
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		err = fmt.Errorf("NewImageFolder() failed: %w", err)
+		return nil, err
+	}
+
+	var paths, classes []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		class := entry.Name()
+		dir := filepath.Join(root, class)
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && hasExtension(path, o.Extensions) {
+				paths = append(paths, path)
+				classes = append(classes, class)
+			}
+			return nil
+		})
+		if err != nil {
+			err = fmt.Errorf("NewImageFolder() failed: %w", err)
+			return nil, err
+		}
+	}
+	if len(paths) == 0 {
+		err := fmt.Errorf("NewImageFolder() failed: no image files with extensions %v found in %q", o.Extensions, root)
+		return nil, err
+	}
+
+	return newImageFolder(paths, classes, o), nil
+}
+
+// NewImageFolderFromFiles creates an ImageFolder from a list of image files and their class names.
+// Classes are indexed in sorted order of class names.
+func NewImageFolderFromFiles(paths, classes []string, opts ...ImageFolderOption) (*ImageFolder, error) {
+	if len(paths) != len(classes) || len(paths) == 0 {
+		err := fmt.Errorf("NewImageFolderFromFiles() failed: expected same non-zero number of files and classes. Got %v and %v", len(paths), len(classes))
+		return nil, err
+	}
+
+	return newImageFolder(paths, classes, NewImageFolderOptions(opts...)), nil
+}
+
+// NewImageFolderFromManifest creates an ImageFolder from a CSV manifest file with records of
+// image file path and class name. An optional header "path,label" is skipped. Relative
+// paths are relative to the manifest directory.
+func NewImageFolderFromManifest(manifest string, opts ...ImageFolderOption) (*ImageFolder, error) {
+	f, err := os.Open(manifest)
+	if err != nil {
+		err = fmt.Errorf("NewImageFolderFromManifest() failed: %w", err)
+		return nil, err
+	}
+	defer f.Close()
+
+	dir := filepath.Dir(manifest)
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 2
+	r.TrimLeadingSpace = true
+
+	var paths, classes []string
+	for line := 0; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			err = fmt.Errorf("NewImageFolderFromManifest() failed: %w", err)
+			return nil, err
+		}
+		if line == 0 && record[0] == "path" && record[1] == "label" {
+			continue
+		}
+		path := record[0]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		paths = append(paths, path)
+		classes = append(classes, record[1])
+	}
+
+	ds, err := NewImageFolderFromFiles(paths, classes, opts...)
+	if err != nil {
+		err = fmt.Errorf("NewImageFolderFromManifest() failed: %w", err)
+		return nil, err
+	}
+	return ds, nil
+}
+
+func newImageFolder(paths, classes []string, opts ImageFolderOptions) *ImageFolder {
+	index := make(map[string]int)
+	for _, class := range classes {
+		index[class] = 0
+	}
+	names := make([]string, 0, len(index))
+	for class := range index {
+		names = append(names, class)
+	}
+	sort.Strings(names)
+	for i, class := range names {
+		index[class] = i
+	}
+
+	labels := make([]int, len(classes))
+	for i, class := range classes {
+		labels[i] = index[class]
+	}
+
+	var cache *imageCache
+	if opts.CacheSize > 0 {
+		cache = newImageCache(opts.CacheSize)
+	}
+
+	return &ImageFolder{
+		paths:     paths,
+		labels:    labels,
+		classes:   names,
+		transform: opts.Transform,
+		cache:     cache,
+	}
+}
+
+func hasExtension(path string, exts []string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range exts {
+		if ext == strings.ToLower(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sample returns sample idx with decoded (and transformed) image.
+// The returned image should be dropped by the caller.
+func (ds *ImageFolder) Sample(idx int) (ImageSample, error) {
+	if idx < 0 || idx >= len(ds.paths) {
+		err := fmt.Errorf("Idx is out of range.")
+		return ImageSample{}, err
+	}
+
+	path := ds.paths[idx]
+	var (
+		image  *ts.Tensor
+		cached bool
+	)
+	if ds.cache != nil {
+		image, cached = ds.cache.get(idx)
+	}
+	if !cached {
+		var err error
+		image, err = Load(path)
+		if err != nil {
+			err = fmt.Errorf("ImageFolder - decoding image %q failed: %w", path, err)
+			return ImageSample{}, err
+		}
+		if ds.cache != nil {
+			image = ds.cache.add(idx, image)
+		}
+	}
+
+	out := image
+	if ds.transform != nil {
+		out = ds.transform.Transform(image)
+		if out != image {
+			image.MustDrop()
+		}
+	}
+
+	return ImageSample{Image: out, Label: ds.labels[idx], Path: path}, nil
+}
+
+// Item implements dutil.Dataset interface. It returns an ImageSample.
+func (ds *ImageFolder) Item(idx int) (interface{}, error) {
+	return ds.Sample(idx)
+}
+
+// DType implements dutil.Dataset interface.
+func (ds *ImageFolder) DType() reflect.Type {
+	return reflect.TypeOf([]ImageSample(nil))
+}
+
+// Len implements dutil.Dataset interface.
+func (ds *ImageFolder) Len() int {
+	return len(ds.paths)
+}
+
+// Classes returns class names ordered by class index.
+func (ds *ImageFolder) Classes() []string {
+	return ds.classes
+}
+
+// Labels returns class index of all samples, e.g. for stratified splitting or weighted
+// sampling.
+func (ds *ImageFolder) Labels() []int {
+	return ds.labels
+}
+
+// Paths returns image file paths of all samples.
+func (ds *ImageFolder) Paths() []string {
+	return ds.paths
+}
+
+// imageCache is a LRU cache of decoded images safe for concurrent use.
+type imageCache struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[int]*list.Element
+	order    *list.List // front is most recently used.
+}
+
+type cacheEntry struct {
+	idx   int
+	image *ts.Tensor
+}
+
+func newImageCache(capacity int) *imageCache {
+	return &imageCache{
+		capacity: capacity,
+		items:    make(map[int]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get returns a shallow clone of cached image owned by the caller.
+func (c *imageCache) get(idx int) (*ts.Tensor, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[idx]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cacheEntry).image.MustShallowClone(), true
+}
+
+// add caches an image and returns a shallow clone owned by the caller. If the sample was
+// added concurrently, the image is dropped and the already cached image is used.
+func (c *imageCache) add(idx int, image *ts.Tensor) *ts.Tensor {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[idx]; ok {
+		image.MustDrop()
+		c.order.MoveToFront(el)
+		return el.Value.(*cacheEntry).image.MustShallowClone()
+	}
+	c.items[idx] = c.order.PushFront(&cacheEntry{idx, image})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		entry := oldest.Value.(*cacheEntry)
+		delete(c.items, entry.idx)
+		entry.image.MustDrop()
+	}
+	return image.MustShallowClone()
+}
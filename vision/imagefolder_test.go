@@ -0,0 +1,120 @@
+package vision_test
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch/ts"
+	"github.com/sugarme/gotch/vision"
+)
+
+func writePNG(t *testing.T, path string, w, h int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	img.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// countTransform counts calls and returns a float copy of the image.
+type countTransform struct {
+	calls int
+}
+
+func (c *countTransform) Transform(x *ts.Tensor) *ts.Tensor {
+	c.calls++
+	return x.MustDiv(ts.MustOfSlice([]float32{255}), false)
+}
+
+func TestImageFolder(t *testing.T) {
+	root := t.TempDir()
+	writePNG(t, filepath.Join(root, "dog", "a.png"), 4, 3)
+	writePNG(t, filepath.Join(root, "cat", "b.png"), 4, 3)
+	writePNG(t, filepath.Join(root, "cat", "nested", "c.PNG"), 2, 2)
+	if err := os.WriteFile(filepath.Join(root, "cat", "notes.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tf := &countTransform{}
+	ds, err := vision.NewImageFolder(root, vision.WithTransform(tf), vision.WithCacheSize(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds.Len() != 3 {
+		t.Fatalf("want 3 images, got %v\n", ds.Len())
+	}
+	if want := []string{"cat", "dog"}; !reflect.DeepEqual(want, ds.Classes()) {
+		t.Errorf("want classes %v, got %v\n", want, ds.Classes())
+	}
+	if want := []int{0, 0, 1}; !reflect.DeepEqual(want, ds.Labels()) {
+		t.Errorf("want labels %v, got %v\n", want, ds.Labels())
+	}
+
+	item, err := ds.Item(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sample := item.(vision.ImageSample)
+	if want := []int64{3, 3, 4}; !reflect.DeepEqual(want, sample.Image.MustSize()) || sample.Label != 1 {
+		t.Errorf("want image shape %v with label 1, got %v with label %v\n", want, sample.Image.MustSize(), sample.Label)
+	}
+	if v := sample.Image.Float64Values()[0]; v != 1 {
+		t.Errorf("want transformed red value 1, got %v\n", v)
+	}
+
+	// Cached image is transformed again.
+	if _, err := ds.Sample(2); err != nil {
+		t.Fatal(err)
+	}
+	if tf.calls != 2 {
+		t.Errorf("want transform called for every sample, got %v calls\n", tf.calls)
+	}
+}
+
+func TestImageFolderFromManifest(t *testing.T) {
+	dir := t.TempDir()
+	writePNG(t, filepath.Join(dir, "images", "1.png"), 2, 2)
+	writePNG(t, filepath.Join(dir, "images", "2.png"), 2, 2)
+	manifest := filepath.Join(dir, "train.csv")
+	content := "path,label\nimages/1.png,b\nimages/2.png,a\n"
+	if err := os.WriteFile(manifest, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := vision.NewImageFolderFromManifest(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 0}; !reflect.DeepEqual(want, ds.Labels()) {
+		t.Errorf("want labels %v, got %v\n", want, ds.Labels())
+	}
+	if want := filepath.Join(dir, "images", "1.png"); ds.Paths()[0] != want {
+		t.Errorf("want path %q, got %q\n", want, ds.Paths()[0])
+	}
+
+	sample, err := ds.Sample(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{3, 2, 2}; !reflect.DeepEqual(want, sample.Image.MustSize()) {
+		t.Errorf("want image shape %v, got %v\n", want, sample.Image.MustSize())
+	}
+
+	if _, err := vision.NewImageFolderFromFiles([]string{"a.png"}, nil); err == nil {
+		t.Errorf("want error for mismatched files and classes\n")
+	}
+}
@@ -62,7 +62,7 @@ func inv(p *nn.Path, cIn, cOut, stride, er int64) ts.ModuleT {
 		} else {
 			return ys
 		}
-	})
+	}, seq)
 }
 
 var invertedResidualSettings [][]int64 = [][]int64{
@@ -124,6 +124,6 @@ func MobileNetV2(p *nn.Path, nclasses int64) ts.ModuleT {
 		tmp3.MustDrop()
 
 		return res
-	})
+	}, features, classifier)
 
 }
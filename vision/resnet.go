@@ -101,6 +101,11 @@ func (bb *basicBlock) ForwardT(x *ts.Tensor, train bool) *ts.Tensor {
 	return res
 }
 
+// Modules implements nn.ModuleLister for basicBlock.
+func (bb *basicBlock) Modules() []interface{} {
+	return []interface{}{bb.Conv1, bb.Bn1, bb.Conv2, bb.Bn2, bb.Downsample}
+}
+
 func resnet(p *nn.Path, nclasses int64, c1, c2, c3, c4 int64) nn.FuncT {
 	seq := nn.SeqT()
 	layer0 := layerZero(p)
@@ -127,7 +132,7 @@ func resnet(p *nn.Path, nclasses int64, c1, c2, c3, c4 int64) nn.FuncT {
 			fv.MustDrop()
 
 			return retVal
-		})
+		}, seq, fc)
 	} else {
 		// no final layer
 		return nn.NewFuncT(func(x *ts.Tensor, train bool) *ts.Tensor {
@@ -137,7 +142,7 @@ func resnet(p *nn.Path, nclasses int64, c1, c2, c3, c4 int64) nn.FuncT {
 			avgpool.MustDrop()
 
 			return retVal
-		})
+		}, seq)
 	}
 }
 
@@ -172,6 +177,11 @@ func (b *bottleneckBlock) ForwardT(xs *ts.Tensor, train bool) *ts.Tensor {
 	return res
 }
 
+// Modules implements nn.ModuleLister for bottleneckBlock.
+func (b *bottleneckBlock) Modules() []interface{} {
+	return []interface{}{b.Conv1, b.Bn1, b.Conv2, b.Bn2, b.Conv3, b.Bn3, b.Downsample}
+}
+
 // Bottleneck versions for ResNet 50, 101, and 152.
 func newBottleneckBlock(path *nn.Path, cIn, cOut, stride, e int64) *bottleneckBlock {
 	eDim := e * cOut
@@ -234,7 +244,7 @@ func bottleneckResnet(path *nn.Path, nclasses int64, c1, c2, c3, c4 int64) ts.Mo
 			fv.MustDrop()
 
 			return retVal
-		})
+		}, seq, fc)
 	} else {
 		// no final layer
 		return nn.NewFuncT(func(x *ts.Tensor, train bool) *ts.Tensor {
@@ -244,7 +254,7 @@ func bottleneckResnet(path *nn.Path, nclasses int64, c1, c2, c3, c4 int64) ts.Mo
 			avgpool.MustDrop()
 
 			return retVal
-		})
+		}, seq)
 	}
 }
 